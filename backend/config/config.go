@@ -1,20 +1,109 @@
 package config
 
 import (
+	"filesh/services/tenant"
+	"filesh/utils"
+	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// defaultContentSecurityPolicy allows the bundled SPA to run while blocking
+// third-party content and framing
+const defaultContentSecurityPolicy = "default-src 'self'; img-src 'self' data: blob:; style-src 'self' 'unsafe-inline'; " +
+	"worker-src 'self' blob:; frame-ancestors 'none'"
+
+// defaultCorsMethods and defaultCorsHeaders are what the frontend needs for
+// cross-origin calls to the private API
+const (
+	defaultCorsMethods = "GET,POST,PUT,HEAD,DELETE,OPTIONS"
+	defaultCorsHeaders = "Origin,Content-Length,Content-Type,X-Upload-Batch-Id,Tus-Resumable,X-Chunk-SHA256,X-API-Key,Range,If-Range," +
+		"Content-Encoding,X-Encryption-Algo,X-Encryption-IV,X-Encryption-Wrapped-Key,X-Batch-Owner-Token"
+)
+
+// httpMethods are the request methods CORS_METHODS may list
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE"}
+
 // Config holds all application configuration
 type Config struct {
-	CorsOrigin      string
+	CorsOrigins     []string
+	CorsCredentials bool
 	Minio           MinioConfig
 	FileExpiry      time.Duration
+	MaxExpiry       time.Duration
 	MaxFileSizeMB   int64
+	// MaxMultipartMemoryMB is how much of a multipart upload is held in memory before spilling to disk
+	MaxMultipartMemoryMB int64
+	// RequestTimeout bounds API calls that don't move file data; UploadTimeout
+	// and DownloadTimeout bound transfers, and AdminTimeout operator calls
+	// that walk the whole bucket. Zero leaves them unbounded.
 	RequestTimeout  time.Duration
+	UploadTimeout   time.Duration
+	DownloadTimeout time.Duration
+	AdminTimeout    time.Duration
 	WriteTimeout    time.Duration
 	ReadTimeout     time.Duration
+	StorageDedup    bool
+	StorageCompress bool
+	ReaperInterval  time.Duration
+	ReaperDryRun    bool
+	ContentSecurity string
+	AdminAPIKey     string
+	StatsCacheTTL   time.Duration
+	DownloadRateBps int64
+	// UploadRateBps caps how fast each upload is sent to storage; zero is unlimited
+	UploadRateBps   int64
+	DailyQuotaBytes int64
+	// MaxBatchesPerDay caps batch creations per client IP over a rolling day; zero disables it
+	MaxBatchesPerDay int64
+	// TenantKeys maps API keys to the tenant whose namespace they access
+	TenantKeys map[string]string
+	// TenantQuotas maps tenant IDs to their storage quota in bytes
+	TenantQuotas  map[string]int64
+	UsageCacheTTL time.Duration
+	PresignExpiry time.Duration
+	// MaxChunksPerBatch caps how many upload URLs batch creation may presign at once
+	MaxChunksPerBatch int
+	// DebugEndpoints enables pprof and expvar on a separate listener at DebugAddr
+	DebugEndpoints bool
+	DebugAddr      string
+	// PublicBaseURL is the frontend origin that share links point to
+	PublicBaseURL string
+	// ReportHashKey keys the hashes of abuse reporter IPs
+	ReportHashKey string
+	// BlocklistRefresh is how often the block-list is reloaded from storage
+	BlocklistRefresh time.Duration
+	// MaxConcurrentRequests caps simultaneous transfers; zero is unlimited
+	MaxConcurrentRequests int
+	// StatCacheTTL is how long object stat results are cached; zero disables the cache
+	StatCacheTTL  time.Duration
+	StatCacheSize int
+	// DeleteConcurrency bounds concurrent object deletions when removing a batch
+	DeleteConcurrency int
+	// BulkConcurrency is how many chunks whole-batch downloads and archives keep open at once
+	BulkConcurrency int
+	// ShutdownDrain is how long readiness fails before the server stops accepting requests
+	ShutdownDrain time.Duration
+	// StartupSelfTest round-trips an object through storage before serving
+	StartupSelfTest bool
+	// VerifyUploadSize checks the stored size of every uploaded chunk against the size sent
+	VerifyUploadSize bool
+	// CorsOriginPatterns are the CORS_ORIGIN entries with a wildcard subdomain,
+	// taken out of CorsOrigins
+	CorsOriginPatterns []utils.OriginPattern
+	// CorsMethods and CorsHeaders are the methods and request headers allowed
+	// in cross-origin requests to the private API
+	CorsMethods []string
+	CorsHeaders []string
+	// CorsMaxAge is how long browsers may cache a preflight response
+	CorsMaxAge time.Duration
+	// StorageSoftCapBytes is the total size the bucket may reach before new
+	// batches and uploads are refused, if StorageSoftCapEnforce; zero disables it
+	StorageSoftCapBytes   int64
+	StorageSoftCapEnforce bool
 }
 
 // MinioConfig holds MinIO configuration
@@ -24,30 +113,300 @@ type MinioConfig struct {
 	SecretAccessKey string
 	UseSSL          bool
 	BucketName      string
+	Region          string
+	PathStyle       bool
+	// CredSource selects where credentials come from: static, iam, env or file
+	CredSource  string
+	CredFile    string
+	CredProfile string
+	// CACertFile is a PEM bundle trusted in addition to the system roots
+	CACertFile  string
+	TLSInsecure bool
+	// UploadPartSize is the part size in bytes for multipart uploads to storage
+	UploadPartSize int64
+	// ObjectPrefix is prepended to every object name, for buckets shared with other applications
+	ObjectPrefix string
+	// SSEMode selects server-side encryption at rest: none, s3 or kms
+	SSEMode     string
+	SSEKMSKeyID string
+	// IdleConnTimeout is how long an unused storage connection is kept open
+	IdleConnTimeout time.Duration
+	// MaxIdleConns caps the unused storage connections kept for reuse
+	MaxIdleConns int
+	// ObjectLockDays retains uploaded chunk and file data for this many days
+	// in compliance mode; zero disables object lock
+	ObjectLockDays int
+	// MaxRetries is how often a failed upload or download is retried
+	MaxRetries int
+	// RetryDelay is the wait before the first retry, doubling after each
+	RetryDelay time.Duration
+	// StorageClass is applied to every object written unless a batch picks
+	// its own; empty leaves it to the provider's default
+	StorageClass string
 }
 
 // Load configuration from environment or use defaults
 func Load() (*Config, error) {
 	// Default configuration
 	cfg := &Config{
-		CorsOrigin: getEnv("CORS_ORIGIN", "http://localhost:5173"), // Default for Vite dev server
+		CorsOrigins:     getEnvList("CORS_ORIGIN", "http://localhost:5173"), // Default for Vite dev server
+		CorsCredentials: getEnv("CORS_ALLOW_CREDENTIALS", "false") == "true",
 		Minio: MinioConfig{
 			Endpoint:        getEnv("MINIO_ENDPOINT", "localhost:9000"),
 			AccessKeyID:     getEnv("MINIO_ACCESS_KEY", "minioadmin"),
 			SecretAccessKey: getEnv("MINIO_SECRET_KEY", "minioadmin"),
 			UseSSL:          getEnv("MINIO_USE_SSL", "false") == "true",
 			BucketName:      getEnv("MINIO_BUCKET_NAME", "filesh"),
+			Region:          getEnv("MINIO_REGION", ""), // Empty lets the client discover it
+			PathStyle:       getEnv("MINIO_PATH_STYLE", "false") == "true",
+			CredSource:      getEnv("MINIO_CRED_SOURCE", "static"),
+			CredFile:        getEnv("MINIO_CRED_FILE", ""), // Empty uses ~/.aws/credentials
+			CredProfile:     getEnv("MINIO_CRED_PROFILE", ""), // Empty uses the default profile
+			CACertFile:      getEnv("MINIO_CA_CERT", ""),
+			TLSInsecure:     getEnv("MINIO_TLS_INSECURE", "false") == "true", // Dev only: skips certificate verification
+			UploadPartSize:  getEnvInt64("UPLOAD_PART_SIZE", 64*1024*1024),
+			ObjectPrefix:    getEnv("OBJECT_PREFIX", ""), // Empty stores objects at the bucket root
+			SSEMode:         getEnv("SSE_MODE", "none"),
+			SSEKMSKeyID:     getEnv("SSE_KMS_KEY_ID", ""),
+			IdleConnTimeout: getEnvDuration("MINIO_IDLE_CONN_TIMEOUT", time.Minute),
+			MaxIdleConns:    int(getEnvInt64("MINIO_MAX_IDLE_CONNS", 16)),
+			ObjectLockDays:  int(getEnvInt64("OBJECT_LOCK_DAYS", 0)),
+			MaxRetries:      int(getEnvInt64("MINIO_MAX_RETRIES", 3)),
+			RetryDelay:      getEnvDuration("MINIO_RETRY_DELAY", 2*time.Second),
+			StorageClass:    getEnv("STORAGE_CLASS", ""), // Empty uses the provider's default
 		},
 		FileExpiry:     getEnvDuration("FILE_EXPIRY", 24*7*time.Hour), // 7 days default
 		MaxFileSizeMB:  getEnvInt64("MAX_FILE_SIZE_MB", 10240),        // 10GB default
-		RequestTimeout: getEnvDuration("REQUEST_TIMEOUT", 30*time.Minute), // 30 minutes for large uploads
+		MaxMultipartMemoryMB: getEnvInt64("MAX_MULTIPART_MEMORY_MB", 32),
+		RequestTimeout: getEnvDuration("REQUEST_TIMEOUT", 2*time.Minute),
+		UploadTimeout:  getEnvDuration("UPLOAD_TIMEOUT", 30*time.Minute),   // 30 minutes for large uploads
+		DownloadTimeout: getEnvDuration("DOWNLOAD_TIMEOUT", 30*time.Minute), // 30 minutes for large downloads
+		AdminTimeout:   getEnvDuration("ADMIN_TIMEOUT", 30*time.Minute),    // 30 minutes for bucket-wide listings
 		WriteTimeout:   getEnvDuration("WRITE_TIMEOUT", 30*time.Minute),   // 30 minutes for large uploads
 		ReadTimeout:    getEnvDuration("READ_TIMEOUT", 30*time.Minute),    // 30 minutes for large downloads
+		StorageDedup:    getEnv("STORAGE_DEDUP", "false") == "true",
+		StorageCompress: getEnv("STORAGE_COMPRESS", "false") == "true",
+		ReaperInterval:  getEnvDuration("REAPER_INTERVAL", time.Hour), // 0 disables the reaper
+		ReaperDryRun:    getEnv("REAPER_DRY_RUN", "false") == "true",
+		ContentSecurity: getEnv("CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy),
+		AdminAPIKey:     getEnv("ADMIN_API_KEY", ""), // Empty disables admin endpoints
+		StatsCacheTTL:   getEnvDuration("STATS_CACHE_TTL", 5*time.Minute),
+		DownloadRateBps: getEnvInt64("DOWNLOAD_RATE_LIMIT_BPS", 0), // 0 means unlimited
+		UploadRateBps:   getEnvInt64("UPLOAD_RATE_LIMIT_BPS", 0),   // 0 means unlimited
+		DailyQuotaBytes: getEnvInt64("DAILY_UPLOAD_QUOTA_BYTES", 0), // 0 disables the quota
+		MaxBatchesPerDay: getEnvInt64("MAX_BATCHES_PER_DAY", 0), // 0 disables the limit
+		UsageCacheTTL:   getEnvDuration("USAGE_CACHE_TTL", 5*time.Minute),
+		PresignExpiry:   getEnvDuration("PRESIGN_EXPIRY", 15*time.Minute),
+		MaxChunksPerBatch: int(getEnvInt64("MAX_CHUNKS_PER_BATCH", 1000)),
+		DebugEndpoints:  getEnv("DEBUG_ENDPOINTS", "false") == "true",
+		DebugAddr:       getEnv("DEBUG_ADDR", "localhost:6060"), // Loopback only by default
+		ReportHashKey:   getEnv("REPORT_HASH_KEY", ""), // Empty uses a random key per process
+		BlocklistRefresh: getEnvDuration("BLOCKLIST_REFRESH", time.Minute),
+		MaxConcurrentRequests: int(getEnvInt64("MAX_CONCURRENT_REQUESTS", 0)),
+		StatCacheTTL:     getEnvDuration("STAT_CACHE_TTL", 5*time.Second), // 0 disables the cache
+		StatCacheSize:    int(getEnvInt64("STAT_CACHE_SIZE", 10000)),
+		DeleteConcurrency: int(getEnvInt64("DELETE_CONCURRENCY", 16)),
+		BulkConcurrency:  int(getEnvInt64("BULK_CONCURRENCY", 4)),
+		ShutdownDrain:    getEnvDuration("SHUTDOWN_DRAIN", 5*time.Second),
+		StartupSelfTest:  getEnv("STARTUP_SELFTEST", "false") == "true",
+		VerifyUploadSize: getEnv("VERIFY_UPLOAD_SIZE", "false") == "true",
+		StorageSoftCapBytes:   getEnvInt64("STORAGE_SOFT_CAP_BYTES", 0), // 0 disables the cap
+		StorageSoftCapEnforce: getEnv("STORAGE_SOFT_CAP_ENFORCE", "true") == "true",
+	}
+
+	switch cfg.Minio.CredSource {
+	case "static", "iam", "env", "file":
+	default:
+		return nil, fmt.Errorf("MINIO_CRED_SOURCE must be one of static, iam, env or file, got %q", cfg.Minio.CredSource)
+	}
+
+	if strings.Contains(cfg.Minio.ObjectPrefix, "..") || strings.Contains(cfg.Minio.ObjectPrefix, "//") {
+		return nil, fmt.Errorf("OBJECT_PREFIX must be a plain path such as \"filesh/\"")
+	}
+
+	switch cfg.Minio.SSEMode {
+	case "none", "s3":
+	case "kms":
+		if cfg.Minio.SSEKMSKeyID == "" {
+			return nil, fmt.Errorf("SSE_MODE=kms requires SSE_KMS_KEY_ID")
+		}
+	default:
+		return nil, fmt.Errorf("SSE_MODE must be one of none, s3 or kms, got %q", cfg.Minio.SSEMode)
+	}
+
+	if cfg.Minio.MaxRetries < 0 || cfg.Minio.MaxRetries > 10 {
+		return nil, fmt.Errorf("MINIO_MAX_RETRIES must be between 0 and 10")
+	}
+
+	if cfg.Minio.RetryDelay <= 0 {
+		return nil, fmt.Errorf("MINIO_RETRY_DELAY must be positive")
+	}
+
+	if cfg.Minio.ObjectLockDays < 0 || cfg.Minio.ObjectLockDays > 36500 {
+		return nil, fmt.Errorf("OBJECT_LOCK_DAYS must be between 0 and 36500")
+	}
+
+	if cfg.Minio.StorageClass != "" && !utils.ValidStorageClass(cfg.Minio.StorageClass) {
+		return nil, fmt.Errorf("STORAGE_CLASS must be a storage class name such as STANDARD_IA, got %q", cfg.Minio.StorageClass)
+	}
+
+	if cfg.MaxMultipartMemoryMB < 1 {
+		return nil, fmt.Errorf("MAX_MULTIPART_MEMORY_MB must be at least 1")
+	}
+
+	if cfg.StorageSoftCapBytes < 0 {
+		return nil, fmt.Errorf("STORAGE_SOFT_CAP_BYTES cannot be negative")
+	}
+
+	if cfg.MaxBatchesPerDay < 0 {
+		return nil, fmt.Errorf("MAX_BATCHES_PER_DAY cannot be negative")
+	}
+
+	if cfg.MaxConcurrentRequests < 0 {
+		return nil, fmt.Errorf("MAX_CONCURRENT_REQUESTS cannot be negative")
+	}
+
+	if cfg.RequestTimeout < 0 || cfg.UploadTimeout < 0 || cfg.DownloadTimeout < 0 || cfg.AdminTimeout < 0 {
+		return nil, fmt.Errorf("REQUEST_TIMEOUT, UPLOAD_TIMEOUT, DOWNLOAD_TIMEOUT and ADMIN_TIMEOUT cannot be negative")
+	}
+
+	// A zero idle timeout would keep idle connections forever
+	if cfg.Minio.IdleConnTimeout <= 0 {
+		return nil, fmt.Errorf("MINIO_IDLE_CONN_TIMEOUT must be positive")
+	}
+
+	if cfg.Minio.MaxIdleConns < 1 {
+		return nil, fmt.Errorf("MINIO_MAX_IDLE_CONNS must be at least 1")
+	}
+
+	if cfg.StatCacheTTL < 0 {
+		return nil, fmt.Errorf("STAT_CACHE_TTL cannot be negative")
+	}
+
+	if cfg.StatCacheSize < 1 {
+		return nil, fmt.Errorf("STAT_CACHE_SIZE must be at least 1")
+	}
+
+	if cfg.DeleteConcurrency < 1 {
+		return nil, fmt.Errorf("DELETE_CONCURRENCY must be at least 1")
+	}
+
+	if cfg.BulkConcurrency < 1 || cfg.BulkConcurrency > 64 {
+		return nil, fmt.Errorf("BULK_CONCURRENCY must be between 1 and 64")
+	}
+
+	if cfg.ShutdownDrain < 0 {
+		return nil, fmt.Errorf("SHUTDOWN_DRAIN cannot be negative")
+	}
+
+	if cfg.BlocklistRefresh <= 0 {
+		return nil, fmt.Errorf("BLOCKLIST_REFRESH must be positive")
+	}
+
+	// S3 rejects presigned URLs valid for longer than a week
+	if cfg.PresignExpiry <= 0 || cfg.PresignExpiry > 7*24*time.Hour {
+		return nil, fmt.Errorf("PRESIGN_EXPIRY must be between 1s and 168h")
+	}
+
+	// Chunk indices run from 0 to 99999
+	if cfg.MaxChunksPerBatch < 1 || cfg.MaxChunksPerBatch > 100000 {
+		return nil, fmt.Errorf("MAX_CHUNKS_PER_BATCH must be between 1 and 100000")
+	}
+
+	// Browsers reject credentialed requests to a wildcard origin
+	for _, origin := range cfg.CorsOrigins {
+		if origin == "*" && cfg.CorsCredentials {
+			return nil, fmt.Errorf("CORS_ORIGIN=* cannot be combined with CORS_ALLOW_CREDENTIALS=true")
+		}
+	}
+
+	// Entries like https://*.myapp.com cover per-user subdomains
+	exactOrigins := cfg.CorsOrigins[:0]
+	for _, origin := range cfg.CorsOrigins {
+		if origin == "*" || !strings.Contains(origin, "*") {
+			exactOrigins = append(exactOrigins, origin)
+			continue
+		}
+		pattern, err := utils.ParseOriginPattern(origin)
+		if err != nil {
+			return nil, fmt.Errorf("CORS_ORIGIN: %w", err)
+		}
+		cfg.CorsOriginPatterns = append(cfg.CorsOriginPatterns, pattern)
+	}
+	cfg.CorsOrigins = exactOrigins
+
+	// Methods are matched case-sensitively by browsers, so they are normalized
+	for _, method := range getEnvList("CORS_METHODS", defaultCorsMethods) {
+		method = strings.ToUpper(method)
+		if !slices.Contains(httpMethods, method) {
+			return nil, fmt.Errorf("CORS_METHODS: unknown HTTP method %q", method)
+		}
+		cfg.CorsMethods = append(cfg.CorsMethods, method)
+	}
+	cfg.CorsHeaders = getEnvList("CORS_HEADERS", defaultCorsHeaders)
+	if len(cfg.CorsMethods) == 0 || len(cfg.CorsHeaders) == 0 {
+		return nil, fmt.Errorf("CORS_METHODS and CORS_HEADERS cannot be empty")
+	}
+	cfg.CorsMaxAge = getEnvDuration("CORS_MAX_AGE", 12*time.Hour)
+	if cfg.CorsMaxAge < 0 {
+		return nil, fmt.Errorf("CORS_MAX_AGE cannot be negative")
+	}
+
+	// Tenant API keys are given as "tenant:key" pairs
+	cfg.TenantKeys = make(map[string]string)
+	for _, entry := range getEnvList("TENANT_API_KEYS", "") {
+		tenantID, key, found := strings.Cut(entry, ":")
+		if !found || key == "" {
+			return nil, fmt.Errorf("TENANT_API_KEYS entry %q must have the form tenant:key", entry)
+		}
+		if err := tenant.Validate(tenantID); err != nil {
+			return nil, fmt.Errorf("TENANT_API_KEYS: %w", err)
+		}
+		if _, dup := cfg.TenantKeys[key]; dup {
+			return nil, fmt.Errorf("TENANT_API_KEYS: duplicate API key for tenant %q", tenantID)
+		}
+		cfg.TenantKeys[key] = tenantID
+	}
+
+	// Tenant quotas are given as "tenant:bytes" pairs; tenants without one are unlimited
+	cfg.TenantQuotas = make(map[string]int64)
+	for _, entry := range getEnvList("TENANT_QUOTAS", "") {
+		tenantID, value, found := strings.Cut(entry, ":")
+		quota, err := strconv.ParseInt(value, 10, 64)
+		if !found || err != nil || quota < 0 {
+			return nil, fmt.Errorf("TENANT_QUOTAS entry %q must have the form tenant:bytes", entry)
+		}
+		if !tenant.IsValid(tenantID) {
+			return nil, fmt.Errorf("TENANT_QUOTAS: invalid tenant ID %q", tenantID)
+		}
+		cfg.TenantQuotas[tenantID] = quota
+	}
+
+	// Share links default to the first frontend origin
+	cfg.PublicBaseURL = getEnv("PUBLIC_BASE_URL", "")
+	if cfg.PublicBaseURL == "" && len(cfg.CorsOrigins) > 0 && cfg.CorsOrigins[0] != "*" {
+		cfg.PublicBaseURL = cfg.CorsOrigins[0]
+	}
+
+	// Per-batch expiry can't outlive the bucket lifecycle by default
+	cfg.MaxExpiry = getEnvDuration("MAX_EXPIRY", cfg.FileExpiry)
+	if cfg.MaxExpiry > time.Duration(LifecycleDays(cfg.FileExpiry))*24*time.Hour {
+		return nil, fmt.Errorf("MAX_EXPIRY (%v) exceeds FILE_EXPIRY (%v), after which the bucket lifecycle deletes objects", cfg.MaxExpiry, cfg.FileExpiry)
 	}
 
 	return cfg, nil
 }
 
+// LifecycleDays converts an expiry duration to whole days, rounding up since
+// lifecycle rules can't expire objects any sooner than one day
+func LifecycleDays(expiry time.Duration) int {
+	days := int((expiry + 24*time.Hour - 1) / (24 * time.Hour))
+	if days < 1 {
+		days = 1
+	}
+	return days
+}
+
 // Helper function to get environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -57,6 +416,17 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// Helper function to get a comma-separated list from environment variable
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // Helper function to get duration from environment variable
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	value := os.Getenv(key)
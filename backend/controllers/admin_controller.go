@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"filesh/models"
+	"filesh/services/batch"
+	"filesh/services/blocklist"
+	"filesh/services/storage"
+	"filesh/services/tenant"
+	"filesh/services/usage"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxObjectListing caps the objects returned by a single object listing
+const maxObjectListing = 10000
+
+// AdminController handles operator-only management endpoints
+type AdminController struct {
+	batchService     *batch.Service
+	usageService     *usage.Service
+	blocklistService *blocklist.Service
+	reaper           *batch.Reaper
+	storage          storage.ObjectStorage
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(batchService *batch.Service, usageService *usage.Service, blocklistService *blocklist.Service, reaper *batch.Reaper, storage storage.ObjectStorage) *AdminController {
+	return &AdminController{
+		batchService:     batchService,
+		usageService:     usageService,
+		blocklistService: blocklistService,
+		reaper:           reaper,
+		storage:          storage,
+	}
+}
+
+// ListBatches lists all batches with their sizes and expiry.
+// Supports ?sort=size|created (default created, newest first) and
+// pagination via ?offset= and ?limit= (default 50).
+func (c *AdminController) ListBatches(ctx *gin.Context) {
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'offset' must be an integer"))
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'limit' must be an integer"))
+		return
+	}
+
+	result, err := c.batchService.ListBatches(ctx.Request.Context(), ctx.Query("sort"), offset, limit)
+	if errors.Is(err, batch.ErrInvalidRequest) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to list batches: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// ListTaggedBatches lists batches across all tenants that carry the tag
+// given as ?tag=key:value. An empty value matches any value of the key.
+func (c *AdminController) ListTaggedBatches(ctx *gin.Context) {
+	key, value, err := batch.ParseTagFilter(ctx.Query("tag"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	batches, err := c.batchService.FindBatchesByTag(ctx.Request.Context(), key, value)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to list batches: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"batches": batches,
+		"total":   len(batches),
+	}))
+}
+
+// DeleteBatch deletes a batch and all of its objects. The batch's tenant is
+// selected with ?tenant= and defaults to the public namespace.
+func (c *AdminController) DeleteBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	tenantID := ctx.DefaultQuery("tenant", tenant.Public)
+	if !tenant.IsValid(tenantID) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'tenant' is not a valid tenant ID"))
+		return
+	}
+
+	deleted, err := c.batchService.DeleteBatch(tenant.NewContext(ctx.Request.Context(), tenantID), batchID)
+	if deleted > 0 {
+		c.usageService.Invalidate(tenantID)
+	}
+	if errors.Is(err, storage.ErrObjectLocked) {
+		ctx.JSON(http.StatusForbidden, models.NewErrorResponse(fmt.Sprintf("Batch is under retention and cannot be deleted yet: %v", err)))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to delete batch: %v", err)))
+		return
+	}
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"batchId": batchID,
+		"tenant":  tenantID,
+		"deleted": deleted,
+	}))
+}
+
+// PurgeExpired deletes every expired batch now instead of waiting for the
+// background reaper, reporting the batches and bytes reclaimed. With
+// ?dryRun=true it only reports what would be deleted.
+func (c *AdminController) PurgeExpired(ctx *gin.Context) {
+	dryRun := ctx.Query("dryRun") == "true"
+
+	summary, err := c.reaper.RunOnce(ctx.Request.Context(), dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to purge expired batches: %v", err)))
+		return
+	}
+
+	// Expired IDs are qualified as "tenant/batchId"
+	if !dryRun {
+		for _, id := range summary.ExpiredIDs {
+			tenantID, _, _ := strings.Cut(id, "/")
+			c.usageService.Invalidate(tenantID)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(summary))
+}
+
+// ListObjects lists raw storage objects under ?prefix= modified after
+// ?since=, newest first. since is an RFC 3339 time or a duration back from
+// now such as "1h"; without it every object matches. At most ?limit= objects
+// (default 1000) are returned.
+func (c *AdminController) ListObjects(ctx *gin.Context) {
+	var since time.Time
+	if value := ctx.Query("since"); value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			since = t
+		} else if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			since = time.Now().Add(-d)
+		} else {
+			ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'since' must be an RFC 3339 time or a positive duration such as \"1h\""))
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "1000"))
+	if err != nil || limit < 1 || limit > maxObjectListing {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Query parameter 'limit' must be between 1 and %d", maxObjectListing)))
+		return
+	}
+
+	objects, err := storage.ListObjectsSince(ctx.Request.Context(), c.storage, ctx.Query("prefix"), since)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to list objects: %v", err)))
+		return
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].LastModified.After(objects[j].LastModified)
+	})
+
+	list := &models.ObjectList{
+		Objects:   make([]models.ObjectSummary, 0, min(len(objects), limit)),
+		Total:     len(objects),
+		Truncated: len(objects) > limit,
+	}
+	for i, obj := range objects {
+		list.TotalBytes += obj.Size
+		if i < limit {
+			list.Objects = append(list.Objects, models.ObjectSummary{
+				Name:         obj.Name,
+				Size:         obj.Size,
+				LastModified: obj.LastModified,
+			})
+		}
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(list))
+}
+
+// ListReports returns the abuse review queue, most reported batches first
+func (c *AdminController) ListReports(ctx *gin.Context) {
+	reports, err := c.batchService.ListReports(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to list reports: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"reports": reports,
+		"total":   len(reports),
+	}))
+}
+
+// TakedownBatch blocks a reported batch, deletes it and records the
+// takedown. The batch's tenant is selected with ?tenant= and defaults to the
+// public namespace.
+func (c *AdminController) TakedownBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	tenantID := ctx.DefaultQuery("tenant", tenant.Public)
+	if !tenant.IsValid(tenantID) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'tenant' is not a valid tenant ID"))
+		return
+	}
+
+	// Block first so the batch can't be re-uploaded while it is being deleted
+	tenantCtx := tenant.NewContext(ctx.Request.Context(), tenantID)
+	if err := c.blocklistService.Block(tenantCtx, batchID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to take down batch: %v", err)))
+		return
+	}
+
+	deleted, err := c.batchService.TakedownBatch(tenantCtx, batchID)
+	if deleted > 0 {
+		c.usageService.Invalidate(tenantID)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to take down batch: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"batchId": batchID,
+		"tenant":  tenantID,
+		"deleted": deleted,
+	}))
+}
+
+// ListBlocked lists the batches on the block-list
+func (c *AdminController) ListBlocked(ctx *gin.Context) {
+	blocked := c.blocklistService.List()
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"blocked": blocked,
+		"total":   len(blocked),
+	}))
+}
+
+// BlockBatch adds a batch to the block-list without deleting it. The batch's
+// tenant is selected with ?tenant= and defaults to the public namespace.
+func (c *AdminController) BlockBatch(ctx *gin.Context) {
+	c.updateBlocklist(ctx, c.blocklistService.Block)
+}
+
+// UnblockBatch removes a batch from the block-list. The batch's tenant is
+// selected with ?tenant= and defaults to the public namespace.
+func (c *AdminController) UnblockBatch(ctx *gin.Context) {
+	c.updateBlocklist(ctx, c.blocklistService.Unblock)
+}
+
+// updateBlocklist applies a block-list change to the batch named in the request
+func (c *AdminController) updateBlocklist(ctx *gin.Context, update func(context.Context, string) error) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	tenantID := ctx.DefaultQuery("tenant", tenant.Public)
+	if !tenant.IsValid(tenantID) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'tenant' is not a valid tenant ID"))
+		return
+	}
+
+	if err := update(tenant.NewContext(ctx.Request.Context(), tenantID), batchID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to update block-list: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"batchId": batchID,
+		"tenant":  tenantID,
+		"blocked": c.blocklistService.IsBlocked(tenant.NewContext(ctx.Request.Context(), tenantID), batchID),
+	}))
+}
@@ -1,10 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"filesh/models"
 	"filesh/services/batch"
+	"filesh/services/chunk"
+	"filesh/services/storage"
+	"filesh/services/tenant"
+	"filesh/services/usage"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,25 +19,108 @@ import (
 
 // BatchController handles batch-related API endpoints
 type BatchController struct {
-	batchService *batch.Service
+	batchService     *batch.Service
+	chunkService     *chunk.Service
+	usageService     *usage.Service
+	publicBaseURL    string
+	presignExpiry    time.Duration
+	maxPresignChunks int
 }
 
-// NewBatchController creates a new batch controller
-func NewBatchController(batchService *batch.Service) *BatchController {
+// NewBatchController creates a new batch controller. publicBaseURL is the
+// origin share links point to; when empty the request's origin is used.
+// Batches created with presigned upload URLs get them for at most
+// maxPresignChunks chunks, valid for presignExpiry.
+func NewBatchController(batchService *batch.Service, chunkService *chunk.Service, usageService *usage.Service, publicBaseURL string, presignExpiry time.Duration, maxPresignChunks int) *BatchController {
 	return &BatchController{
-		batchService: batchService,
+		batchService:     batchService,
+		chunkService:     chunkService,
+		usageService:     usageService,
+		publicBaseURL:    publicBaseURL,
+		presignExpiry:    presignExpiry,
+		maxPresignChunks: maxPresignChunks,
 	}
 }
 
 // CreateBatch creates a new upload batch
 func (c *BatchController) CreateBatch(ctx *gin.Context) {
+	// The request body is optional; older clients send none
+	var req models.CreateBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	// Presigned uploads need a known chunk count. The declared total size only
+	// turns away batches that can't fit; each upload is held to the quota
+	// when it is confirmed, as the client may send more than it declared
+	if req.Presign {
+		if req.TotalChunks <= 0 || req.TotalChunks > c.maxPresignChunks {
+			ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Presigned uploads need totalChunks between 1 and %d", c.maxPresignChunks)))
+			return
+		}
+		if err := c.usageService.CheckQuota(ctx.Request.Context(), max(req.TotalSize, 0)); err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse("Storage quota exceeded"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check storage quota: %v", err)))
+			return
+		}
+	}
+
 	// Create a new batch using the batch service
-	metadata := c.batchService.CreateBatch()
+	metadata, err := c.batchService.CreateBatch(ctx.Request.Context(), req)
+	if errors.Is(err, batch.ErrInvalidRequest) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to create batch: %v", err)))
+		return
+	}
+
+	// Return the batch metadata as JSON, with a link ready to share
+	baseURL := externalBaseURL(ctx, c.publicBaseURL)
+	metadata.ShareURL = shareLink(baseURL, metadata.ID)
+
+	if req.Presign {
+		uploads, err := c.presignAll(ctx, metadata.ID, metadata.TotalChunks)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to presign uploads: %v", err)))
+			return
+		}
+		uploads.ConfirmURL = fmt.Sprintf("%s/api/upload/%s/{index}/confirm", baseURL, metadata.ID)
+		metadata.Uploads = uploads
+	}
 
-	// Return the batch metadata as JSON
 	ctx.JSON(http.StatusOK, metadata)
 }
 
+// presignAll presigns uploads of chunks 0 to count-1, as many at a time as the
+// chunk service allows
+func (c *BatchController) presignAll(ctx *gin.Context, batchID string, count int) (*models.PresignedUploads, error) {
+	uploads := &models.PresignedUploads{Chunks: make([]*models.PresignedUpload, count)}
+	for start := 0; start < count; start += chunk.MaxPresignIndices {
+		indices := make([]int, 0, chunk.MaxPresignIndices)
+		for index := start; index < min(start+chunk.MaxPresignIndices, count); index++ {
+			indices = append(indices, index)
+		}
+
+		result, err := c.chunkService.PresignUploads(ctx.Request.Context(), batchID, indices, c.presignExpiry, maxFileSize)
+		if err != nil {
+			return nil, err
+		}
+		for index, upload := range result.Uploads {
+			uploads.Chunks[index] = upload
+		}
+		if uploads.ExpiresAt.IsZero() {
+			uploads.ExpiresAt = result.ExpiresAt
+		}
+	}
+	return uploads, nil
+}
+
 // GetBatchInfo retrieves information about a batch
 func (c *BatchController) GetBatchInfo(ctx *gin.Context) {
 	batchID := ctx.Param("batchId")
@@ -41,6 +131,10 @@ func (c *BatchController) GetBatchInfo(ctx *gin.Context) {
 
 	// Get batch info from the service
 	metadata, stats, err := c.batchService.GetBatchInfo(ctx.Request.Context(), batchID)
+	if errors.Is(err, batch.ErrBatchExpired) {
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(fmt.Sprintf("Batch not found: %v", err)))
 		return
@@ -48,17 +142,76 @@ func (c *BatchController) GetBatchInfo(ctx *gin.Context) {
 
 	// Return both metadata and stats in the response
 	response := gin.H{
-		"id":           metadata.ID,
-		"createdAt":    metadata.CreatedAt.Format(time.RFC3339),
-		"expiresAt":    metadata.ExpiresAt.Format(time.RFC3339),
-		"totalSize":    stats.TotalSize,
-		"chunksCount":  stats.ChunksCount,
-		"lastActivity": stats.LastActivity.Format(time.RFC3339),
+		"id":             metadata.ID,
+		"createdAt":      metadata.CreatedAt.Format(time.RFC3339),
+		"expiresAt":      metadata.ExpiresAt.Format(time.RFC3339),
+		"totalSize":      stats.TotalSize,
+		"chunksCount":    stats.ChunksCount,
+		"lastActivity":   stats.LastActivity.Format(time.RFC3339),
+		"isComplete":     stats.IsComplete,
+		"finalized":      metadata.Finalized,
+		"uploadedChunks": stats.UploadedChunks,
+		"expectedChunks": stats.ExpectedChunks,
+	}
+	if stats.ProgressPercent != nil {
+		response["progressPercent"] = *stats.ProgressPercent
+	}
+	if metadata.TotalChunks > 0 {
+		response["totalChunks"] = metadata.TotalChunks
+	}
+	if metadata.TotalSize > 0 {
+		response["expectedSize"] = metadata.TotalSize
+	}
+	if metadata.Locked {
+		response["locked"] = true
 	}
 	
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(response))
 }
 
+// GetBatchStatus returns just the upload progress of a batch, for clients
+// polling until it completes. The response carries a weak ETag derived from
+// the progress, so polls sending If-None-Match get 304 while nothing changed.
+func (c *BatchController) GetBatchStatus(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	_, stats, err := c.batchService.GetBatchInfo(ctx.Request.Context(), batchID)
+	if errors.Is(err, batch.ErrBatchNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+		return
+	}
+	if errors.Is(err, batch.ErrBatchExpired) {
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to get batch status: %v", err)))
+		return
+	}
+
+	status := models.BatchProgressStatus{
+		UploadedChunks: stats.UploadedChunks,
+		ExpectedChunks: stats.ExpectedChunks,
+		TotalSize:      stats.TotalSize,
+		IsComplete:     stats.IsComplete,
+	}
+	etag := fmt.Sprintf("%d-%d-%d-%t", status.UploadedChunks, status.ExpectedChunks, status.TotalSize, status.IsComplete)
+
+	// Polls must revalidate every time rather than trust a cached copy
+	ctx.Header("ETag", fmt.Sprintf("W/\"%s\"", etag))
+	ctx.Header("Cache-Control", "no-cache")
+	if inm := ctx.GetHeader("If-None-Match"); inm != "" && etagListMatches(inm, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(status))
+}
+
 // ListChunks lists all chunks in a batch
 func (c *BatchController) ListChunks(ctx *gin.Context) {
 	batchID := ctx.Param("batchId")
@@ -75,4 +228,314 @@ func (c *BatchController) ListChunks(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(batchStatus))
-} 
\ No newline at end of file
+} 
+
+// ListMissingChunks reports which chunk indices are still missing from a batch
+func (c *BatchController) ListMissingChunks(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	// Parse the expected chunk count from the query string
+	expected, err := strconv.Atoi(ctx.Query("expected"))
+	if err != nil || expected < 0 {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'expected' must be a non-negative integer"))
+		return
+	}
+	if expected > batch.MaxExpectedChunks {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Query parameter 'expected' cannot exceed %d", batch.MaxExpectedChunks)))
+		return
+	}
+
+	// Compare present chunks against the expected range
+	missing, err := c.batchService.FindMissingChunks(ctx.Request.Context(), batchID, expected)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to compute missing chunks: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(missing))
+}
+
+// CreateAlias generates a short alias that can be used in place of the batch ID
+func (c *BatchController) CreateAlias(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	alias, err := c.batchService.CreateAlias(ctx.Request.Context(), batchID)
+	if errors.Is(err, batch.ErrBatchNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to create alias: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(alias))
+}
+
+// GetManifest returns the list of files recorded when the batch was created
+func (c *BatchController) GetManifest(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	manifest, err := c.batchService.GetManifest(ctx.Request.Context(), batchID)
+	if errors.Is(err, batch.ErrBatchNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+		return
+	}
+	if errors.Is(err, batch.ErrBatchExpired) {
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to read manifest: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(manifest))
+}
+
+// VerifyBatch reassembles a batch and checks it against the whole-file
+// sha256 given at creation, answering 422 with both digests on a mismatch
+func (c *BatchController) VerifyBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	result, err := c.batchService.VerifyBatch(ctx.Request.Context(), batchID)
+	switch {
+	case errors.Is(err, batch.ErrDigestMismatch):
+		ctx.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(fmt.Sprintf("Batch does not match its sha256: expected %s, got %s", result.Expected, result.SHA256)))
+	case errors.Is(err, batch.ErrNoDigest):
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse("Batch was created without a sha256 to verify against"))
+	case errors.Is(err, batch.ErrBatchNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+	case errors.Is(err, batch.ErrBatchExpired):
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+	case errors.Is(err, batch.ErrFileIncomplete):
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse(err.Error()))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to verify batch: %v", err)))
+	default:
+		ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+	}
+}
+
+// FinalizeBatch marks a fully uploaded batch as done, writing a completion
+// marker with its chunk count, size and sha256. An optional {"lock": true}
+// body refuses any further chunk uploads with 409.
+func (c *BatchController) FinalizeBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	// The request body is optional
+	var req models.FinalizeBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	completion, err := c.batchService.FinalizeBatch(ctx.Request.Context(), batchID, req.Lock)
+	switch {
+	case errors.Is(err, batch.ErrFileIncomplete):
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, batch.ErrDigestMismatch):
+		ctx.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(fmt.Sprintf("Batch does not match its sha256: %v", err)))
+	case errors.Is(err, batch.ErrBatchNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+	case errors.Is(err, batch.ErrBatchExpired):
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to finalize batch: %v", err)))
+	default:
+		ctx.JSON(http.StatusOK, models.NewSuccessResponse(completion))
+	}
+}
+
+// CopyBatch duplicates a batch under a new ID with its own expiry, copying
+// the chunks within storage. The copier becomes the owner of the new batch.
+func (c *BatchController) CopyBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	// The request body is optional
+	var req models.CopyBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	// The copy counts against the tenant's quota like an upload of the same size
+	_, stats, err := c.batchService.GetBatchInfo(ctx.Request.Context(), batchID)
+	if err == nil {
+		err = c.usageService.CheckQuota(ctx.Request.Context(), stats.TotalSize)
+	}
+	if errors.Is(err, usage.ErrQuotaExceeded) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse("Storage quota exceeded"))
+		return
+	}
+
+	var result *models.BatchCopy
+	if err == nil {
+		result, err = c.batchService.CopyBatch(ctx.Request.Context(), batchID, req.ExpiresIn)
+	}
+	switch {
+	case errors.Is(err, batch.ErrInvalidRequest):
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, batch.ErrBatchTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, batch.ErrBatchNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+	case errors.Is(err, batch.ErrBatchExpired):
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to copy batch: %v", err)))
+	default:
+		c.usageService.RecordUpload(tenant.FromContext(ctx.Request.Context()), result.TotalSize)
+		result.Batch.ShareURL = shareLink(externalBaseURL(ctx, c.publicBaseURL), result.Batch.ID)
+		ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+	}
+}
+
+// RenameBatch moves a batch to a custom, human-chosen ID, for links that
+// must stay stable without an alias in between
+func (c *BatchController) RenameBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	var req models.RenameBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	metadata, err := c.batchService.RenameBatch(ctx.Request.Context(), batchID, req.NewID)
+	switch {
+	case errors.Is(err, batch.ErrInvalidRequest):
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, batch.ErrBatchExists):
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse(fmt.Sprintf("Batch ID %q is already in use", req.NewID)))
+	case errors.Is(err, batch.ErrBatchNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+	case errors.Is(err, batch.ErrBatchExpired):
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to rename batch: %v", err)))
+	default:
+		metadata.ShareURL = shareLink(externalBaseURL(ctx, c.publicBaseURL), metadata.ID)
+		ctx.JSON(http.StatusOK, models.NewSuccessResponse(metadata))
+	}
+}
+
+// ExtendBatch pushes back a batch's expiry
+func (c *BatchController) ExtendBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	var req models.ExtendBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	metadata, err := c.batchService.ExtendExpiry(ctx.Request.Context(), batchID, req.ExpiresIn)
+	switch {
+	case errors.Is(err, batch.ErrInvalidRequest):
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, batch.ErrBatchNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+	case errors.Is(err, batch.ErrBatchExpired):
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to extend batch: %v", err)))
+	default:
+		ctx.JSON(http.StatusOK, models.NewSuccessResponse(metadata))
+	}
+}
+
+// AbortBatch cancels an incomplete upload, deleting the chunks uploaded so far
+// along with the batch metadata
+func (c *BatchController) AbortBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	deleted, err := c.batchService.AbortBatch(ctx.Request.Context(), batchID)
+	if deleted > 0 {
+		c.usageService.Invalidate(tenant.FromContext(ctx.Request.Context()))
+	}
+	if errors.Is(err, batch.ErrBatchNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+		return
+	}
+	if errors.Is(err, storage.ErrObjectLocked) {
+		ctx.JSON(http.StatusForbidden, models.NewErrorResponse("Batch is under retention and cannot be deleted yet"))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to abort batch: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"batchId": batchID,
+		"deleted": deleted,
+	}))
+}
+
+// ReportBatch flags a batch as abusive for operators to review
+func (c *BatchController) ReportBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	var req models.ReportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	err := c.batchService.ReportBatch(ctx.Request.Context(), batchID, req.Reason, ctx.ClientIP())
+	if errors.Is(err, batch.ErrInvalidReport) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+	if errors.Is(err, batch.ErrBatchNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Batch not found"))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to report batch: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(gin.H{"batchId": batchID}))
+}
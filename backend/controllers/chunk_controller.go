@@ -1,30 +1,93 @@
 package controllers
 
 import (
-	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"filesh/middleware"
 	"filesh/models"
+	"filesh/services/batch"
 	"filesh/services/chunk"
+	"filesh/services/storage"
+	"filesh/services/usage"
+	"filesh/utils"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+var (
+	// errUnsupportedEncoding is returned for request bodies in an encoding other than gzip
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+	// errInvalidEncoding is returned when a body doesn't decode as its declared encoding
+	errInvalidEncoding = errors.New("invalid encoded body")
+	// errBodyTooLarge is returned for requests declaring a body over the limit
+	errBodyTooLarge = errors.New("request body too large")
+)
+
+// downloadContentSecurityPolicy is sent with uploaded data so that, should a
+// browser render it inline, it runs sandboxed away from the app's origin
+const downloadContentSecurityPolicy = "sandbox"
+
+// multipartOverhead allows for the boundaries and part headers around the
+// file in a multipart upload form
+const multipartOverhead = 1 << 20
+
+const (
+	// chunkSHA256Header carries a chunk's SHA-256 digest, sent on upload and returned on download
+	chunkSHA256Header = "X-Chunk-SHA256"
+	// checksumVerifiedHeader is the trailer reporting whether a served chunk matched its digest
+	checksumVerifiedHeader = "X-Checksum-Verified"
+	// Client-side encryption parameters, stored on upload and returned on download
+	encryptionAlgoHeader = "X-Encryption-Algo"
+	encryptionIVHeader   = "X-Encryption-IV"
+	wrappedKeyHeader     = "X-Encryption-Wrapped-Key"
+)
+
 // ChunkController handles chunk-related API endpoints
 type ChunkController struct {
-	chunkService *chunk.Service
+	chunkService      *chunk.Service
+	batchService      *batch.Service
+	usageService      *usage.Service
+	downloadRateLimit int64
+	presignExpiry     time.Duration
 }
 
-// NewChunkController creates a new chunk controller
-func NewChunkController(chunkService *chunk.Service) *ChunkController {
+// NewChunkController creates a new chunk controller. downloadRateLimit caps
+// download bandwidth in bytes per second; zero means unlimited. presignExpiry
+// is how long presigned upload URLs stay valid.
+func NewChunkController(chunkService *chunk.Service, batchService *batch.Service, usageService *usage.Service, downloadRateLimit int64, presignExpiry time.Duration) *ChunkController {
 	return &ChunkController{
-		chunkService: chunkService,
+		chunkService:      chunkService,
+		batchService:      batchService,
+		usageService:      usageService,
+		downloadRateLimit: downloadRateLimit,
+		presignExpiry:     presignExpiry,
 	}
 }
 
-// UploadChunk handles file chunk uploads
+// UploadChunk handles file chunk uploads.
+//
+// Clients may send the expected SHA-256 of the chunk as a hex string in the
+// X-Chunk-SHA256 header; a mismatch removes the chunk and returns 422.
+//
+// Clients encrypting chunks may record how in X-Encryption-Algo,
+// X-Encryption-IV and X-Encryption-Wrapped-Key. The values are stored as
+// given and returned by CheckChunk and on download; the server never sees an
+// unwrapped key.
+//
+// By default an existing chunk is overwritten. Clients that want to avoid
+// clobbering a chunk on retry can send "If-None-Match: *", which makes the
+// server respond 412 Precondition Failed when the chunk already exists.
+// An explicit "?overwrite=true" always allows replacing the chunk.
 func (c *ChunkController) UploadChunk(ctx *gin.Context) {
 	// Extract batch ID and chunk index from URL parameters
 	batchID := ctx.Param("batchId")
@@ -43,9 +106,43 @@ func (c *ChunkController) UploadChunk(ctx *gin.Context) {
 		return
 	}
 
-	// Parse multipart form for the uploaded file - reduced memory usage
-	maxMemory := int64(32 * 1024 * 1024) // 32MB - optimized for chunk processing
-	if err := ctx.Request.ParseMultipartForm(maxMemory); err != nil {
+	// Refuse oversized bodies before anything is parsed or spooled to disk
+	if err := limitRequestBody(ctx, maxFileSize+multipartOverhead); err != nil {
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("Request body exceeds %d bytes", maxFileSize+multipartOverhead)))
+		return
+	}
+
+	// Read upload options from the request headers
+	opts, err := uploadOptions(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	// Keep the index within the chunk count the batch was created with
+	record, ok := c.prepareUpload(ctx, batchID, chunkIndex)
+	if !ok {
+		return
+	}
+	opts.Tags = record.Tags
+	opts.StorageClass = record.StorageClass
+
+	// A compressed body is inflated before the form is parsed
+	charged := ctx.Request.ContentLength
+	encoded, err := decodeRequestBody(ctx)
+	if err != nil {
+		respondEncodingError(ctx, err)
+		return
+	}
+
+	// Parse multipart form for the uploaded file, holding at most the router's
+	// MaxMultipartMemory in memory and spilling the rest to temp files
+	if _, err := ctx.MultipartForm(); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit)))
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Failed to parse form: %v", err)))
 		return
 	}
@@ -57,11 +154,20 @@ func (c *ChunkController) UploadChunk(ctx *gin.Context) {
 		return
 	}
 
+	// A batch-wide media type takes precedence over the type of each part
+	opts.ContentType = uploadContentType(file.Header.Get("Content-Type"))
+	if record.ContentType != "" {
+		opts.ContentType = record.ContentType
+	}
+
 	// Check for zero-sized file
 	if file.Size == 0 {
 		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Received empty chunk (zero bytes)"))
 		return
 	}
+	if encoded && !chargeDecodedSize(ctx, charged, file.Size) {
+		return
+	}
 
 	// Open uploaded file
 	src, err := file.Open()
@@ -71,21 +177,235 @@ func (c *ChunkController) UploadChunk(ctx *gin.Context) {
 	}
 	defer src.Close()
 
-	// Create a buffered reader with limited buffer size for memory efficiency
-	bufReader := bufio.NewReaderSize(src, 64*1024) // 64KB buffer
+	// Upload the chunk using chunk service; the file stays seekable so its
+	// digest can be worked out before it is stored
+	result, err := c.chunkService.UploadChunk(ctx.Request.Context(), batchID, chunkIndex, src, file.Size, opts)
+	if err != nil {
+		respondUploadError(ctx, chunkIndex, err)
+		return
+	}
 
-	// Upload the chunk using chunk service
-	result, err := c.chunkService.UploadChunk(ctx.Request.Context(), batchID, chunkIndex, bufReader, file.Size)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UploadChunkStream handles raw-body chunk uploads.
+//
+// Unlike UploadChunk, which expects a multipart form as sent by browsers, this
+// endpoint streams the request body straight to storage without buffering it
+// in memory or temp files, which makes it the preferred path for CLI clients:
+//
+//	curl -T chunk.bin -H "X-Chunk-SHA256: $(sha256sum chunk.bin | cut -d' ' -f1)" \
+//		http://host/api/upload/<batchId>/<chunkIndex>
+//
+// The request must carry Content-Length and, since the digest is stored with
+// the chunk before the body has been read, X-Chunk-SHA256. Overwrite
+// semantics match UploadChunk.
+//
+// Clients may pre-compress the body and send "Content-Encoding: gzip". The
+// chunk is stored decompressed, so it is first inflated to a temp file to
+// learn its size, which is what the upload quotas are charged; X-Chunk-SHA256
+// refers to the decompressed data.
+func (c *ChunkController) UploadChunkStream(ctx *gin.Context) {
+	// Extract batch ID and chunk index from URL parameters
+	batchID := ctx.Param("batchId")
+	chunkIndexStr := ctx.Param("chunkIndex")
+
+	// Validate batch ID
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	// Parse and validate chunk index
+	chunkIndex, err := c.chunkService.ParseChunkIndex(chunkIndexStr)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Upload failed: %v", err)))
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid chunk index: %v", err)))
+		return
+	}
+
+	// Refuse oversized bodies before anything is read
+	if err := limitRequestBody(ctx, maxFileSize); err != nil {
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("Request body exceeds %d bytes", maxFileSize)))
+		return
+	}
+
+	// Read upload options from the request headers
+	opts, err := uploadOptions(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	// Keep the index within the chunk count the batch was created with
+	record, ok := c.prepareUpload(ctx, batchID, chunkIndex)
+	if !ok {
+		return
+	}
+	opts.Tags = record.Tags
+	opts.StorageClass = record.StorageClass
+
+	opts.ContentType = uploadContentType(ctx.GetHeader("Content-Type"))
+	if record.ContentType != "" {
+		opts.ContentType = record.ContentType
+	}
+
+	// A compressed body is inflated to a temp file first, since storage needs
+	// the decompressed size up front
+	body := io.Reader(ctx.Request.Body)
+	size := ctx.Request.ContentLength
+	encoded, err := decodeRequestBody(ctx)
+	if err != nil {
+		respondEncodingError(ctx, err)
+		return
+	}
+	if encoded {
+		spool, err := spoolBody(ctx.Request.Body)
+		if err != nil {
+			respondEncodingError(ctx, err)
+			return
+		}
+		defer spool.Close()
+		if !chargeDecodedSize(ctx, size, spool.size) {
+			return
+		}
+		body, size = spool, spool.size
+	}
+
+	// The body size must be known up front so storage can stream it
+	if size < 0 {
+		ctx.JSON(http.StatusLengthRequired, models.NewErrorResponse("Content-Length header is required"))
+		return
+	}
+	if size == 0 {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Received empty chunk (zero bytes)"))
+		return
+	}
+
+	// Stream the request body directly to storage
+	result, err := c.chunkService.UploadChunk(ctx.Request.Context(), batchID, chunkIndex, body, size, opts)
+	if err != nil {
+		respondUploadError(ctx, chunkIndex, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, result)
 }
 
-// CheckChunk checks if a chunk exists
-func (c *ChunkController) CheckChunk(ctx *gin.Context) {
+// PresignUploads returns presigned POST forms so browsers can upload chunks
+// directly to storage. Storage refuses chunks over the maximum file size; the
+// quota and the batch's lock are checked when each upload is confirmed with
+// ConfirmChunk.
+func (c *ChunkController) PresignUploads(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	// Parse the list of indices to presign
+	var req models.PresignRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+	if len(req.Indices) == 0 || len(req.Indices) > chunk.MaxPresignIndices {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Between 1 and %d indices are required", chunk.MaxPresignIndices)))
+		return
+	}
+
+	// Don't hand out upload URLs for batches that are already gone
+	if err := c.batchService.CheckExpiry(ctx.Request.Context(), batchID); err != nil {
+		if errors.Is(err, batch.ErrBatchExpired) {
+			ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check batch expiry: %v", err)))
+		return
+	}
+
+	// Keep the indices within the chunk count the batch was created with
+	if _, ok := c.prepareUpload(ctx, batchID, req.Indices...); !ok {
+		return
+	}
+
+	result, err := c.chunkService.PresignUploads(ctx.Request.Context(), batchID, req.Indices, c.presignExpiry, maxFileSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to presign uploads: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// ConfirmChunk records a chunk uploaded directly to storage with a presigned
+// form. The upload bypassed the server, so it is only moved into place once
+// it passes the checks other uploads get up front: the batch's lock, the
+// maximum file size, the tenant's quota and, with "If-None-Match: *" or
+// without ?overwrite=true, overwrite protection. Refused uploads are deleted.
+func (c *ChunkController) ConfirmChunk(ctx *gin.Context) {
+	// Extract batch ID and chunk index from URL parameters
+	batchID := ctx.Param("batchId")
+	chunkIndexStr := ctx.Param("chunkIndex")
+
+	// Validate batch ID
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	// Parse and validate chunk index
+	chunkIndex, err := c.chunkService.ParseChunkIndex(chunkIndexStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid chunk index: %v", err)))
+		return
+	}
+
+	// Direct uploads bypass the server, so the batch's tags are applied on confirmation
+	record, ok := c.prepareUpload(ctx, batchID, chunkIndex)
+	if !ok {
+		c.chunkService.DiscardUpload(ctx.Request.Context(), batchID, chunkIndex)
+		return
+	}
+
+	// Without a pending upload, ConfirmChunk reports a chunk confirmed before
+	size, err := c.chunkService.PendingUploadSize(ctx.Request.Context(), batchID, chunkIndex)
+	if err != nil && !errors.Is(err, chunk.ErrChunkNotFound) {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to confirm chunk: %v", err)))
+		return
+	}
+	if err == nil {
+		if size > maxFileSize {
+			c.chunkService.DiscardUpload(ctx.Request.Context(), batchID, chunkIndex)
+			ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("Chunk exceeds %d bytes", maxFileSize)))
+			return
+		}
+		if err := c.usageService.CheckQuota(ctx.Request.Context(), size); err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				c.chunkService.DiscardUpload(ctx.Request.Context(), batchID, chunkIndex)
+				ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse("Storage quota exceeded"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check storage quota: %v", err)))
+			return
+		}
+	}
+
+	result, err := c.chunkService.ConfirmChunk(ctx.Request.Context(), batchID, chunkIndex, record.Tags, allowOverwrite(ctx))
+	if errors.Is(err, chunk.ErrChunkNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(fmt.Sprintf("Chunk %d has not been uploaded", chunkIndex)))
+		return
+	}
+	if err != nil {
+		respondUploadError(ctx, chunkIndex, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// PresignDownload returns a presigned GET URL so clients and CDNs can fetch a
+// chunk directly from storage
+func (c *ChunkController) PresignDownload(ctx *gin.Context) {
 	// Extract batch ID and chunk index from URL parameters
 	batchID := ctx.Param("batchId")
 	chunkIndexStr := ctx.Param("chunkIndex")
@@ -103,10 +423,56 @@ func (c *ChunkController) CheckChunk(ctx *gin.Context) {
 		return
 	}
 
+	// A presigned URL would outlive the batch's expiry check, so check it now
+	if err := c.batchService.CheckExpiry(ctx.Request.Context(), batchID); err != nil {
+		if errors.Is(err, batch.ErrBatchExpired) {
+			ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check batch expiry: %v", err)))
+		return
+	}
+
+	result, err := c.chunkService.PresignDownload(ctx.Request.Context(), batchID, chunkIndex, c.presignExpiry)
+	switch {
+	case errors.Is(err, chunk.ErrChunkNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(fmt.Sprintf("Chunk %d not found", chunkIndex)))
+	case errors.Is(err, storage.ErrPresignUnsupported):
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse("Chunk must be downloaded through the API"))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to presign download: %v", err)))
+	default:
+		ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+	}
+}
+
+// CheckChunk answers HEAD on the upload path, for clients resuming an upload.
+// As HEAD requires, the answer is in the status and headers alone: 200 with
+// Content-Length, ETag, X-Chunk-SHA256 and any encryption headers when the
+// chunk exists, 404 when it doesn't, and no body even on errors. Clients
+// wanting the same information as JSON use ChunkStatus.
+func (c *ChunkController) CheckChunk(ctx *gin.Context) {
+	// Extract batch ID and chunk index from URL parameters
+	batchID := ctx.Param("batchId")
+	chunkIndexStr := ctx.Param("chunkIndex")
+
+	// Validate batch ID
+	if batchID == "" {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	// Parse and validate chunk index
+	chunkIndex, err := c.chunkService.ParseChunkIndex(chunkIndexStr)
+	if err != nil {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
 	// Check if the chunk exists using chunk service
 	result, err := c.chunkService.CheckChunk(ctx.Request.Context(), batchID, chunkIndex)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check chunk: %v", err)))
+		ctx.Status(http.StatusInternalServerError)
 		return
 	}
 
@@ -115,6 +481,14 @@ func (c *ChunkController) CheckChunk(ctx *gin.Context) {
 		ctx.Header("Content-Length", fmt.Sprintf("%d", result.Size))
 		ctx.Header("ETag", fmt.Sprintf("\"%s\"", result.ETag))
 		ctx.Header("Last-Modified", result.Uploaded)
+		if result.SHA256 != "" {
+			ctx.Header("X-Chunk-SHA256", result.SHA256)
+		}
+		if enc := result.Encryption; enc != nil {
+			ctx.Header(encryptionAlgoHeader, enc.Algorithm)
+			ctx.Header(encryptionIVHeader, enc.IV)
+			ctx.Header(wrappedKeyHeader, enc.WrappedKey)
+		}
 		ctx.Status(http.StatusOK)
 	} else {
 		// Not found
@@ -122,7 +496,128 @@ func (c *ChunkController) CheckChunk(ctx *gin.Context) {
 	}
 }
 
-// DownloadChunk downloads a file chunk
+// ChunkStatus is the JSON counterpart of CheckChunk, for clients that want a
+// body. The status code matches CheckChunk's: 200 when the chunk exists and
+// 404 when it doesn't, with the chunk status as the body either way.
+func (c *ChunkController) ChunkStatus(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	chunkIndex, err := c.chunkService.ParseChunkIndex(ctx.Param("chunkIndex"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid chunk index: %v", err)))
+		return
+	}
+
+	result, err := c.chunkService.CheckChunk(ctx.Request.Context(), batchID, chunkIndex)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check chunk: %v", err)))
+		return
+	}
+
+	if !result.Exists {
+		ctx.JSON(http.StatusNotFound, result)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// CheckChunks checks the existence of several chunks in one request
+func (c *ChunkController) CheckChunks(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	// Parse the list of indices to check
+	var req models.ChunkCheckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+	if len(req.Indices) > chunk.MaxCheckIndices {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Too many indices (max %d)", chunk.MaxCheckIndices)))
+		return
+	}
+
+	// Check all chunks concurrently using chunk service
+	result, err := c.chunkService.CheckChunks(ctx.Request.Context(), batchID, req.Indices)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check chunks: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// HeadChunk answers HEAD on the download path with the same headers a GET
+// would serve and an empty body, so download managers can learn the size and
+// whether range requests are possible before fetching anything
+func (c *ChunkController) HeadChunk(ctx *gin.Context) {
+	// Extract batch ID and chunk index from URL parameters
+	batchID := ctx.Param("batchId")
+	chunkIndexStr := ctx.Param("chunkIndex")
+
+	// Validate batch ID
+	if batchID == "" {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	// Parse and validate chunk index
+	chunkIndex, err := c.chunkService.ParseChunkIndex(chunkIndexStr)
+	if err != nil {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	// Expired batches are gone, just like on GET
+	if err := c.batchService.CheckExpiry(ctx.Request.Context(), batchID); err != nil {
+		if errors.Is(err, batch.ErrBatchExpired) {
+			ctx.Status(http.StatusGone)
+			return
+		}
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	disposition, err := contentDisposition(ctx, fmt.Sprintf("%s_%d", batchID, chunkIndex))
+	if err != nil {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	info, err := c.chunkService.StatChunk(ctx.Request.Context(), batchID, chunkIndex)
+	if errors.Is(err, chunk.ErrChunkNotFound) {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if notModified(ctx, info) {
+		setValidatorHeaders(ctx, info)
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	setChunkHeaders(ctx, disposition, info)
+	ctx.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	ctx.Status(http.StatusOK)
+}
+
+// DownloadChunk downloads a file chunk. A single byte range may be requested
+// with the Range header, which is answered with 206 Partial Content.
+//
+// With ?verify=true the whole chunk is hashed as it is served and compared to
+// the digest recorded at upload; the outcome is sent in the
+// X-Checksum-Verified trailer.
 func (c *ChunkController) DownloadChunk(ctx *gin.Context) {
 	// Extract batch ID and chunk index from URL parameters
 	batchID := ctx.Param("batchId")
@@ -141,23 +636,632 @@ func (c *ChunkController) DownloadChunk(ctx *gin.Context) {
 		return
 	}
 
+	// Let the client choose between previewing and saving, and the saved name
+	disposition, err := contentDisposition(ctx, fmt.Sprintf("%s_%d", batchID, chunkIndex))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	// Refuse to serve expired batches even if storage hasn't deleted them yet
+	if err := c.batchService.CheckExpiry(ctx.Request.Context(), batchID); err != nil {
+		if errors.Is(err, batch.ErrBatchExpired) {
+			ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check batch expiry: %v", err)))
+		return
+	}
+
 	// Get chunk data using chunk service
 	reader, info, err := c.chunkService.DownloadChunk(ctx.Request.Context(), batchID, chunkIndex)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(fmt.Sprintf("Failed to download chunk: %v", err)))
+		if errors.Is(err, chunk.ErrChunkNotFound) {
+			ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Chunk not found"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to download chunk: %v", err)))
 		return
 	}
 	defer reader.Close()
 
+	// Let browsers and caches reuse a copy they already have
+	if notModified(ctx, info) {
+		setValidatorHeaders(ctx, info)
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	// Verification compares against the digest recorded at upload
+	verify := ctx.Query("verify") == "true"
+	if verify && info.SHA256 == "" {
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse("Chunk has no stored checksum to verify against"))
+		return
+	}
+
 	// Set appropriate headers
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_%d\"", batchID, chunkIndex))
-	ctx.Header("Content-Type", "application/octet-stream")
-	if info != nil {
-		ctx.Header("Content-Length", strconv.FormatInt(info.Size, 10))
-		ctx.Header("ETag", fmt.Sprintf("\"%s\"", info.ETag))
-		ctx.Header("Last-Modified", info.LastModified.Format(time.RFC1123))
+	setChunkHeaders(ctx, disposition, info)
+
+	// Serve only the requested part when a single valid range was asked for
+	status := http.StatusOK
+	length := info.Size
+	var body io.Reader = reader
+	// A stale If-Range means the client's partial copy is outdated, so send it
+	// all; verification needs the whole object too
+	rangeHeader := ctx.GetHeader("Range")
+	if verify || !ifRangeMatches(ctx, info) {
+		rangeHeader = ""
+	}
+	byteRange, err := utils.ParseByteRange(rangeHeader, info.Size)
+	if err != nil {
+		ctx.Header("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+		ctx.Status(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if byteRange != nil {
+		if _, err := io.CopyN(io.Discard, reader, byteRange.Start); err != nil {
+			ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to seek chunk: %v", err)))
+			return
+		}
+		status = http.StatusPartialContent
+		length = byteRange.Length
+		body = io.LimitReader(reader, byteRange.Length)
+		ctx.Header("Content-Range", byteRange.ContentRange(info.Size))
+	}
+
+	// Stream the file to the client, throttled if configured
+	throttled := utils.NewRateLimitedReader(ctx.Request.Context(), body, downloadRateLimit(ctx, c.downloadRateLimit))
+	if !verify {
+		ctx.DataFromReader(status, length, "application/octet-stream", throttled, nil)
+		return
+	}
+
+	// The result is only known once the body is sent, so it goes in a trailer,
+	// which needs chunked encoding and therefore no Content-Length
+	hasher := sha256.New()
+	ctx.Header("Trailer", checksumVerifiedHeader)
+	ctx.DataFromReader(status, -1, "application/octet-stream", io.TeeReader(throttled, hasher), nil)
+	ctx.Writer.Header().Set(checksumVerifiedHeader, strconv.FormatBool(hex.EncodeToString(hasher.Sum(nil)) == info.SHA256))
+}
+
+// PreviewChunk serves a small text or image chunk inline, so sharing pages
+// can render it without downloading and sniffing the whole object. Text is
+// truncated; other content types are refused with 415.
+func (c *ChunkController) PreviewChunk(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+
+	chunkIndex, err := c.chunkService.ParseChunkIndex(ctx.Param("chunkIndex"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid chunk index: %v", err)))
+		return
+	}
+
+	if err := c.batchService.CheckExpiry(ctx.Request.Context(), batchID); err != nil {
+		if errors.Is(err, batch.ErrBatchExpired) {
+			ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check batch expiry: %v", err)))
+		return
+	}
+
+	preview, err := c.chunkService.PreviewChunk(ctx.Request.Context(), batchID, chunkIndex)
+	switch {
+	case errors.Is(err, chunk.ErrChunkNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Chunk not found"))
+		return
+	case errors.Is(err, chunk.ErrPreviewUnsupported):
+		ctx.JSON(http.StatusUnsupportedMediaType, models.NewErrorResponse(fmt.Sprintf("No preview available: %v", err)))
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to preview chunk: %v", err)))
+		return
+	}
+	defer preview.Close()
+
+	// Previews are uploader-controlled content rendered on our origin, so
+	// sandbox them in case a browser renders one as something active
+	ctx.DataFromReader(http.StatusOK, preview.Size, preview.ContentType, preview, map[string]string{
+		"Content-Disposition":     utils.ContentDisposition("inline", fmt.Sprintf("%s_%d", batchID, chunkIndex)),
+		"Content-Security-Policy": downloadContentSecurityPolicy,
+		"X-Preview-Truncated":     strconv.FormatBool(preview.Truncated),
+	})
+}
+
+// DownloadFile streams one file of a multi-file batch by concatenating its
+// chunks in order. The file name is taken from the batch manifest. A single
+// Range is honoured, as for DownloadBatch.
+func (c *ChunkController) DownloadFile(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	name := strings.TrimPrefix(ctx.Param("name"), "/")
+	if batchID == "" || name == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID and file name are required"))
+		return
+	}
+
+	stream, err := c.batchService.FileStream(ctx.Request.Context(), batchID, name)
+	if err != nil {
+		respondStreamError(ctx, err)
+		return
+	}
+
+	c.serveStream(ctx, stream)
+}
+
+// DownloadBatch streams the whole batch as one file by reading its chunks in
+// order. A single Range is mapped onto the chunks it covers, so clients get
+// one resumable URL without the file being merged in storage first.
+func (c *ChunkController) DownloadBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	stream, err := c.batchService.BatchStream(ctx.Request.Context(), batchID)
+	if err != nil {
+		respondStreamError(ctx, err)
+		return
+	}
+
+	c.serveStream(ctx, stream)
+}
+
+// HeadBatch reports the size and ETag of the whole batch as served by
+// DownloadBatch, without reading any chunk, so download managers can plan
+// parallel range requests
+func (c *ChunkController) HeadBatch(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	stream, err := c.batchService.BatchStream(ctx.Request.Context(), batchID)
+	switch {
+	case errors.Is(err, batch.ErrBatchNotFound):
+		ctx.Status(http.StatusNotFound)
+		return
+	case errors.Is(err, batch.ErrBatchExpired):
+		ctx.Status(http.StatusGone)
+		return
+	case errors.Is(err, batch.ErrFileIncomplete):
+		ctx.Status(http.StatusConflict)
+		return
+	case err != nil:
+		ctx.Status(http.StatusInternalServerError)
+		return
 	}
 
-	// Stream the file to the client
-	ctx.DataFromReader(http.StatusOK, info.Size, "application/octet-stream", reader, nil)
-} 
\ No newline at end of file
+	disposition, err := contentDisposition(ctx, stream.Name)
+	if err != nil {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	ctx.Header("ETag", fmt.Sprintf("\"%s\"", stream.ETag()))
+	ctx.Header("Content-Disposition", disposition)
+	ctx.Header("Content-Type", downloadContentType(stream.ContentType))
+	ctx.Header("Content-Security-Policy", downloadContentSecurityPolicy)
+	ctx.Header("Accept-Ranges", "bytes")
+	ctx.Header("Content-Length", strconv.FormatInt(stream.Size, 10))
+	ctx.Status(http.StatusOK)
+}
+
+// DownloadArchive streams the chunks of a batch as a zip or, with
+// ?format=tar, a tar archive with one entry per chunk named by its index.
+// Tar archives have a known length; zip archives are sent without one.
+func (c *ChunkController) DownloadArchive(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+
+	archive, err := c.batchService.BatchArchive(ctx.Request.Context(), batchID, ctx.DefaultQuery("format", batch.ArchiveZip))
+	if err != nil {
+		if errors.Is(err, batch.ErrUnsupportedFormat) {
+			ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'format' must be 'zip' or 'tar'"))
+			return
+		}
+		respondStreamError(ctx, err)
+		return
+	}
+
+	disposition, err := contentDisposition(ctx, archive.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	// The archive is written on the fly, so a failure can only cut it short
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(archive.Write(ctx.Request.Context(), writer))
+	}()
+	defer reader.Close()
+
+	throttled := utils.NewRateLimitedReader(ctx.Request.Context(), reader, downloadRateLimit(ctx, c.downloadRateLimit))
+	ctx.DataFromReader(http.StatusOK, archive.Size(), archive.ContentType(), throttled, map[string]string{
+		"Content-Disposition": disposition,
+	})
+}
+
+// respondStreamError maps errors from opening a batch or file stream to HTTP responses
+func respondStreamError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, batch.ErrBatchNotFound), errors.Is(err, batch.ErrFileNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, batch.ErrBatchExpired):
+		ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+	case errors.Is(err, batch.ErrFileIncomplete):
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse(err.Error()))
+	default:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to open download: %v", err)))
+	}
+}
+
+// serveStream writes a chunk stream as an attachment, honouring a single Range
+// along with If-Range and If-None-Match against the stream's composite ETag
+func (c *ChunkController) serveStream(ctx *gin.Context, stream *batch.Stream) {
+	disposition, err := contentDisposition(ctx, stream.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	// Let browsers and caches reuse a copy they already have
+	etag := stream.ETag()
+	if inm := ctx.GetHeader("If-None-Match"); inm != "" && etagListMatches(inm, etag) {
+		ctx.Header("ETag", fmt.Sprintf("\"%s\"", etag))
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	// A stale If-Range means the client's partial copy is outdated, so send it
+	// all. The stream has no modification date, so only its ETag can match.
+	rangeHeader := ctx.GetHeader("Range")
+	if !ifRangeMatches(ctx, &storage.ObjectInfo{ETag: etag}) {
+		rangeHeader = ""
+	}
+	byteRange, err := utils.ParseByteRange(rangeHeader, stream.Size)
+	if err != nil {
+		ctx.Header("Content-Range", fmt.Sprintf("bytes */%d", stream.Size))
+		ctx.Status(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	status := http.StatusOK
+	offset, length := int64(0), stream.Size
+	if byteRange != nil {
+		status = http.StatusPartialContent
+		offset, length = byteRange.Start, byteRange.Length
+		ctx.Header("Content-Range", byteRange.ContentRange(stream.Size))
+	}
+
+	reader := stream.Open(ctx.Request.Context(), offset, length)
+	defer reader.Close()
+
+	ctx.Header("Content-Disposition", disposition)
+	ctx.Header("Content-Security-Policy", downloadContentSecurityPolicy)
+	ctx.Header("Accept-Ranges", "bytes")
+	ctx.Header("ETag", fmt.Sprintf("\"%s\"", etag))
+
+	// Stream the file to the client, throttled if configured
+	throttled := utils.NewRateLimitedReader(ctx.Request.Context(), reader, downloadRateLimit(ctx, c.downloadRateLimit))
+	ctx.DataFromReader(status, length, downloadContentType(stream.ContentType), throttled, nil)
+}
+
+// downloadContentType is the Content-Type to serve uploaded data with. Types a
+// browser could run are served as opaque bytes, as uploads from before they
+// were refused may still carry them.
+func downloadContentType(contentType string) string {
+	if contentType == "" || utils.ActiveContentType(contentType) {
+		return "application/octet-stream"
+	}
+	return contentType
+}
+
+// contentDisposition builds the Content-Disposition header for a download.
+// Clients may pick ?disposition=inline to preview in the browser instead of
+// saving, and ?filename= to override the default name.
+func contentDisposition(ctx *gin.Context, defaultName string) (string, error) {
+	dispositionType := ctx.DefaultQuery("disposition", "attachment")
+	if dispositionType != "attachment" && dispositionType != "inline" {
+		return "", fmt.Errorf("Query parameter 'disposition' must be 'inline' or 'attachment'")
+	}
+
+	filename := defaultName
+	if override := ctx.Query("filename"); override != "" {
+		filename = override
+	}
+	return utils.ContentDisposition(dispositionType, filename), nil
+}
+
+// setValidatorHeaders sets the cache validators for a chunk
+func setValidatorHeaders(ctx *gin.Context, info *storage.ObjectInfo) {
+	ctx.Header("ETag", fmt.Sprintf("\"%s\"", info.ETag))
+	ctx.Header("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+}
+
+// setChunkHeaders sets the entity headers shared by GET and HEAD chunk downloads
+func setChunkHeaders(ctx *gin.Context, disposition string, info *storage.ObjectInfo) {
+	ctx.Header("Content-Disposition", disposition)
+	ctx.Header("Content-Type", downloadContentType(info.ContentType))
+	ctx.Header("Content-Security-Policy", downloadContentSecurityPolicy)
+	ctx.Header("Accept-Ranges", "bytes")
+	if info.SHA256 != "" {
+		ctx.Header(chunkSHA256Header, info.SHA256)
+	}
+	if enc := chunk.EncryptionFromMetadata(info.UserMetadata); enc != nil {
+		ctx.Header(encryptionAlgoHeader, enc.Algorithm)
+		ctx.Header(encryptionIVHeader, enc.IV)
+		ctx.Header(wrappedKeyHeader, enc.WrappedKey)
+	}
+	setValidatorHeaders(ctx, info)
+}
+
+// uploadOptions builds chunk upload options from the request
+func uploadOptions(ctx *gin.Context) (chunk.UploadOptions, error) {
+	opts := chunk.UploadOptions{
+		Overwrite:      allowOverwrite(ctx),
+		ExpectedSHA256: ctx.GetHeader(chunkSHA256Header),
+	}
+
+	// Validate the digest format before accepting any data
+	if opts.ExpectedSHA256 != "" {
+		if decoded, err := hex.DecodeString(opts.ExpectedSHA256); err != nil || len(decoded) != sha256.Size {
+			return opts, fmt.Errorf("X-Chunk-SHA256 must be a hex-encoded SHA-256 digest")
+		}
+	}
+
+	// Encryption parameters are passed through untouched; the key stays wrapped
+	encryption := &models.EncryptionInfo{
+		Algorithm:  ctx.GetHeader(encryptionAlgoHeader),
+		IV:         ctx.GetHeader(encryptionIVHeader),
+		WrappedKey: ctx.GetHeader(wrappedKeyHeader),
+	}
+	if err := chunk.ValidateEncryption(encryption); err != nil {
+		return opts, err
+	}
+	if encryption.Algorithm != "" {
+		opts.Encryption = encryption
+	}
+
+	return opts, nil
+}
+
+// limitRequestBody caps how much of the request body can be read, so an
+// oversized upload fails before it is buffered rather than after. Requests
+// whose Content-Length is already over the limit get errBodyTooLarge.
+func limitRequestBody(ctx *gin.Context, limit int64) error {
+	if ctx.Request.ContentLength > limit {
+		return errBodyTooLarge
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+	return nil
+}
+
+// decodeRequestBody replaces a gzip-encoded request body with its
+// decompressed content, bounded by the maximum file size. It reports whether
+// the body was encoded; encodings other than gzip yield errUnsupportedEncoding.
+func decodeRequestBody(ctx *gin.Context) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(ctx.GetHeader("Content-Encoding"))) {
+	case "", "identity":
+		return false, nil
+	case "gzip", "x-gzip":
+	default:
+		return false, errUnsupportedEncoding
+	}
+
+	gz, err := gzip.NewReader(ctx.Request.Body)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", errInvalidEncoding, err)
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, gz, maxFileSize)
+	ctx.Request.ContentLength = -1
+	ctx.Request.Header.Del("Content-Encoding")
+	return true, nil
+}
+
+// chargeDecodedSize charges the upload quotas for the part of a decompressed
+// body its Content-Length didn't cover. It responds and returns false when
+// that takes the client over a quota.
+func chargeDecodedSize(ctx *gin.Context, charged, size int64) bool {
+	err := middleware.ChargeUpload(ctx, size-max(charged, 0))
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, middleware.ErrDailyQuotaExceeded):
+		ctx.JSON(http.StatusTooManyRequests, models.NewErrorResponse("Daily upload quota exceeded. Please try again later."))
+	case errors.Is(err, usage.ErrQuotaExceeded):
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse("Storage quota exceeded"))
+	default:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check storage quota: %v", err)))
+	}
+	return false
+}
+
+// spooledBody is a request body buffered to a temp file, removed on Close
+type spooledBody struct {
+	*os.File
+	size int64
+}
+
+// spoolBody copies a body of unknown length to a temp file and rewinds it
+func spoolBody(body io.Reader) (*spooledBody, error) {
+	file, err := os.CreateTemp("", "filesh-chunk-*")
+	if err != nil {
+		return nil, err
+	}
+	spool := &spooledBody{File: file}
+
+	spool.size, err = io.Copy(file, decodingReader{body})
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spool.Close()
+		return nil, err
+	}
+	return spool, nil
+}
+
+// decodingReader marks read errors of a decoded body as invalid encoding, so
+// they aren't mistaken for failures on our side
+type decodingReader struct {
+	io.Reader
+}
+
+func (r decodingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %w", errInvalidEncoding, err)
+	}
+	return n, err
+}
+
+// Close closes and removes the temp file
+func (s *spooledBody) Close() error {
+	err := s.File.Close()
+	os.Remove(s.Name())
+	return err
+}
+
+// respondEncodingError maps request body decoding errors to HTTP responses
+func respondEncodingError(ctx *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errUnsupportedEncoding):
+		ctx.JSON(http.StatusUnsupportedMediaType, models.NewErrorResponse("Unsupported Content-Encoding; only gzip is accepted"))
+	case errors.As(err, &maxBytesErr):
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("Decompressed chunk exceeds %d bytes", maxBytesErr.Limit)))
+	case errors.Is(err, errInvalidEncoding):
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid compressed body: %v", err)))
+	default:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to read upload: %v", err)))
+	}
+}
+
+// uploadContentType returns the declared media type of an upload, or an
+// empty string when it is missing or malformed
+func uploadContentType(contentType string) string {
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return ""
+	}
+	return contentType
+}
+
+// prepareUpload loads the batch ahead of a chunk upload and rejects chunk
+// indices beyond its expected chunk count and uploads to locked batches,
+// writing the error response. It returns the batch record, empty for batches
+// without metadata, and reports whether the request may proceed.
+func (c *ChunkController) prepareUpload(ctx *gin.Context, batchID string, indices ...int) (*models.BatchRecord, bool) {
+	record, err := c.batchService.PrepareUpload(ctx.Request.Context(), batchID, indices...)
+	if errors.Is(err, batch.ErrChunkOutOfRange) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid chunk index: %v", err)))
+		return nil, false
+	}
+	if errors.Is(err, batch.ErrBatchLocked) {
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse("Batch is finalized and no longer accepts uploads"))
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to read batch metadata: %v", err)))
+		return nil, false
+	}
+	if record == nil {
+		return &models.BatchRecord{}, true
+	}
+	return record, true
+}
+
+// allowOverwrite reports whether an upload may replace an existing chunk
+func allowOverwrite(ctx *gin.Context) bool {
+	if ctx.Query("overwrite") == "true" {
+		return true
+	}
+	return ctx.GetHeader("If-None-Match") != "*"
+}
+
+// respondUploadError maps chunk upload errors to HTTP responses
+func respondUploadError(ctx *gin.Context, chunkIndex int, err error) {
+	switch {
+	case errors.Is(err, chunk.ErrChunkExists):
+		ctx.JSON(http.StatusPreconditionFailed, models.NewErrorResponse(fmt.Sprintf("Chunk %d already exists; retry with ?overwrite=true to replace it", chunkIndex)))
+	case errors.Is(err, chunk.ErrChecksumMismatch):
+		ctx.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(fmt.Sprintf("Upload rejected: %v", err)))
+	case errors.Is(err, chunk.ErrDigestRequired):
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("X-Chunk-SHA256 header is required for raw-body uploads"))
+	default:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Upload failed: %v", err)))
+	}
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since against a chunk.
+// If-Modified-Since is ignored when If-None-Match is present, as per RFC 7232.
+func notModified(ctx *gin.Context, info *storage.ObjectInfo) bool {
+	if inm := ctx.GetHeader("If-None-Match"); inm != "" {
+		return etagListMatches(inm, info.ETag)
+	}
+
+	if ims := ctx.GetHeader("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates only have second precision
+		return !info.LastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagListMatches reports whether an If-None-Match list names the given
+// unquoted ETag. Comparison is weak, as If-None-Match requires.
+func etagListMatches(list, etag string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || strings.Trim(tag, "\"") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifRangeMatches evaluates If-Range against a chunk. It holds when the header
+// is absent or names the current strong ETag or exact modification date, as
+// per RFC 7233; weak ETags never match.
+func ifRangeMatches(ctx *gin.Context, info *storage.ObjectInfo) bool {
+	ifRange := strings.TrimSpace(ctx.GetHeader("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, "\"") {
+		return strings.Trim(ifRange, "\"") == info.ETag
+	}
+	if strings.HasPrefix(ifRange, "W/") {
+		return false
+	}
+
+	date, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	// HTTP dates only have second precision
+	return info.LastModified.Truncate(time.Second).Equal(date)
+}
+
+// downloadRateLimit returns the bandwidth cap for a download. Clients may
+// lower the configured cap with ?maxBps= but never raise it.
+func downloadRateLimit(ctx *gin.Context, configured int64) int64 {
+	requested, err := strconv.ParseInt(ctx.Query("maxBps"), 10, 64)
+	if err != nil || requested <= 0 {
+		return configured
+	}
+	if configured > 0 && requested > configured {
+		return configured
+	}
+	return requested
+}
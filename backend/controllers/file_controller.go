@@ -1,17 +1,25 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
+	"filesh/services/batch"
 	"filesh/services/storage"
 	"filesh/utils"
+	"filesh/utils/thumbnail"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,23 +28,68 @@ import (
 // Maximum file size (10GB - configurable via environment)
 var maxFileSize = getMaxFileSize()
 
+// FileOwnerTokenHeader carries the token returned when a file was uploaded
+const FileOwnerTokenHeader = "X-File-Owner-Token"
+
+const (
+	// metadataOwnerTokenHash is the user-metadata key holding the hash of a file's owner token
+	metadataOwnerTokenHash = "Owner-Token-Hash"
+	// metadataFilename is the user-metadata key holding a file's sanitized
+	// name, path-escaped since metadata travels in HTTP headers
+	metadataFilename = "Filename"
+	// maxExtensionLength bounds the extension kept in a file's object name
+	maxExtensionLength = 16
+	// defaultThumbnailWidth and maxThumbnailWidth bound the ?w= of thumbnail requests
+	defaultThumbnailWidth = 200
+	maxThumbnailWidth     = 1024
+	// maxThumbnailSource is the largest stored image a thumbnail is made from
+	maxThumbnailSource = 50 * 1024 * 1024
+	// thumbnailMarker separates a file ID from the width in cached thumbnail names
+	thumbnailMarker = "_thumb_"
+)
+
 // FileController handles direct file uploads and downloads
 type FileController struct {
-	storage storage.ObjectStorage
-	logger  *log.Logger
+	storage           storage.ObjectStorage
+	downloadRateLimit int64
+	publicBaseURL     string
+	logger            *log.Logger
 }
 
-// NewFileController creates a new file controller
-func NewFileController(storage storage.ObjectStorage) *FileController {
+// NewFileController creates a new file controller. downloadRateLimit caps
+// download bandwidth in bytes per second; zero means unlimited. publicBaseURL
+// is the origin download links point to; when empty the request's is used.
+func NewFileController(storage storage.ObjectStorage, downloadRateLimit int64, publicBaseURL string) *FileController {
 	return &FileController{
-		storage: storage,
-		logger:  utils.NewCustomLogger("FILE"),
+		storage:           storage,
+		downloadRateLimit: downloadRateLimit,
+		publicBaseURL:     publicBaseURL,
+		logger:            utils.NewCustomLogger("FILE"),
 	}
 }
 
 // UploadFile handles direct file upload with size limit
 func (c *FileController) UploadFile(ctx *gin.Context) {
-	// Get file from form data
+	// Refuse oversized bodies before the form is spooled to temp files
+	tooLarge := gin.H{"error": fmt.Sprintf("File too large. Maximum size is %d MB", maxFileSize/1024/1024)}
+	if err := limitRequestBody(ctx, maxFileSize+multipartOverhead); err != nil {
+		c.logger.Printf("Upload too large: %d bytes declared (max %d)", ctx.Request.ContentLength, maxFileSize)
+		ctx.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	// Parse the form within the router's memory bound, then get the file
+	if _, err := ctx.MultipartForm(); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.logger.Printf("Upload too large: body exceeds %d bytes", maxBytesErr.Limit)
+			ctx.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		c.logger.Printf("Error parsing upload form: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid file"})
+		return
+	}
 	file, header, err := ctx.Request.FormFile("file")
 	if err != nil {
 		c.logger.Printf("Error getting uploaded file: %v", err)
@@ -57,15 +110,35 @@ func (c *FileController) UploadFile(ctx *gin.Context) {
 	// Generate unique file ID
 	fileID := uuid.New().String()
 	
-	// Get original filename and extension
-	originalFilename := header.Filename
-	extension := filepath.Ext(originalFilename)
+	// Crafted names must not reach object names or headers as sent
+	originalFilename, err := utils.CleanFilename(header.Filename)
+	if err != nil {
+		c.logger.Printf("Rejected upload filename %q: %v", header.Filename, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	extension := objectExtension(originalFilename)
 	
 	// Object path in storage
 	objectPath := fmt.Sprintf("files/%s%s", fileID, extension)
 	
-	// Upload file to storage
-	err = c.storage.UploadObject(context.Background(), objectPath, file, header.Size)
+	// Only the uploader gets the token needed to delete the file
+	ownerToken, ownerTokenHash, err := batch.NewOwnerToken()
+	if err != nil {
+		c.logger.Printf("Error generating owner token: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	// Upload file to storage, keeping the declared type for thumbnails
+	info, err := c.storage.UploadObjectWithOptions(ctx.Request.Context(), objectPath, file, header.Size, storage.UploadOptions{
+		ContentType: uploadContentType(header.Header.Get("Content-Type")),
+		Metadata: map[string]string{
+			metadataOwnerTokenHash: ownerTokenHash,
+			metadataFilename:       url.PathEscape(originalFilename),
+		},
+		Retain: true,
+	})
 	if err != nil {
 		c.logger.Printf("Error uploading file to storage: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
@@ -76,11 +149,64 @@ func (c *FileController) UploadFile(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"fileId":       fileID,
 		"filename":     originalFilename,
-		"size":         header.Size,
+		"size":         info.Size,
+		"etag":         info.ETag,
 		"downloadPath": fmt.Sprintf("/api/file/%s", fileID),
+		"downloadUrl":  fmt.Sprintf("%s/api/file/%s", externalBaseURL(ctx, c.publicBaseURL), fileID),
+		"ownerToken":   ownerToken,
 	})
 }
 
+// DeleteFile removes an uploaded file and its cached thumbnails. The owner
+// token returned at upload must be sent in the X-File-Owner-Token header.
+func (c *FileController) DeleteFile(ctx *gin.Context) {
+	fileID := ctx.Param("fileId")
+
+	objectPath, err := c.findFile(ctx.Request.Context(), fileID)
+	if err != nil {
+		c.respondStorageError(ctx, err, "Failed to look up file")
+		return
+	}
+
+	info, err := c.storage.GetObjectInfo(ctx.Request.Context(), objectPath)
+	if err != nil {
+		c.logger.Printf("Error getting object info %s: %v", objectPath, err)
+		c.respondStorageError(ctx, err, "Failed to retrieve file info")
+		return
+	}
+
+	// Files uploaded before owner tokens existed can only expire
+	tokenHash := info.UserMetadata[metadataOwnerTokenHash]
+	if tokenHash == "" {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "File has no owner token and cannot be deleted"})
+		return
+	}
+	token := ctx.GetHeader(FileOwnerTokenHeader)
+	if token == "" || subtle.ConstantTimeCompare([]byte(batch.HashOwnerToken(token)), []byte(tokenHash)) != 1 {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "A valid " + FileOwnerTokenHeader + " header is required"})
+		return
+	}
+
+	if err := c.storage.DeleteObject(ctx.Request.Context(), objectPath); err != nil {
+		c.logger.Printf("Error deleting file %s: %v", objectPath, err)
+		c.respondStorageError(ctx, err, "Failed to delete file")
+		return
+	}
+
+	// Stale thumbnails would otherwise outlive the file until expiry
+	thumbnails, err := c.storage.ListObjects(ctx.Request.Context(), "files/"+fileID+thumbnailMarker)
+	if err != nil {
+		c.logger.Printf("Error listing thumbnails of %s: %v", fileID, err)
+	}
+	for _, thumb := range thumbnails {
+		if err := c.storage.DeleteObject(ctx.Request.Context(), thumb.Name); err != nil {
+			c.logger.Printf("Error deleting thumbnail %s: %v", thumb.Name, err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"fileId": fileID, "deleted": true})
+}
+
 // DownloadFile handles file download by ID
 func (c *FileController) DownloadFile(ctx *gin.Context) {
 	fileID := ctx.Param("fileId")
@@ -90,48 +216,210 @@ func (c *FileController) DownloadFile(ctx *gin.Context) {
 	}
 	
 	// Find the file in storage
-	// First we need to get the file extension by listing objects with this prefix
-	objectsInfo, err := c.storage.ListObjects(context.Background(), "files/" + fileID)
-	if err != nil || len(objectsInfo) == 0 {
+	objectPath, err := c.findFile(ctx.Request.Context(), fileID)
+	if err != nil {
 		c.logger.Printf("Error finding file %s: %v", fileID, err)
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		c.respondStorageError(ctx, err, "Failed to look up file")
 		return
 	}
 	
-	// Get the first matching object
-	objectPath := objectsInfo[0].Name
-	
 	// Get file from storage
-	objectInfo, err := c.storage.GetObjectInfo(context.Background(), objectPath)
+	objectInfo, err := c.storage.GetObjectInfo(ctx.Request.Context(), objectPath)
 	if err != nil {
 		c.logger.Printf("Error getting object info %s: %v", objectPath, err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file info"})
+		c.respondStorageError(ctx, err, "Failed to retrieve file info")
 		return
 	}
 	
-	reader, err := c.storage.DownloadObject(context.Background(), objectPath)
+	reader, err := c.storage.DownloadObject(ctx.Request.Context(), objectPath)
 	if err != nil {
 		c.logger.Printf("Error downloading file %s: %v", objectPath, err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		c.respondStorageError(ctx, err, "Failed to retrieve file")
 		return
 	}
 	defer reader.Close()
 	
 	// Fallback to fileID + extension if metadata is missing
-	originalFilename := filepath.Base(objectPath)
+	filename := filepath.Base(objectPath)
+	if stored, err := url.PathUnescape(objectInfo.UserMetadata[metadataFilename]); err == nil && stored != "" {
+		filename = stored
+	}
+	disposition, err := contentDisposition(ctx, filename)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	
 	// Default content type
 	contentType := "application/octet-stream"
 	
 	// Set appropriate headers for download
 	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", originalFilename))
+	ctx.Header("Content-Disposition", disposition)
 	ctx.Header("Content-Type", contentType)
 	ctx.Header("Content-Length", fmt.Sprintf("%d", objectInfo.Size))
 	
 	// Stream file to response
 	ctx.Status(http.StatusOK)
-	io.Copy(ctx.Writer, reader)
+	io.Copy(ctx.Writer, utils.NewRateLimitedReader(ctx.Request.Context(), reader, downloadRateLimit(ctx, c.downloadRateLimit)))
+}
+
+// GetThumbnail returns a JPEG thumbnail of an uploaded image, ?w= pixels
+// wide. Thumbnails are generated once per width and cached in storage.
+func (c *FileController) GetThumbnail(ctx *gin.Context) {
+	fileID := ctx.Param("fileId")
+	if _, err := uuid.Parse(fileID); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	width, err := strconv.Atoi(ctx.DefaultQuery("w", strconv.Itoa(defaultThumbnailWidth)))
+	if err != nil || width < 1 || width > maxThumbnailWidth {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Width must be between 1 and %d", maxThumbnailWidth)})
+		return
+	}
+
+	// Serve a previously generated thumbnail when there is one
+	thumbPath := fmt.Sprintf("files/%s%s%d", fileID, thumbnailMarker, width)
+	if c.serveCachedThumbnail(ctx, thumbPath) {
+		return
+	}
+
+	objectPath, err := c.findFile(ctx.Request.Context(), fileID)
+	if err != nil {
+		c.respondStorageError(ctx, err, "Failed to look up file")
+		return
+	}
+	info, err := c.storage.GetObjectInfo(ctx.Request.Context(), objectPath)
+	if err != nil {
+		c.logger.Printf("Error getting object info %s: %v", objectPath, err)
+		c.respondStorageError(ctx, err, "Failed to retrieve file info")
+		return
+	}
+
+	// Files uploaded before types were recorded fall back to their extension
+	contentType := info.ContentType
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = mime.TypeByExtension(filepath.Ext(objectPath))
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File is not an image"})
+		return
+	}
+	if info.Size > maxThumbnailSource {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Image is too large to thumbnail"})
+		return
+	}
+
+	reader, err := c.storage.DownloadObject(ctx.Request.Context(), objectPath)
+	if err != nil {
+		c.logger.Printf("Error downloading file %s: %v", objectPath, err)
+		c.respondStorageError(ctx, err, "Failed to retrieve file")
+		return
+	}
+	defer reader.Close()
+
+	data, err := thumbnail.Generate(reader, width)
+	switch {
+	case errors.Is(err, thumbnail.ErrUnsupported):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Image format is not supported (JPEG, PNG, GIF and WebP are)"})
+		return
+	case errors.Is(err, thumbnail.ErrTooLarge):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Image is too large to thumbnail"})
+		return
+	case err != nil:
+		c.logger.Printf("Error generating thumbnail of %s: %v", objectPath, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate thumbnail"})
+		return
+	}
+
+	// A failed cache write only costs a regeneration next time
+	_, err = c.storage.UploadObjectWithOptions(ctx.Request.Context(), thumbPath, bytes.NewReader(data), int64(len(data)), storage.UploadOptions{ContentType: "image/jpeg"})
+	if err != nil {
+		c.logger.Printf("Error caching thumbnail %s: %v", thumbPath, err)
+	}
+
+	c.serveThumbnail(ctx, bytes.NewReader(data), int64(len(data)))
+}
+
+// serveCachedThumbnail writes a stored thumbnail and reports whether there was one
+func (c *FileController) serveCachedThumbnail(ctx *gin.Context, thumbPath string) bool {
+	info, err := c.storage.GetObjectInfo(ctx.Request.Context(), thumbPath)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			c.logger.Printf("Error checking cached thumbnail %s, regenerating: %v", thumbPath, err)
+		}
+		return false
+	}
+	reader, err := c.storage.DownloadObject(ctx.Request.Context(), thumbPath)
+	if err != nil {
+		c.logger.Printf("Error reading cached thumbnail %s, regenerating: %v", thumbPath, err)
+		return false
+	}
+	defer reader.Close()
+
+	c.serveThumbnail(ctx, reader, info.Size)
+	return true
+}
+
+// serveThumbnail writes a JPEG thumbnail; file IDs are never reused, so it may be cached
+func (c *FileController) serveThumbnail(ctx *gin.Context, reader io.Reader, size int64) {
+	ctx.DataFromReader(http.StatusOK, size, "image/jpeg", reader, map[string]string{
+		"Cache-Control": "public, max-age=86400, immutable",
+	})
+}
+
+// respondStorageError answers 404 when the file doesn't exist, 403 when it is
+// still under retention and 500 with the given message for any other storage
+// failure
+func (c *FileController) respondStorageError(ctx *gin.Context, err error, message string) {
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if errors.Is(err, storage.ErrObjectLocked) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "File is under retention and cannot be deleted yet"})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// findFile returns the object path of an uploaded file, whose extension
+// isn't known from its ID. Only the ID itself plus an extension matches, so a
+// partial ID can't resolve to someone else's file and cached thumbnails,
+// which share the prefix, are skipped. A missing file yields storage.ErrNotFound.
+func (c *FileController) findFile(ctx context.Context, fileID string) (string, error) {
+	if _, err := uuid.Parse(fileID); err != nil {
+		return "", fmt.Errorf("%w: invalid file ID %q", storage.ErrNotFound, fileID)
+	}
+
+	prefix := "files/" + fileID
+	objects, err := c.storage.ListObjects(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	for _, obj := range objects {
+		rest := strings.TrimPrefix(obj.Name, prefix)
+		if rest == "" || strings.HasPrefix(rest, ".") {
+			return obj.Name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no object for file %s", storage.ErrNotFound, fileID)
+}
+
+// objectExtension returns the extension of an uploaded file's name for use in
+// its object name, or nothing when it is long or has unusual characters
+func objectExtension(filename string) string {
+	extension := filepath.Ext(filename)
+	if len(extension) < 2 || len(extension) > maxExtensionLength {
+		return ""
+	}
+	for _, r := range extension[1:] {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return ""
+		}
+	}
+	return extension
 }
 
 // getMaxFileSize returns the maximum file size from environment or default (10GB)
@@ -1,29 +1,96 @@
 package controllers
 
 import (
+	"context"
+	"filesh/services/storage"
+	"filesh/version"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// readyTimeout bounds the storage check made by the readiness probe
+const readyTimeout = 5 * time.Second
+
 // HealthController handles health check endpoints
 type HealthController struct {
-	version string
+	build        version.Info
+	storage      storage.ObjectStorage
+	startedAt    time.Time
+	shuttingDown atomic.Bool
 }
 
-// NewHealthController creates a new health controller
-func NewHealthController(version string) *HealthController {
+// NewHealthController creates a new health controller reporting the given build
+func NewHealthController(build version.Info, storage storage.ObjectStorage) *HealthController {
 	return &HealthController{
-		version: version,
+		build:     build,
+		storage:   storage,
+		startedAt: time.Now(),
 	}
 }
 
+// ShuttingDown makes the readiness probe fail so load balancers stop
+// routing new requests here while in-flight ones finish
+func (c *HealthController) ShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
 // HealthCheck returns the health status of the API
 func (c *HealthController) HealthCheck(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
 		"timestamp": time.Now().Format(time.RFC3339),
-		"version":   c.version,
+		"version":   c.build.Version,
+		"commit":    c.build.Commit,
+		"buildDate": c.build.BuildDate,
+	})
+}
+
+// Version reports exactly which build is running
+func (c *HealthController) Version(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.build)
+}
+
+// Live reports that the process is running. It never touches storage, so a
+// storage outage doesn't get the instance restarted.
+func (c *HealthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Ready reports whether the instance should receive traffic: storage must be
+// reachable and the server must not be shutting down
+func (c *HealthController) Ready(ctx *gin.Context) {
+	uptime := time.Since(c.startedAt).Round(time.Second)
+
+	if c.shuttingDown.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":        "shutting down",
+			"uptimeSeconds": int64(uptime.Seconds()),
+			"version":       c.build.Version,
+		})
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readyTimeout)
+	defer cancel()
+
+	if err := c.storage.Ping(checkCtx); err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":        "unavailable",
+			"storage":       err.Error(),
+			"uptimeSeconds": int64(uptime.Seconds()),
+			"version":       c.build.Version,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":        "ready",
+		"storage":       "ok",
+		"uptime":        uptime.String(),
+		"uptimeSeconds": int64(uptime.Seconds()),
+		"version":       c.build.Version,
 	})
-} 
\ No newline at end of file
+}
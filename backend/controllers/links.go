@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// externalBaseURL returns the origin to put in links handed to clients: the
+// configured public base URL, or else the scheme and host the request came
+// in on, as forwarded by a proxy when there is one
+func externalBaseURL(ctx *gin.Context, configured string) string {
+	if configured != "" {
+		return strings.TrimRight(configured, "/")
+	}
+
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	host := ctx.Request.Host
+	if forwarded := ctx.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host, _, _ = strings.Cut(forwarded, ",")
+		host = strings.TrimSpace(host)
+	}
+	return scheme + "://" + host
+}
+
+// shareLink returns the frontend link that opens a batch
+func shareLink(baseURL, batchID string) string {
+	return baseURL + "/?batch=" + url.QueryEscape(batchID)
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"errors"
+	"filesh/models"
+	"filesh/services/multipart"
+	"filesh/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MultipartController handles multipart upload session endpoints
+type MultipartController struct {
+	multipartService *multipart.Service
+}
+
+// NewMultipartController creates a new multipart controller
+func NewMultipartController(multipartService *multipart.Service) *MultipartController {
+	return &MultipartController{
+		multipartService: multipartService,
+	}
+}
+
+// CreateUpload starts a new multipart upload session
+func (c *MultipartController) CreateUpload(ctx *gin.Context) {
+	// The request body is optional
+	var req models.CreateMultipartRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+
+	result, err := c.multipartService.CreateUpload(ctx.Request.Context(), req.Filename)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to start multipart upload: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// UploadPart streams a single part of a multipart upload
+func (c *MultipartController) UploadPart(ctx *gin.Context) {
+	uploadID := ctx.Param("uploadId")
+	if uploadID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Upload ID is required"))
+		return
+	}
+
+	// Parse and validate part number
+	partNumber, err := strconv.Atoi(ctx.Param("partNumber"))
+	if err != nil || partNumber < multipart.MinPartNumber || partNumber > multipart.MaxPartNumber {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Part number must be between %d and %d", multipart.MinPartNumber, multipart.MaxPartNumber)))
+		return
+	}
+
+	// The part size must be known up front so storage can stream it
+	size := ctx.Request.ContentLength
+	if size < 0 {
+		ctx.JSON(http.StatusLengthRequired, models.NewErrorResponse("Content-Length header is required"))
+		return
+	}
+	// No part can be larger than the whole file may be; the total is checked on completion
+	if err := limitRequestBody(ctx, maxFileSize); err != nil {
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("File too large. Maximum size is %d MB", maxFileSize/1024/1024)))
+		return
+	}
+
+	result, err := c.multipartService.UploadPart(ctx.Request.Context(), uploadID, partNumber, ctx.Request.Body, size)
+	if errors.Is(err, multipart.ErrSessionNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Multipart upload not found"))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to upload part: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// CompleteUpload assembles the uploaded parts into the final file
+func (c *MultipartController) CompleteUpload(ctx *gin.Context) {
+	uploadID := ctx.Param("uploadId")
+	if uploadID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Upload ID is required"))
+		return
+	}
+
+	var req models.CompleteMultipartRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+	if len(req.Parts) == 0 {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("At least one part is required"))
+		return
+	}
+
+	result, err := c.multipartService.CompleteUpload(ctx.Request.Context(), uploadID, req.Parts, maxFileSize)
+	switch {
+	case errors.Is(err, multipart.ErrSessionNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Multipart upload not found"))
+		return
+	case errors.Is(err, multipart.ErrFileTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("File too large. Maximum size is %d MB", maxFileSize/1024/1024)))
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to complete multipart upload: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// AbortUpload cancels a multipart upload and discards its parts
+func (c *MultipartController) AbortUpload(ctx *gin.Context) {
+	uploadID := ctx.Param("uploadId")
+	if uploadID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Upload ID is required"))
+		return
+	}
+
+	err := c.multipartService.AbortUpload(ctx.Request.Context(), uploadID)
+	if errors.Is(err, multipart.ErrSessionNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("Multipart upload not found"))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to abort multipart upload: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{"uploadId": uploadID}))
+}
+
+// UploadRange stores one piece of a large file sent as
+// "Content-Range: bytes start-end/total" to PUT /api/file/:fileId, where
+// the client picks a new UUID as the file ID. Pieces must arrive in order;
+// every piece but the last must be at least 5MB, and ?filename= on the first
+// one sets the file's extension. Incomplete uploads answer 202 and the final
+// piece 200. Sending "bytes */total" with no body reports progress instead,
+// so an interrupted upload can resume; a piece out of order gets 409 with the
+// same report. Progress is also given in a Range header.
+func (c *MultipartController) UploadRange(ctx *gin.Context) {
+	fileID := ctx.Param("fileId")
+
+	byteRange, total, err := utils.ParseContentRange(ctx.GetHeader("Content-Range"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Invalid Content-Range header: %v", err)))
+		return
+	}
+	if total > maxFileSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(fmt.Sprintf("File too large. Maximum size is %d MB", maxFileSize/1024/1024)))
+		return
+	}
+
+	// Without a range the client is asking how far the upload got
+	if byteRange == nil {
+		status, err := c.multipartService.RangeStatus(ctx.Request.Context(), fileID)
+		if errors.Is(err, multipart.ErrSessionNotFound) {
+			ctx.JSON(http.StatusNotFound, models.NewErrorResponse("No upload in progress for this file"))
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to read upload progress: %v", err)))
+			return
+		}
+		respondRangeStatus(ctx, http.StatusAccepted, status)
+		return
+	}
+
+	// The piece must be exactly the announced range so storage can stream it
+	if ctx.Request.ContentLength != byteRange.Length {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Content-Length must match the length of the Content-Range"))
+		return
+	}
+
+	status, err := c.multipartService.UploadRange(ctx.Request.Context(), fileID, ctx.Query("filename"), byteRange, total, ctx.Request.Body)
+	switch {
+	case errors.Is(err, multipart.ErrRangeMismatch):
+		respondRangeStatus(ctx, http.StatusConflict, status)
+	case errors.Is(err, multipart.ErrSessionNotFound):
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse("No upload in progress for this file; the first piece must start at byte 0"))
+	case errors.Is(err, multipart.ErrFileExists):
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse("File ID is already in use"))
+	case errors.Is(err, multipart.ErrInvalidRange):
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to upload range: %v", err)))
+	case status.Complete:
+		respondRangeStatus(ctx, http.StatusOK, status)
+	default:
+		respondRangeStatus(ctx, http.StatusAccepted, status)
+	}
+}
+
+// respondRangeStatus reports the progress of a range upload, including the
+// bytes received so far as a Range header once there are any
+func respondRangeStatus(ctx *gin.Context, code int, status *models.RangeUploadStatus) {
+	if status.Received > 0 {
+		ctx.Header("Range", fmt.Sprintf("bytes=0-%d", status.Received-1))
+	}
+	ctx.JSON(code, models.NewSuccessResponse(status))
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"filesh/middleware"
+	"filesh/models"
+	"filesh/services/batch"
+	"filesh/services/chunk"
+	"filesh/services/storage"
+	"filesh/services/usage"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newQuotaRouter serves streaming chunk uploads behind a per-IP daily quota
+// and a storage quota for tenant-a
+func newQuotaRouter(t *testing.T, dailyQuota, tenantQuota int64) (*gin.Engine, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logger := log.New(io.Discard, "", 0)
+	objectStorage := storage.NewMemoryStorage()
+	batchService := batch.NewService(objectStorage, batch.Options{}, logger)
+	chunkService := chunk.NewService(objectStorage, false, logger)
+	usageService := usage.NewService(objectStorage, map[string]int64{"tenant-a": tenantQuota}, time.Minute, logger)
+	batchController := NewBatchController(batchService, chunkService, nil, "", 0, 0)
+	chunkController := NewChunkController(chunkService, batchService, usageService, 0, 0)
+
+	r := gin.New()
+	api := r.Group("/api", middleware.TenantAuth(map[string]string{"key-a": "tenant-a"}))
+	api.POST("/batch", batchController.CreateBatch)
+	api.PUT("/upload/:batchId/:chunkIndex", middleware.NewUploadQuota(dailyQuota).Limit(), middleware.TenantQuota(usageService), chunkController.UploadChunkStream)
+
+	w := serve(r, http.MethodPost, "/api/batch", "key-a", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("creating batch: got %d: %s", w.Code, w.Body)
+	}
+	var created models.BatchMetadata
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decoding batch: %v", err)
+	}
+	return r, created.ID
+}
+
+// putGzip uploads data as a gzip-encoded chunk
+func putGzip(r *gin.Engine, path string, data []byte) *httptest.ResponseRecorder {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(data)
+	gz.Close()
+
+	req := httptest.NewRequest(http.MethodPut, path, &compressed)
+	req.Header.Set("X-API-Key", "key-a")
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressedUploadChargesDecodedSize(t *testing.T) {
+	data := make([]byte, 100000)
+
+	for _, tc := range []struct {
+		name                    string
+		dailyQuota, tenantQuota int64
+		want                    int
+	}{
+		{"within quotas", 200000, 200000, http.StatusOK},
+		{"daily quota", 50000, 200000, http.StatusTooManyRequests},
+		{"tenant quota", 200000, 50000, http.StatusRequestEntityTooLarge},
+	} {
+		r, batchID := newQuotaRouter(t, tc.dailyQuota, tc.tenantQuota)
+		if w := putGzip(r, "/api/upload/"+batchID+"/0", data); w.Code != tc.want {
+			t.Errorf("%s: got %d, want %d: %s", tc.name, w.Code, tc.want, w.Body)
+		}
+	}
+}
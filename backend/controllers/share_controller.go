@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"filesh/models"
+	"filesh/services/batch"
+	"filesh/utils/qrcode"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Bounds for the pixel size of generated QR codes
+	minQRSize     = 64
+	maxQRSize     = 1024
+	defaultQRSize = 256
+)
+
+// ShareController handles endpoints that help share batches
+type ShareController struct {
+	batchService  *batch.Service
+	publicBaseURL string
+}
+
+// NewShareController creates a new share controller. publicBaseURL is the
+// origin of the frontend that share links point to; empty disables QR codes.
+func NewShareController(batchService *batch.Service, publicBaseURL string) *ShareController {
+	return &ShareController{
+		batchService:  batchService,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// GetQRCode returns a QR code of a batch's share link as PNG, or as SVG with
+// ?format=svg. The pixel size is set with ?size= (default 256).
+//
+// The encryption key never reaches the server, so the encoded link only
+// identifies the batch; the recipient still needs the key to decrypt it.
+func (c *ShareController) GetQRCode(ctx *gin.Context) {
+	batchID := ctx.Param("batchId")
+	if batchID == "" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Batch ID is required"))
+		return
+	}
+	if c.publicBaseURL == "" {
+		ctx.JSON(http.StatusNotImplemented, models.NewErrorResponse("No public base URL is configured for share links"))
+		return
+	}
+
+	size, err := strconv.Atoi(ctx.DefaultQuery("size", strconv.Itoa(defaultQRSize)))
+	if err != nil || size < minQRSize || size > maxQRSize {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Query parameter 'size' must be between %d and %d", minQRSize, maxQRSize)))
+		return
+	}
+	format := ctx.DefaultQuery("format", "png")
+	if format != "png" && format != "svg" {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse("Query parameter 'format' must be 'png' or 'svg'"))
+		return
+	}
+
+	// Don't hand out links to batches that are already gone
+	if err := c.batchService.CheckExpiry(ctx.Request.Context(), batchID); err != nil {
+		if errors.Is(err, batch.ErrBatchExpired) {
+			ctx.JSON(http.StatusGone, models.NewErrorResponse("Batch has expired"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to check batch expiry: %v", err)))
+		return
+	}
+
+	shareURL := shareLink(c.publicBaseURL, batchID)
+	code, err := qrcode.Encode([]byte(shareURL))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Failed to encode share link: %v", err)))
+		return
+	}
+
+	if format == "svg" {
+		ctx.Data(http.StatusOK, "image/svg+xml", code.SVG(size))
+		return
+	}
+
+	image, err := code.PNG(size)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.Data(http.StatusOK, "image/png", image)
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"filesh/models"
+	"filesh/services/stats"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsController handles operator statistics endpoints
+type StatsController struct {
+	statsService *stats.Service
+}
+
+// NewStatsController creates a new statistics controller
+func NewStatsController(statsService *stats.Service) *StatsController {
+	return &StatsController{
+		statsService: statsService,
+	}
+}
+
+// GetStats returns bucket-wide storage statistics
+func (c *StatsController) GetStats(ctx *gin.Context) {
+	result, err := c.statsService.GetStats(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to compute stats: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// GetCapacity returns the bytes stored across the instance against its soft cap
+func (c *StatsController) GetCapacity(ctx *gin.Context) {
+	result, err := c.statsService.Capacity(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to compute capacity: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
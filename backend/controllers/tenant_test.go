@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"filesh/middleware"
+	"filesh/models"
+	"filesh/services/batch"
+	"filesh/services/chunk"
+	"filesh/services/storage"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTenantRouter serves batch creation, batch info and chunk up- and
+// downloads over in-memory storage, with tenants resolved from API keys
+func newTenantRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logger := log.New(io.Discard, "", 0)
+	objectStorage := storage.NewMemoryStorage()
+	batchService := batch.NewService(objectStorage, batch.Options{}, logger)
+	chunkService := chunk.NewService(objectStorage, false, logger)
+	batchController := NewBatchController(batchService, chunkService, nil, "", 0, 0)
+	chunkController := NewChunkController(chunkService, batchService, nil, 0, 0)
+
+	r := gin.New()
+	api := r.Group("/api", middleware.TenantAuth(map[string]string{
+		"key-a": "tenant-a",
+		"key-b": "tenant-b",
+	}))
+	api.POST("/batch", batchController.CreateBatch)
+	api.GET("/batch/:batchId", batchController.GetBatchInfo)
+	api.PUT("/upload/:batchId/:chunkIndex", chunkController.UploadChunkStream)
+	api.GET("/download/:batchId/:chunkIndex", chunkController.DownloadChunk)
+	return r
+}
+
+// upload streams a chunk with its digest, as raw-body uploads require
+func upload(r *gin.Engine, path, apiKey, data string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(data))
+	req.Header.Set("X-API-Key", apiKey)
+	sum := sha256.Sum256([]byte(data))
+	req.Header.Set("X-Chunk-SHA256", hex.EncodeToString(sum[:]))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// serve sends a request with the given API key, if any
+func serve(r *gin.Engine, method, path, apiKey, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestBatchIsInvisibleToOtherTenants(t *testing.T) {
+	r := newTenantRouter(t)
+
+	w := serve(r, http.MethodPost, "/api/batch", "key-a", `{"totalChunks":1}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("creating batch: got %d: %s", w.Code, w.Body)
+	}
+	var created models.BatchMetadata
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decoding batch: %v", err)
+	}
+
+	if w := upload(r, "/api/upload/"+created.ID+"/0", "key-a", "chunk data"); w.Code != http.StatusOK {
+		t.Fatalf("uploading chunk: got %d: %s", w.Code, w.Body)
+	}
+
+	if w := serve(r, http.MethodGet, "/api/batch/"+created.ID, "key-a", ""); w.Code != http.StatusOK {
+		t.Errorf("owning tenant: got %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := serve(r, http.MethodGet, "/api/download/"+created.ID+"/0", "key-a", ""); w.Code != http.StatusOK || w.Body.String() != "chunk data" {
+		t.Errorf("owning tenant download: got %d %q", w.Code, w.Body)
+	}
+
+	for _, key := range []string{"key-b", ""} {
+		if w := serve(r, http.MethodGet, "/api/batch/"+created.ID, key, ""); w.Code != http.StatusNotFound {
+			t.Errorf("batch with key %q: got %d, want %d", key, w.Code, http.StatusNotFound)
+		}
+		if w := serve(r, http.MethodGet, "/api/download/"+created.ID+"/0", key, ""); w.Code != http.StatusNotFound {
+			t.Errorf("chunk with key %q: got %d, want %d", key, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestTenantUploadsDoNotCollide(t *testing.T) {
+	r := newTenantRouter(t)
+
+	w := serve(r, http.MethodPost, "/api/batch", "key-a", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("creating batch: got %d: %s", w.Code, w.Body)
+	}
+	var created models.BatchMetadata
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decoding batch: %v", err)
+	}
+
+	// Another tenant using the same batch ID writes to its own namespace
+	for key, data := range map[string]string{"key-a": "alpha", "key-b": "bravo"} {
+		if w := upload(r, "/api/upload/"+created.ID+"/0", key, data); w.Code != http.StatusOK {
+			t.Fatalf("uploading with key %q: got %d: %s", key, w.Code, w.Body)
+		}
+	}
+
+	for key, want := range map[string]string{"key-a": "alpha", "key-b": "bravo"} {
+		if w := serve(r, http.MethodGet, "/api/download/"+created.ID+"/0", key, ""); w.Body.String() != want {
+			t.Errorf("download with key %q: got %q, want %q", key, w.Body, want)
+		}
+	}
+}
+
+func TestUnknownAPIKeyIsRejected(t *testing.T) {
+	r := newTenantRouter(t)
+
+	if w := serve(r, http.MethodPost, "/api/batch", "key-c", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRawUploadNeedsDigest(t *testing.T) {
+	r := newTenantRouter(t)
+
+	w := serve(r, http.MethodPost, "/api/batch", "key-a", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("creating batch: got %d: %s", w.Code, w.Body)
+	}
+	var created models.BatchMetadata
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decoding batch: %v", err)
+	}
+
+	if w := serve(r, http.MethodPut, "/api/upload/"+created.ID+"/0", "key-a", "chunk data"); w.Code != http.StatusBadRequest {
+		t.Errorf("upload without digest: got %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := serve(r, http.MethodGet, "/api/download/"+created.ID+"/0", "key-a", ""); w.Code != http.StatusNotFound {
+		t.Errorf("chunk after rejected upload: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"filesh/models"
+	"filesh/services/usage"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageController handles tenant storage usage endpoints
+type UsageController struct {
+	usageService *usage.Service
+}
+
+// NewUsageController creates a new usage controller
+func NewUsageController(usageService *usage.Service) *UsageController {
+	return &UsageController{
+		usageService: usageService,
+	}
+}
+
+// GetUsage returns the calling tenant's current storage usage and quota
+func (c *UsageController) GetUsage(ctx *gin.Context) {
+	result, err := c.usageService.GetUsage(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(fmt.Sprintf("Failed to compute usage: %v", err)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
@@ -2,34 +2,42 @@ package main
 
 import (
 	"context"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"filesh/config"
 	"filesh/controllers"
 	"filesh/middleware"
+	"filesh/models"
 	"filesh/router"
 	"filesh/services/batch"
+	"filesh/services/blocklist"
 	"filesh/services/chunk"
+	"filesh/services/multipart"
+	"filesh/services/stats"
 	"filesh/services/storage"
+	"filesh/services/tenant"
+	"filesh/services/usage"
 	"filesh/utils"
+	"filesh/version"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// Application version
-const version = "1.0.0"
-
 func main() {
 	// Configure logging
 	logger := utils.SetupLogging()
 	
 	// Log startup information
-	logger.Printf("File.sh server starting up (v%s)...", version)
+	build := version.Get()
+	logger.Printf("File.sh server starting up (v%s, commit %s, built %s)...", build.Version, build.Commit, build.BuildDate)
 	
 	// Set Gin to release mode in production
 	gin.SetMode(gin.ReleaseMode)
@@ -37,49 +45,149 @@ func main() {
 	// Create a new Gin router with no middleware
 	r := gin.New()
 
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Use recovery middleware
 	r.Use(gin.Recovery())
+
+	// Set security headers on API and static responses
+	r.Use(middleware.SecurityHeaders(cfg.ContentSecurity))
 	
 	// Use custom logger middleware
 	r.Use(middleware.APILogger(logger))
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		logger.Fatalf("Failed to load configuration: %v", err)
+	// Count requests refused by auth and limits, for the expvar metrics
+	r.Use(middleware.RejectionMetrics())
+
+	if len(cfg.TenantKeys) > 0 {
+		logger.Printf("Multi-tenancy enabled with %d API key(s)", len(cfg.TenantKeys))
 	}
 
 	// Initialize object storage
 	storageLogger := utils.NewCustomLogger("STORAGE")
 	logger.Printf("Connecting to storage backend (%s)...", cfg.Minio.Endpoint)
-	objectStorage, err := storage.NewMinioStorage(cfg.Minio, storageLogger)
+	// Only batches and data tied to them expire with the bucket lifecycle
+	expiringPrefixes := tenant.ExpiringPrefixes(slices.Collect(maps.Values(cfg.TenantKeys)))
+	objectStorage, err := storage.NewMinioStorage(cfg.Minio, cfg.FileExpiry, expiringPrefixes, storageLogger)
 	if err != nil {
 		logger.Fatalf("Failed to initialize storage: %v", err)
 	}
 	logger.Printf("Successfully connected to storage backend, bucket: %s", objectStorage.GetBucketName())
 
+	// Keep all objects under a prefix when the bucket is shared
+	if prefix := storage.NormalizeObjectPrefix(cfg.Minio.ObjectPrefix); prefix != "" {
+		logger.Printf("Storing objects under prefix %s", prefix)
+		objectStorage = storage.NewPrefixStorage(objectStorage, prefix)
+	}
+
+	// Optionally cap how fast each upload is sent to storage
+	if cfg.UploadRateBps > 0 {
+		logger.Printf("Upload rate limit: %d bytes/s per upload", cfg.UploadRateBps)
+		objectStorage = storage.NewThrottleStorage(objectStorage, cfg.UploadRateBps)
+	}
+
+	// Optionally store identical content only once
+	if cfg.StorageDedup {
+		logger.Printf("Content-addressable deduplication enabled")
+		objectStorage = storage.NewDedupStorage(objectStorage, utils.NewCustomLogger("DEDUP"))
+	}
+
+	// Optionally compress compressible data at rest
+	if cfg.StorageCompress {
+		logger.Printf("Transparent compression enabled")
+		objectStorage = storage.NewCompressStorage(objectStorage, utils.NewCustomLogger("COMPRESS"))
+	}
+
+	// Briefly remember object stats to save round trips on repeated downloads
+	if cfg.StatCacheTTL > 0 {
+		logger.Printf("Object stat cache enabled (TTL %v, %d entries)", cfg.StatCacheTTL, cfg.StatCacheSize)
+		objectStorage = storage.NewCacheStorage(objectStorage, cfg.StatCacheTTL, cfg.StatCacheSize)
+	}
+
+	// Optionally prove that storage accepts writes, reads and deletes before serving
+	if cfg.StartupSelfTest {
+		selfTestCtx, cancelSelfTest := context.WithTimeout(context.Background(), 30*time.Second)
+		err := storage.SelfTest(selfTestCtx, objectStorage)
+		cancelSelfTest()
+		if err != nil {
+			logger.Fatalf("Storage self-test failed: %v", err)
+		}
+		logger.Printf("Storage self-test passed")
+	}
+
 	// Initialize services
-	batchService := batch.NewService(objectStorage, utils.NewCustomLogger("BATCH"))
-	chunkService := chunk.NewService(objectStorage, utils.NewCustomLogger("CHUNK"))
+	batchService := batch.NewService(objectStorage, batch.Options{
+		DefaultExpiry:   cfg.FileExpiry,
+		MaxExpiry:       cfg.MaxExpiry,
+		MaxCopyChunks:   cfg.MaxChunksPerBatch,
+		MaxCopySize:     cfg.MaxFileSizeMB * 1024 * 1024,
+		ReportHashKey:   []byte(cfg.ReportHashKey),
+		DeleteWorkers:   cfg.DeleteConcurrency,
+		BulkConcurrency: cfg.BulkConcurrency,
+	}, utils.NewCustomLogger("BATCH"))
+	chunkService := chunk.NewService(objectStorage, cfg.VerifyUploadSize, utils.NewCustomLogger("CHUNK"))
+	multipartService := multipart.NewService(objectStorage, utils.NewCustomLogger("MULTIPART"))
+	statsService := stats.NewService(objectStorage, cfg.StatsCacheTTL, cfg.StorageSoftCapBytes, cfg.StorageSoftCapEnforce, utils.NewCustomLogger("STATS"))
+	usageService := usage.NewService(objectStorage, cfg.TenantQuotas, cfg.UsageCacheTTL, utils.NewCustomLogger("USAGE"))
+
+	// Background workers stop on shutdown
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Start the background reaper for expired batches; operators can also
+	// trigger a run through the admin API
+	reaper := batch.NewReaper(batchService, cfg.ReaperInterval, cfg.ReaperDryRun, utils.NewCustomLogger("REAPER"))
+	if cfg.ReaperInterval > 0 {
+		go reaper.Start(backgroundCtx)
+	}
+
+	// Keep the block-list of taken-down batches in memory
+	blocklistService := blocklist.NewService(objectStorage, cfg.BlocklistRefresh, utils.NewCustomLogger("BLOCKLIST"))
+	go blocklistService.Start(backgroundCtx)
 
 	// Initialize controllers
-	healthController := controllers.NewHealthController(version)
-	batchController := controllers.NewBatchController(batchService)
-	chunkController := controllers.NewChunkController(chunkService)
-	fileController := controllers.NewFileController(objectStorage)
+	healthController := controllers.NewHealthController(build, objectStorage)
+	batchController := controllers.NewBatchController(batchService, chunkService, usageService, cfg.PublicBaseURL, cfg.PresignExpiry, cfg.MaxChunksPerBatch)
+	chunkController := controllers.NewChunkController(chunkService, batchService, usageService, cfg.DownloadRateBps, cfg.PresignExpiry)
+	fileController := controllers.NewFileController(objectStorage, cfg.DownloadRateBps, cfg.PublicBaseURL)
+	multipartController := controllers.NewMultipartController(multipartService)
+	statsController := controllers.NewStatsController(statsService)
+	adminController := controllers.NewAdminController(batchService, usageService, blocklistService, reaper, objectStorage)
+	usageController := controllers.NewUsageController(usageService)
+	shareController := controllers.NewShareController(batchService, cfg.PublicBaseURL)
 
-	// Configure CORS - allow frontend origin for private API
+	// Configure CORS - allow frontend origins for private API
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{cfg.CorsOrigin}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "HEAD", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "X-Upload-Batch-Id", "Tus-Resumable"}
+	if slices.Contains(cfg.CorsOrigins, "*") {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = cfg.CorsOrigins
+		if len(cfg.CorsOriginPatterns) > 0 {
+			// Only consulted for origins not listed exactly
+			corsConfig.AllowOriginFunc = func(origin string) bool {
+				return slices.ContainsFunc(cfg.CorsOriginPatterns, func(p utils.OriginPattern) bool {
+					return p.Matches(origin)
+				})
+			}
+		}
+	}
+	corsConfig.AllowCredentials = cfg.CorsCredentials
+	corsConfig.AllowMethods = cfg.CorsMethods
+	corsConfig.AllowHeaders = cfg.CorsHeaders
+	corsConfig.MaxAge = cfg.CorsMaxAge
+	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified", "X-Preview-Truncated", "X-Chunk-SHA256", "X-Checksum-Verified", "X-Batch-Quota-Remaining", "X-Encryption-Algo", "X-Encryption-IV", "X-Encryption-Wrapped-Key"}
 	r.Use(cors.New(corsConfig))
 	
 	// Create a separate middleware for the public API
 	publicCorsConfig := cors.DefaultConfig()
 	publicCorsConfig.AllowAllOrigins = true
 	publicCorsConfig.AllowMethods = []string{"GET", "POST", "PUT", "HEAD", "DELETE", "OPTIONS"}
-	publicCorsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type"}
+	publicCorsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Content-Range", controllers.FileOwnerTokenHeader}
+	publicCorsConfig.ExposeHeaders = []string{"Range"}
 	
 	// Apply the public CORS middleware to /api/file paths
 	r.Use(func(c *gin.Context) {
@@ -90,17 +198,42 @@ func main() {
 		c.Next()
 	})
 	
-	// Configure router for handling large files - reduced memory usage
-	r.MaxMultipartMemory = 32 << 20 // 32MB instead of 100MB
+	// Bound the memory used to parse multipart uploads; larger parts spill to temp files
+	r.MaxMultipartMemory = cfg.MaxMultipartMemoryMB << 20
 
 	// Register all API routes
-	router.RegisterRoutes(r, healthController, batchController, chunkController, fileController)
+	router.RegisterRoutes(r, router.Controllers{
+		Health:    healthController,
+		Batch:     batchController,
+		Chunk:     chunkController,
+		File:      fileController,
+		Multipart: multipartController,
+		Stats:     statsController,
+		Admin:     adminController,
+		Usage:     usageController,
+		Share:     shareController,
+	}, router.Middleware{
+		AdminAuth:       middleware.AdminAuth(cfg.AdminAPIKey),
+		UploadQuota:     middleware.NewUploadQuota(cfg.DailyQuotaBytes).Limit(),
+		BatchQuota:      middleware.NewBatchQuota(cfg.MaxBatchesPerDay).Limit(),
+		Tenant:          middleware.TenantAuth(cfg.TenantKeys),
+		TenantQuota:     middleware.TenantQuota(usageService),
+		BatchAlias:      middleware.ResolveBatchAlias(batchService),
+		BatchOwner:      middleware.RequireBatchOwner(batchService),
+		Blocked:         middleware.BlockedBatches(blocklistService),
+		Transfer:        middleware.MaxConcurrency(cfg.MaxConcurrentRequests),
+		StorageCap:      middleware.StorageCap(statsService),
+		Timeout:         middleware.Timeout(cfg.RequestTimeout),
+		UploadTimeout:   middleware.Timeout(cfg.UploadTimeout),
+		DownloadTimeout: middleware.Timeout(cfg.DownloadTimeout),
+		AdminTimeout:    middleware.Timeout(cfg.AdminTimeout),
+	})
 
 	// Static file serving for frontend
 	r.NoRoute(func(c *gin.Context) {
-		// Only serve static files for non-API paths
-		if len(c.Request.URL.Path) >= 4 && c.Request.URL.Path[:4] == "/api" {
-			c.AbortWithStatus(404)
+		// Only serve static files for non-API paths; API clients get an error they can parse
+		if path := c.Request.URL.Path; path == "/api" || strings.HasPrefix(path, "/api/") {
+			c.AbortWithStatusJSON(http.StatusNotFound, models.NewCodedErrorResponse(models.ErrCodeNotFound, "not found"))
 			return
 		}
 		// Serve static files from frontend/dist
@@ -123,8 +256,12 @@ func main() {
 	}
 
 	logger.Printf("Starting server on :%s", port)
-	logger.Printf("Frontend CORS origin: %s", cfg.CorsOrigin)
+	logger.Printf("Frontend CORS origins: %s", strings.Join(cfg.CorsOrigins, ", "))
+	if len(cfg.CorsOriginPatterns) > 0 {
+		logger.Printf("Frontend CORS origin patterns: %v", cfg.CorsOriginPatterns)
+	}
 	logger.Printf("Read timeout: %v, Write timeout: %v", cfg.ReadTimeout, cfg.WriteTimeout)
+	logger.Printf("Request timeout: %v, Upload timeout: %v, Download timeout: %v, Admin timeout: %v", cfg.RequestTimeout, cfg.UploadTimeout, cfg.DownloadTimeout, cfg.AdminTimeout)
 
 	// Start server in a goroutine
 	go func() {
@@ -133,6 +270,18 @@ func main() {
 		}
 	}()
 
+	// Optionally serve profiling endpoints on a separate private listener
+	var debugSrv *http.Server
+	if cfg.DebugEndpoints {
+		debugSrv = utils.NewDebugServer(cfg.DebugAddr)
+		logger.Printf("Debug endpoints (pprof, expvar) listening on %s", cfg.DebugAddr)
+		go func() {
+			if err := debugSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Printf("Debug server failed: %v", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -140,10 +289,22 @@ func main() {
 
 	logger.Printf("Shutting down server...")
 
+	// Fail readiness first and keep serving while load balancers notice
+	healthController.ShuttingDown()
+	if cfg.ShutdownDrain > 0 {
+		logger.Printf("Draining for %v before closing listeners", cfg.ShutdownDrain)
+		time.Sleep(cfg.ShutdownDrain)
+	}
+	stopBackground()
+
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if debugSrv != nil {
+		debugSrv.Close()
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"filesh/services/batch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResolveBatchAlias creates a middleware that lets routes accept a short
+// alias wherever a batch ID is expected, by replacing the :batchId parameter
+// with the batch the alias points to. Unknown aliases are passed through
+// unchanged. It must run after TenantAuth.
+func ResolveBatchAlias(batchService *batch.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batchID := c.Param("batchId")
+		if !batch.IsAlias(batchID) {
+			c.Next()
+			return
+		}
+
+		resolved, err := batchService.ResolveAlias(c.Request.Context(), batchID)
+		if err != nil && !errors.Is(err, batch.ErrAliasNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to resolve batch alias",
+			})
+			c.Abort()
+			return
+		}
+
+		if resolved != "" {
+			for i := range c.Params {
+				if c.Params[i].Key == "batchId" {
+					c.Params[i].Value = resolved
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth creates a middleware that only admits requests carrying the
+// admin API key in the X-API-Key header. With no key configured the admin
+// endpoints are disabled entirely.
+func AdminAuth(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Admin API is disabled",
+			})
+			c.Abort()
+			return
+		}
+
+		provided := c.GetHeader("X-API-Key")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(adminKey)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or missing API key",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchQuota limits how many batches each IP may create over a rolling day,
+// so cheap batch creation can't be used to flood storage with empty batches.
+// Counts are kept in memory only, like UploadQuota.
+type BatchQuota struct {
+	// Maximum batches per IP per rolling day
+	batchesPerDay int64
+	// Map to track hourly creation counts per IP
+	clients map[string]*clientQuota
+	mu      sync.Mutex
+}
+
+// NewBatchQuota creates a new batch creation quota middleware
+func NewBatchQuota(batchesPerDay int64) *BatchQuota {
+	return &BatchQuota{
+		batchesPerDay: batchesPerDay,
+		clients:       make(map[string]*clientQuota),
+	}
+}
+
+// Limit creates a middleware function enforcing the quota. Only batches that
+// were actually created count against it; a zero quota disables it.
+func (bq *BatchQuota) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if bq.batchesPerDay <= 0 {
+			c.Next()
+			return
+		}
+
+		ip := c.ClientIP()
+		now := time.Now()
+		hour := now.Unix() / 3600
+
+		bq.mu.Lock()
+
+		// Drop clients that haven't created a batch for a full day
+		if len(bq.clients) > 0 && now.Second()%30 == 0 {
+			for ip, client := range bq.clients {
+				if time.Since(client.lastUpload) > 24*time.Hour {
+					delete(bq.clients, ip)
+				}
+			}
+		}
+
+		client, exists := bq.clients[ip]
+		if !exists {
+			client = &clientQuota{}
+			bq.clients[ip] = client
+		}
+
+		// Reserve a slot now so concurrent requests can't overshoot the limit
+		used := client.used(hour)
+		exceed := used >= bq.batchesPerDay
+		if !exceed {
+			client.add(hour, 1)
+			client.lastUpload = now
+			used++
+		}
+
+		bq.mu.Unlock()
+
+		c.Header("X-Batch-Quota-Remaining", strconv.FormatInt(max(bq.batchesPerDay-used, 0), 10))
+
+		if exceed {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Daily batch creation limit reached. Please try again later.",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		// Give the slot back when no batch was created
+		if c.Writer.Status() >= http.StatusMultipleChoices {
+			bq.mu.Lock()
+			client.add(hour, -1)
+			bq.mu.Unlock()
+		}
+	}
+}
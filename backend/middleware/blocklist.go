@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"filesh/services/blocklist"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockedBatches creates a middleware that refuses every request naming a
+// batch on the block-list with 451 Unavailable For Legal Reasons, covering
+// uploads, downloads and metadata alike. It must run after TenantAuth and
+// ResolveBatchAlias.
+func BlockedBatches(blocklistService *blocklist.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batchID := c.Param("batchId")
+		if batchID != "" && blocklistService.IsBlocked(c.Request.Context(), batchID) {
+			c.JSON(http.StatusUnavailableForLegalReasons, gin.H{
+				"error": "This batch has been taken down",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// concurrencyRetryAfter is the Retry-After hint, in seconds, sent when the server is full
+const concurrencyRetryAfter = "5"
+
+// MaxConcurrency creates a middleware that lets at most limit requests run at
+// once across the routes it guards. Requests beyond that are turned away with
+// 503 Service Unavailable instead of queueing, so a flood of large transfers
+// can't exhaust memory or file descriptors. A limit of zero disables it.
+func MaxConcurrency(limit int) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	slots := make(chan struct{}, limit)
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			c.Next()
+		default:
+			c.Header("Retry-After", concurrencyRetryAfter)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Server is busy, please retry shortly",
+			})
+			c.Abort()
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rejectionReasons names the statuses that mean a client was turned away by
+// auth or a limit, as opposed to a server error
+var rejectionReasons = map[int]string{
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusRequestEntityTooLarge: "too_large",
+	http.StatusTooManyRequests:       "rate_limited",
+}
+
+// rejectedRequests counts rejections by reason and then by route. It is
+// published with the other expvar metrics on the debug listener.
+var rejectedRequests = expvar.NewMap("rejected_requests")
+
+func init() {
+	for _, reason := range rejectionReasons {
+		rejectedRequests.Set(reason, new(expvar.Map).Init())
+	}
+}
+
+// RejectionMetrics creates a middleware that counts responses refusing a
+// request for failed auth (401), size or quota limits (413) and rate limits
+// (429), whichever middleware or handler sent them. Routes are recorded by
+// their pattern, so IDs in paths don't multiply the counters.
+func RejectionMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		reason, ok := rejectionReasons[c.Writer.Status()]
+		if !ok {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		rejectedRequests.Get(reason).(*expvar.Map).Add(c.Request.Method+" "+route, 1)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"filesh/services/batch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OwnerTokenHeader carries the token returned when a batch was created
+const OwnerTokenHeader = "X-Batch-Owner-Token"
+
+// RequireBatchOwner creates a middleware that restricts a route to the
+// uploader of the batch named by :batchId, who proves ownership with the
+// token returned from batch creation. It must run after TenantAuth and
+// ResolveBatchAlias.
+func RequireBatchOwner(batchService *batch.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := batchService.VerifyOwner(c.Request.Context(), c.Param("batchId"), c.GetHeader(OwnerTokenHeader))
+		switch {
+		case err == nil:
+			c.Next()
+			return
+		case errors.Is(err, batch.ErrBatchNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Batch not found",
+			})
+		case errors.Is(err, batch.ErrNotOwner), errors.Is(err, batch.ErrNoOwner):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "A valid " + OwnerTokenHeader + " header is required",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to verify batch owner",
+			})
+		}
+		c.Abort()
+	}
+}
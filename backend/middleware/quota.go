@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quotaBuckets splits the rolling day into hourly buckets
+const quotaBuckets = 24
+
+// uploadChargesKey is the gin context key holding the quotas an upload passed
+// through, for ChargeUpload
+const uploadChargesKey = "uploadCharges"
+
+// ErrDailyQuotaExceeded is returned by ChargeUpload when the extra bytes
+// would take the client over its daily upload quota
+var ErrDailyQuotaExceeded = errors.New("daily upload quota exceeded")
+
+// addUploadCharge registers a quota that ChargeUpload charges extra bytes to
+func addUploadCharge(c *gin.Context, charge func(extra int64) error) {
+	charges, _ := c.Get(uploadChargesKey)
+	list, _ := charges.([]func(int64) error)
+	c.Set(uploadChargesKey, append(list, charge))
+}
+
+// ChargeUpload charges bytes an upload turned out to hold beyond its
+// Content-Length, such as a decompressed body, to the quotas the request
+// passed through. It fails with ErrDailyQuotaExceeded or
+// usage.ErrQuotaExceeded when that would take the client over a quota.
+func ChargeUpload(c *gin.Context, extra int64) error {
+	if extra <= 0 {
+		return nil
+	}
+	charges, _ := c.Get(uploadChargesKey)
+	list, _ := charges.([]func(int64) error)
+	for _, charge := range list {
+		if err := charge(extra); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UploadQuota limits the total number of bytes each IP may upload over a
+// rolling day. Usage is kept in memory only and is never logged.
+type UploadQuota struct {
+	// Maximum bytes per IP per rolling day
+	bytesPerDay int64
+	// Map to track hourly upload totals per IP
+	clients map[string]*clientQuota
+	mu      sync.Mutex
+}
+
+type clientQuota struct {
+	// Bytes uploaded per hour, indexed by hour modulo quotaBuckets
+	buckets [quotaBuckets]int64
+	// The hour each bucket was last written in
+	hours      [quotaBuckets]int64
+	lastUpload time.Time
+}
+
+// NewUploadQuota creates a new upload quota middleware
+func NewUploadQuota(bytesPerDay int64) *UploadQuota {
+	return &UploadQuota{
+		bytesPerDay: bytesPerDay,
+		clients:     make(map[string]*clientQuota),
+	}
+}
+
+// used returns the bytes uploaded during the last day
+func (q *clientQuota) used(hour int64) int64 {
+	var total int64
+	for i := range q.buckets {
+		if hour-q.hours[i] < quotaBuckets {
+			total += q.buckets[i]
+		}
+	}
+	return total
+}
+
+// add records bytes uploaded during the given hour
+func (q *clientQuota) add(hour int64, bytes int64) {
+	i := hour % quotaBuckets
+	if q.hours[i] != hour {
+		q.hours[i] = hour
+		q.buckets[i] = 0
+	}
+	q.buckets[i] += bytes
+}
+
+// Limit creates a middleware function enforcing the quota. The upload size is
+// taken from Content-Length when the request starts, and handlers add what a
+// compressed body inflates to with ChargeUpload; a zero quota disables it.
+func (uq *UploadQuota) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if uq.bytesPerDay <= 0 {
+			c.Next()
+			return
+		}
+
+		ip := c.ClientIP()
+		size := max(c.Request.ContentLength, 0)
+		now := time.Now()
+		hour := now.Unix() / 3600
+
+		uq.mu.Lock()
+
+		// Drop clients that haven't uploaded for a full day
+		if len(uq.clients) > 0 && now.Second()%30 == 0 {
+			for ip, client := range uq.clients {
+				if time.Since(client.lastUpload) > 24*time.Hour {
+					delete(uq.clients, ip)
+				}
+			}
+		}
+
+		client, exists := uq.clients[ip]
+		if !exists {
+			client = &clientQuota{}
+			uq.clients[ip] = client
+		}
+
+		used := client.used(hour)
+		exceed := used+size > uq.bytesPerDay
+		if !exceed {
+			client.add(hour, size)
+			client.lastUpload = now
+			used += size
+		}
+
+		uq.mu.Unlock()
+
+		c.Header("X-Upload-Quota-Remaining", strconv.FormatInt(max(uq.bytesPerDay-used, 0), 10))
+
+		// Return 429 if the upload would exceed the quota
+		if exceed {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Daily upload quota exceeded. Please try again later.",
+			})
+			c.Abort()
+			return
+		}
+
+		// A decompressed body is charged on top once its size is known
+		addUploadCharge(c, func(extra int64) error {
+			uq.mu.Lock()
+			defer uq.mu.Unlock()
+
+			if client.used(hour)+extra > uq.bytesPerDay {
+				return ErrDailyQuotaExceeded
+			}
+			client.add(hour, extra)
+			return nil
+		})
+
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"filesh/services/stats"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StorageCap creates a middleware that turns away new batches and uploads with
+// 507 Insufficient Storage once the bucket has reached its soft cap. The size
+// comes from the cached bucket listing, so uploads may overshoot the cap by
+// whatever arrives within one cache TTL. It does nothing unless the cap is
+// enforced.
+func StorageCap(statsService *stats.Service) gin.HandlerFunc {
+	if !statsService.CapEnforced() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		if err := statsService.CheckCapacity(c.Request.Context()); err != nil {
+			if errors.Is(err, stats.ErrStorageFull) {
+				c.JSON(http.StatusInsufficientStorage, gin.H{
+					"error": "Storage is full, no new uploads are accepted",
+				})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to check storage capacity",
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
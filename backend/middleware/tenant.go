@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"filesh/services/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantContextKey is the gin context key holding the resolved tenant ID
+const TenantContextKey = "tenant"
+
+// TenantAuth creates a middleware that resolves the tenant a request belongs
+// to from the X-API-Key header, using a map of API key to tenant ID. Requests
+// without a key are scoped to the shared public namespace; unknown keys are
+// rejected. The tenant is stored both in the gin context and in the request
+// context so that services namespace their objects accordingly.
+func TenantAuth(keys map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := tenant.Public
+
+		if provided := c.GetHeader("X-API-Key"); provided != "" {
+			tenantID = ""
+			for key, id := range keys {
+				if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+					tenantID = id
+				}
+			}
+			if tenantID == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "Invalid API key",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set(TenantContextKey, tenantID)
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
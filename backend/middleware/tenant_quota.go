@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"filesh/services/tenant"
+	"filesh/services/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantQuota creates a middleware that rejects uploads which would take the
+// request's tenant over its storage quota. The upload size is taken from
+// Content-Length, and handlers add what a compressed body inflates to with
+// ChargeUpload; successful uploads are added to the tenant's cached usage.
+// It must run after TenantAuth.
+func TenantQuota(usageService *usage.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		size := max(c.Request.ContentLength, 0)
+
+		if err := usageService.CheckQuota(c.Request.Context(), size); err != nil {
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": "Storage quota exceeded",
+				})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to check storage quota",
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		addUploadCharge(c, func(extra int64) error {
+			if err := usageService.CheckQuota(c.Request.Context(), size+extra); err != nil {
+				return err
+			}
+			size += extra
+			return nil
+		})
+
+		c.Next()
+
+		if c.Writer.Status() < http.StatusMultipleChoices {
+			usageService.RecordUpload(tenant.FromContext(c.Request.Context()), size)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout creates a middleware that cancels the request context after the
+// given duration. Handlers pass that context on to storage, so an overlong
+// request, like one whose client went away, stops its storage calls too.
+// Contexts can't be extended, so each route should get a single Timeout.
+// A duration of zero disables it.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	if timeout <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
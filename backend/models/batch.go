@@ -7,10 +7,116 @@ import (
 
 // BatchMetadata represents metadata about a batch of uploaded files
 type BatchMetadata struct {
-	ID        string    `json:"id"`
+	ID           string            `json:"id"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	ExpiresAt    time.Time         `json:"expiresAt"`
+	TotalChunks  int               `json:"totalChunks,omitempty"`
+	TotalSize    int64             `json:"totalSize,omitempty"`
+	ChunkMap     []string          `json:"chunkMap,omitempty"`
+	Files        []FileEntry       `json:"files,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	SHA256       string            `json:"sha256,omitempty"`
+	ContentType  string            `json:"contentType,omitempty"`
+	StorageClass string            `json:"storageClass,omitempty"`
+	Finalized    bool              `json:"finalized,omitempty"`
+	Locked       bool              `json:"locked,omitempty"`
+	// OwnerToken, ShareURL and Uploads are only set in the response to batch creation
+	OwnerToken string            `json:"ownerToken,omitempty"`
+	ShareURL   string            `json:"shareUrl,omitempty"`
+	Uploads    *PresignedUploads `json:"uploads,omitempty"`
+}
+
+// PresignedUploads holds a presigned upload for every expected chunk of a
+// new batch, indexed by chunk. Each chunk must be confirmed once uploaded by
+// POSTing to ConfirmURL with {index} replaced by its index.
+type PresignedUploads struct {
+	Chunks     []*PresignedUpload `json:"chunks"`
+	ExpiresAt  time.Time          `json:"expiresAt"`
+	ConfirmURL string             `json:"confirmUrl"`
+}
+
+// FileEntry describes one file of a multi-file batch as a contiguous run of chunks
+type FileEntry struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	ChunkStart int    `json:"chunkStart"`
+	ChunkCount int    `json:"chunkCount"`
+}
+
+// BatchManifest lists the files stored in a batch
+type BatchManifest struct {
+	BatchID string      `json:"batchId"`
+	Files   []FileEntry `json:"files"`
+}
+
+// CreateBatchRequest represents the optional body of a batch creation request
+type CreateBatchRequest struct {
+	TotalChunks int               `json:"totalChunks"`
+	TotalSize   int64             `json:"totalSize"`
+	ExpiresIn   string            `json:"expiresIn"`
+	Files       []FileEntry       `json:"files"`
+	Tags        map[string]string `json:"tags"`
+	// SHA256 is the hex digest of the whole batch, all chunks concatenated in order
+	SHA256 string `json:"sha256"`
+	// ContentType is the media type of the batch as a whole, stored on every chunk
+	ContentType string `json:"contentType"`
+	// StorageClass overrides the configured storage class for the batch's
+	// chunks, such as STANDARD_IA for rarely downloaded batches
+	StorageClass string `json:"storageClass"`
+	// Presign asks for presigned upload URLs for all TotalChunks chunks
+	Presign bool `json:"presign"`
+}
+
+// BatchAlias maps a short alias to a batch ID
+type BatchAlias struct {
+	Alias     string    `json:"alias"`
+	BatchID   string    `json:"batchId"`
 	CreatedAt time.Time `json:"createdAt"`
-	ExpiresAt time.Time `json:"expiresAt"`
-	ChunkMap  []string  `json:"chunkMap,omitempty"`
+}
+
+// BatchRecord is the metadata sidecar persisted alongside a batch's chunks.
+// It may hold private fields and must never be returned to clients as-is.
+type BatchRecord struct {
+	ID          string            `json:"id"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	ExpiresAt   time.Time         `json:"expiresAt"`
+	TotalChunks int               `json:"totalChunks,omitempty"`
+	TotalSize   int64             `json:"totalSize,omitempty"`
+	Files       []FileEntry       `json:"files,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	SHA256      string            `json:"sha256,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	// StorageClass is the storage class the batch's chunks are written with
+	StorageClass string `json:"storageClass,omitempty"`
+	// OwnerTokenHash is the SHA-256 of the token returned to the uploader
+	OwnerTokenHash string `json:"ownerTokenHash,omitempty"`
+	// FinalizedAt is set once the batch has been finalized
+	FinalizedAt *time.Time `json:"finalizedAt,omitempty"`
+	// Locked batches refuse further chunk uploads
+	Locked bool `json:"locked,omitempty"`
+}
+
+// IsExpired reports whether the batch is past its expiry time
+func (r *BatchRecord) IsExpired() bool {
+	return !r.ExpiresAt.IsZero() && time.Now().After(r.ExpiresAt)
+}
+
+// Metadata returns the public view of the batch record
+func (r *BatchRecord) Metadata() BatchMetadata {
+	return BatchMetadata{
+		ID:           r.ID,
+		CreatedAt:    r.CreatedAt,
+		ExpiresAt:    r.ExpiresAt,
+		TotalChunks:  r.TotalChunks,
+		TotalSize:    r.TotalSize,
+		Files:        r.Files,
+		Tags:         r.Tags,
+		SHA256:       r.SHA256,
+		ContentType:  r.ContentType,
+		StorageClass: r.StorageClass,
+		Finalized:    r.FinalizedAt != nil,
+		Locked:       r.Locked,
+	}
 }
 
 // MarshalJSON custom JSON marshaler for BatchMetadata to format dates
@@ -48,11 +154,22 @@ func (c ChunkInfo) MarshalJSON() ([]byte, error) {
 
 // BatchStatus represents the status of a batch with detailed chunk information
 type BatchStatus struct {
-	ID        string      `json:"id"`
-	CreatedAt time.Time   `json:"createdAt"`
-	ExpiresAt time.Time   `json:"expiresAt"`
-	Chunks    []ChunkInfo `json:"chunks"`
-	TotalSize int64       `json:"totalSize"`
+	ID            string      `json:"id"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	ExpiresAt     time.Time   `json:"expiresAt"`
+	Chunks        []ChunkInfo `json:"chunks"`
+	TotalSize     int64       `json:"totalSize"`
+	IsComplete    bool        `json:"isComplete"`
+	MissingChunks []int       `json:"missingChunks,omitempty"`
+	Progress
+}
+
+// Progress summarises how much of a batch has been uploaded. ExpectedChunks
+// is -1 and ProgressPercent is omitted when the expected count is unknown.
+type Progress struct {
+	UploadedChunks  int      `json:"uploadedChunks"`
+	ExpectedChunks  int      `json:"expectedChunks"`
+	ProgressPercent *float64 `json:"progressPercent,omitempty"`
 }
 
 // MarshalJSON custom JSON marshaler for BatchStatus to format dates
@@ -69,11 +186,83 @@ func (b BatchStatus) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// BatchVerification reports the digest computed over a whole batch
+type BatchVerification struct {
+	BatchID  string `json:"batchId"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Expected string `json:"expected"`
+	Verified bool   `json:"verified"`
+}
+
+// ExtendBatchRequest represents the body of a request to extend a batch's expiry
+type ExtendBatchRequest struct {
+	// ExpiresIn is the new lifetime counted from now, as a Go duration string
+	ExpiresIn string `json:"expiresIn"`
+}
+
+// RenameBatchRequest represents the body of a request to move a batch to a custom ID
+type RenameBatchRequest struct {
+	NewID string `json:"newId" binding:"required"`
+}
+
+// CopyBatchRequest represents the optional body of a request to copy a batch
+type CopyBatchRequest struct {
+	// ExpiresIn is the lifetime of the copy; empty selects the default
+	ExpiresIn string `json:"expiresIn"`
+}
+
+// BatchCopy describes a batch created by copying another
+type BatchCopy struct {
+	SourceID  string        `json:"sourceId"`
+	Chunks    int           `json:"chunks"`
+	TotalSize int64         `json:"totalSize"`
+	Batch     BatchMetadata `json:"batch"`
+}
+
+// FinalizeBatchRequest represents the optional body of a finalize request
+type FinalizeBatchRequest struct {
+	// Lock refuses any further chunk uploads to the batch
+	Lock bool `json:"lock"`
+}
+
+// BatchCompletion is the marker written when a batch is finalized
+type BatchCompletion struct {
+	BatchID     string    `json:"batchId"`
+	Chunks      int       `json:"chunks"`
+	TotalSize   int64     `json:"totalSize"`
+	SHA256      string    `json:"sha256"`
+	Locked      bool      `json:"locked"`
+	FinalizedAt time.Time `json:"finalizedAt"`
+}
+
+// MarshalJSON custom JSON marshaler for BatchCompletion to format dates
+func (b BatchCompletion) MarshalJSON() ([]byte, error) {
+	type Alias BatchCompletion
+	return json.Marshal(&struct {
+		FinalizedAt string `json:"finalizedAt"`
+		*Alias
+	}{
+		FinalizedAt: b.FinalizedAt.Format(time.RFC3339),
+		Alias:       (*Alias)(&b),
+	})
+}
+
+// BatchProgressStatus is the small upload progress summary meant for polling
+type BatchProgressStatus struct {
+	UploadedChunks int   `json:"uploadedChunks"`
+	ExpectedChunks int   `json:"expectedChunks"`
+	TotalSize      int64 `json:"totalSize"`
+	IsComplete     bool  `json:"isComplete"`
+}
+
 // BatchStats contains statistics about a batch
 type BatchStats struct {
 	TotalSize    int64     `json:"totalSize"`
 	ChunksCount  int       `json:"chunks"`
 	LastActivity time.Time `json:"lastActivity"`
+	IsComplete   bool      `json:"isComplete"`
+	Progress
 }
 
 // MarshalJSON custom JSON marshaler for BatchStats to format dates
@@ -86,4 +275,56 @@ func (b BatchStats) MarshalJSON() ([]byte, error) {
 		LastActivity: b.LastActivity.Format(time.RFC3339),
 		Alias:        (*Alias)(&b),
 	})
-} 
\ No newline at end of file
+}
+
+// MissingChunks describes which chunks of a batch have not been uploaded yet
+type MissingChunks struct {
+	BatchID         string  `json:"batchId"`
+	ExpectedChunks  int     `json:"expectedChunks"`
+	PresentChunks   int     `json:"presentChunks"`
+	Missing         []int   `json:"missing"`
+	PercentComplete float64 `json:"percentComplete"`
+}
+
+// PurgeSummary reports the outcome of a sweep for expired batches
+type PurgeSummary struct {
+	DryRun         bool     `json:"dryRun"`
+	BatchesScanned int      `json:"batchesScanned"`
+	BatchesExpired int      `json:"batchesExpired"`
+	BatchesDeleted int      `json:"batchesDeleted"`
+	BytesReclaimed int64    `json:"bytesReclaimed"`
+	ExpiredIDs     []string `json:"expiredIds,omitempty"`
+}
+
+// BatchSummary is an operator-facing overview of a single batch
+type BatchSummary struct {
+	ID        string            `json:"id"`
+	Tenant    string            `json:"tenant"`
+	Chunks    int               `json:"chunks"`
+	TotalSize int64             `json:"totalSize"`
+	CreatedAt time.Time         `json:"createdAt"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// MarshalJSON custom JSON marshaler for BatchSummary to format dates
+func (b BatchSummary) MarshalJSON() ([]byte, error) {
+	type Alias BatchSummary
+	return json.Marshal(&struct {
+		CreatedAt string `json:"createdAt"`
+		ExpiresAt string `json:"expiresAt"`
+		*Alias
+	}{
+		CreatedAt: b.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: b.ExpiresAt.Format(time.RFC3339),
+		Alias:     (*Alias)(&b),
+	})
+}
+
+// BatchList is a page of batch summaries
+type BatchList struct {
+	Batches []*BatchSummary `json:"batches"`
+	Total   int             `json:"total"`
+	Offset  int             `json:"offset"`
+	Limit   int             `json:"limit"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// MultipartSession is the persisted state of a multipart upload session
+type MultipartSession struct {
+	UploadID   string    `json:"uploadId"`
+	FileID     string    `json:"fileId"`
+	Filename   string    `json:"filename,omitempty"`
+	ObjectName string    `json:"objectName"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// Total, Received and Parts track uploads sent as Content-Range pieces
+	Total    int64           `json:"total,omitempty"`
+	Received int64           `json:"received,omitempty"`
+	Parts    []MultipartPart `json:"parts,omitempty"`
+}
+
+// CreateMultipartRequest represents the optional body of a multipart upload creation
+type CreateMultipartRequest struct {
+	Filename string `json:"filename"`
+}
+
+// MultipartUploadResponse represents the response for a new multipart upload session
+type MultipartUploadResponse struct {
+	UploadID string `json:"uploadId"`
+	FileID   string `json:"fileId"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// MultipartPart identifies an uploaded part of a multipart upload
+type MultipartPart struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size,omitempty"`
+}
+
+// CompleteMultipartRequest lists the parts to assemble into the final file
+type CompleteMultipartRequest struct {
+	Parts []MultipartPart `json:"parts"`
+}
+
+// MultipartCompleteResponse represents the response for a completed multipart upload
+type MultipartCompleteResponse struct {
+	FileID       string `json:"fileId"`
+	Filename     string `json:"filename,omitempty"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag,omitempty"`
+	DownloadPath string `json:"downloadPath"`
+}
+
+// RangeUploadStatus reports the progress of a file uploaded in Content-Range pieces
+type RangeUploadStatus struct {
+	FileID       string `json:"fileId"`
+	Filename     string `json:"filename,omitempty"`
+	Received     int64  `json:"received"`
+	Total        int64  `json:"total"`
+	Complete     bool   `json:"complete"`
+	ETag         string `json:"etag,omitempty"`
+	DownloadPath string `json:"downloadPath,omitempty"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ReportRequest is the body of an abuse report
+type ReportRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReportRecord is the persisted set of abuse reports against a batch.
+// Reporters holds keyed hashes of reporter IPs and must never be returned to clients.
+type ReportRecord struct {
+	BatchID         string         `json:"batchId"`
+	Tenant          string         `json:"tenant"`
+	Reasons         map[string]int `json:"reasons"`
+	Reporters       []string       `json:"reporters"`
+	FirstReportedAt time.Time      `json:"firstReportedAt"`
+	LastReportedAt  time.Time      `json:"lastReportedAt"`
+	TakenDownAt     time.Time      `json:"takenDownAt,omitempty"`
+}
+
+// Summary returns the operator-facing view of the record
+func (r *ReportRecord) Summary() ReportSummary {
+	return ReportSummary{
+		BatchID:         r.BatchID,
+		Tenant:          r.Tenant,
+		Reasons:         r.Reasons,
+		Reports:         len(r.Reporters),
+		FirstReportedAt: r.FirstReportedAt,
+		LastReportedAt:  r.LastReportedAt,
+		TakenDownAt:     r.TakenDownAt,
+	}
+}
+
+// ReportSummary describes a flagged batch in the admin review queue
+type ReportSummary struct {
+	BatchID         string         `json:"batchId"`
+	Tenant          string         `json:"tenant"`
+	Reasons         map[string]int `json:"reasons"`
+	Reports         int            `json:"reports"`
+	FirstReportedAt time.Time      `json:"firstReportedAt"`
+	LastReportedAt  time.Time      `json:"lastReportedAt"`
+	TakenDownAt     time.Time      `json:"takenDownAt"`
+}
+
+// MarshalJSON custom JSON marshaler for ReportSummary to format dates
+func (r ReportSummary) MarshalJSON() ([]byte, error) {
+	type Alias ReportSummary
+	return json.Marshal(&struct {
+		FirstReportedAt string `json:"firstReportedAt"`
+		LastReportedAt  string `json:"lastReportedAt"`
+		TakenDownAt     string `json:"takenDownAt,omitempty"`
+		*Alias
+	}{
+		FirstReportedAt: r.FirstReportedAt.Format(time.RFC3339),
+		LastReportedAt:  r.LastReportedAt.Format(time.RFC3339),
+		TakenDownAt:     formatOptionalTime(r.TakenDownAt),
+		Alias:           (*Alias)(&r),
+	})
+}
+
+// BlockedBatch is an entry of the block-list of taken-down batches
+type BlockedBatch struct {
+	BatchID   string    `json:"batchId"`
+	Tenant    string    `json:"tenant"`
+	BlockedAt time.Time `json:"blockedAt"`
+}
+
+// MarshalJSON custom JSON marshaler for BlockedBatch to format dates
+func (b BlockedBatch) MarshalJSON() ([]byte, error) {
+	type Alias BlockedBatch
+	return json.Marshal(&struct {
+		BlockedAt string `json:"blockedAt"`
+		*Alias
+	}{
+		BlockedAt: b.BlockedAt.Format(time.RFC3339),
+		Alias:     (*Alias)(&b),
+	})
+}
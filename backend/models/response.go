@@ -1,12 +1,19 @@
 package models
 
+import "time"
+
 // APIResponse represents a standard API response structure
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// Code is a stable, machine-readable error identifier, if any
+	Code string `json:"code,omitempty"`
 }
 
+// ErrCodeNotFound is the error code for requests to unknown API routes
+const ErrCodeNotFound = "NOT_FOUND"
+
 // NewSuccessResponse creates a new success response
 func NewSuccessResponse(data interface{}) APIResponse {
 	return APIResponse{
@@ -23,6 +30,15 @@ func NewErrorResponse(message string) APIResponse {
 	}
 }
 
+// NewCodedErrorResponse creates a new error response carrying an error code
+func NewCodedErrorResponse(code, message string) APIResponse {
+	return APIResponse{
+		Success: false,
+		Error:   message,
+		Code:    code,
+	}
+}
+
 // ChunkUploadResponse represents the response for a chunk upload
 type ChunkUploadResponse struct {
 	Success    bool   `json:"success"`
@@ -30,6 +46,7 @@ type ChunkUploadResponse struct {
 	ChunkIndex int    `json:"chunkIndex"`
 	Size       int64  `json:"size"`
 	ETag       string `json:"etag,omitempty"`
+	SHA256     string `json:"sha256,omitempty"`
 	Uploaded   string `json:"uploaded,omitempty"`
 	UploadTime string `json:"uploadTime,omitempty"`
 }
@@ -41,5 +58,54 @@ type ChunkStatusResponse struct {
 	ChunkIndex int    `json:"chunkIndex"`
 	Size       int64  `json:"size,omitempty"`
 	ETag       string `json:"etag,omitempty"`
+	SHA256     string `json:"sha256,omitempty"`
 	Uploaded   string `json:"uploaded,omitempty"`
-} 
\ No newline at end of file
+	Encryption *EncryptionInfo `json:"encryption,omitempty"`
+} 
+
+// EncryptionInfo describes how a client encrypted a chunk. It never holds key
+// material in clear: the content key is only stored wrapped by the client.
+type EncryptionInfo struct {
+	Algorithm  string `json:"algorithm"`
+	IV         string `json:"iv,omitempty"`
+	WrappedKey string `json:"wrappedKey,omitempty"`
+}
+
+// ChunkCheckRequest represents a bulk chunk existence check
+type ChunkCheckRequest struct {
+	Indices []int `json:"indices"`
+}
+
+// ChunkCheckResponse maps chunk indices to their status
+type ChunkCheckResponse struct {
+	BatchID string                       `json:"batchId"`
+	Chunks  map[int]*ChunkStatusResponse `json:"chunks"`
+}
+
+// PresignRequest asks for presigned upload URLs for a set of chunks
+type PresignRequest struct {
+	Indices []int `json:"indices"`
+}
+
+// PresignedUpload is a presigned direct-to-storage upload: a
+// multipart/form-data POST to URL with every entry of Fields, followed by the
+// chunk itself as the "file" field
+type PresignedUpload struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+// PresignResponse maps chunk indices to presigned uploads
+type PresignResponse struct {
+	BatchID   string                   `json:"batchId"`
+	ExpiresAt time.Time                `json:"expiresAt"`
+	Uploads   map[int]*PresignedUpload `json:"uploads"`
+}
+
+// PresignDownloadResponse holds a presigned download URL for a chunk
+type PresignDownloadResponse struct {
+	BatchID    string    `json:"batchId"`
+	ChunkIndex int       `json:"chunkIndex"`
+	URL        string    `json:"url"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
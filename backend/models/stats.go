@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StorageStats contains bucket-wide statistics
+type StorageStats struct {
+	ObjectCount  int       `json:"objectCount"`
+	TotalBytes   int64     `json:"totalBytes"`
+	BatchCount   int       `json:"batchCount"`
+	OldestObject time.Time `json:"oldestObject"`
+	NewestObject time.Time `json:"newestObject"`
+	GeneratedAt  time.Time `json:"generatedAt"`
+}
+
+// StorageCapacity reports the bytes stored across the instance against its
+// soft cap. SoftCapBytes and RemainingBytes are omitted when no cap is set.
+type StorageCapacity struct {
+	TotalBytes     int64     `json:"totalBytes"`
+	SoftCapBytes   int64     `json:"softCapBytes,omitempty"`
+	RemainingBytes *int64    `json:"remainingBytes,omitempty"`
+	Exceeded       bool      `json:"exceeded"`
+	Enforced       bool      `json:"enforced"`
+	GeneratedAt    time.Time `json:"generatedAt"`
+}
+
+// MarshalJSON custom JSON marshaler for StorageStats to format dates
+func (s StorageStats) MarshalJSON() ([]byte, error) {
+	type Alias StorageStats
+	return json.Marshal(&struct {
+		OldestObject string `json:"oldestObject,omitempty"`
+		NewestObject string `json:"newestObject,omitempty"`
+		GeneratedAt  string `json:"generatedAt"`
+		*Alias
+	}{
+		OldestObject: formatOptionalTime(s.OldestObject),
+		NewestObject: formatOptionalTime(s.NewestObject),
+		GeneratedAt:  s.GeneratedAt.Format(time.RFC3339),
+		Alias:        (*Alias)(&s),
+	})
+}
+
+// formatOptionalTime formats a time as RFC3339, leaving zero times empty
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// ObjectSummary describes one stored object
+type ObjectSummary struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// MarshalJSON custom JSON marshaler for ObjectSummary to format dates
+func (o ObjectSummary) MarshalJSON() ([]byte, error) {
+	type Alias ObjectSummary
+	return json.Marshal(&struct {
+		LastModified string `json:"lastModified"`
+		*Alias
+	}{
+		LastModified: o.LastModified.Format(time.RFC3339),
+		Alias:        (*Alias)(&o),
+	})
+}
+
+// ObjectList is a listing of stored objects, newest first. Total and
+// TotalBytes cover every match, even when Objects was truncated to the limit.
+type ObjectList struct {
+	Objects    []ObjectSummary `json:"objects"`
+	Total      int             `json:"total"`
+	TotalBytes int64           `json:"totalBytes"`
+	Truncated  bool            `json:"truncated"`
+}
+
+// TenantUsage reports a tenant's storage consumption against its quota
+type TenantUsage struct {
+	Tenant         string `json:"tenant"`
+	UsedBytes      int64  `json:"usedBytes"`
+	QuotaBytes     int64  `json:"quotaBytes"`               // 0 means unlimited
+	RemainingBytes *int64 `json:"remainingBytes,omitempty"` // Only set when a quota applies
+}
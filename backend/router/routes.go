@@ -7,30 +7,141 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Controllers groups the controllers whose routes are registered
+type Controllers struct {
+	Health    *controllers.HealthController
+	Batch     *controllers.BatchController
+	Chunk     *controllers.ChunkController
+	File      *controllers.FileController
+	Multipart *controllers.MultipartController
+	Stats     *controllers.StatsController
+	Admin     *controllers.AdminController
+	Usage     *controllers.UsageController
+	Share     *controllers.ShareController
+}
+
+// Middleware groups the route-specific middleware
+type Middleware struct {
+	// AdminAuth guards operator-only endpoints
+	AdminAuth gin.HandlerFunc
+	// UploadQuota enforces the per-IP daily upload quota
+	UploadQuota gin.HandlerFunc
+	// BatchQuota enforces the per-IP daily batch creation limit
+	BatchQuota gin.HandlerFunc
+	// Tenant scopes batch and chunk requests to the caller's namespace
+	Tenant gin.HandlerFunc
+	// TenantQuota enforces the per-tenant storage quota
+	TenantQuota gin.HandlerFunc
+	// BatchAlias resolves short aliases given in place of batch IDs
+	BatchAlias gin.HandlerFunc
+	// BatchOwner restricts privileged batch actions to the uploader
+	BatchOwner gin.HandlerFunc
+	// Blocked refuses requests for batches that were taken down
+	Blocked gin.HandlerFunc
+	// Transfer caps the number of uploads and downloads in flight
+	Transfer gin.HandlerFunc
+	// StorageCap refuses new batches and uploads once the instance is full
+	StorageCap gin.HandlerFunc
+	// Timeout bounds API calls that don't move file data
+	Timeout gin.HandlerFunc
+	// UploadTimeout bounds requests that send file data
+	UploadTimeout gin.HandlerFunc
+	// DownloadTimeout bounds requests that fetch file data
+	DownloadTimeout gin.HandlerFunc
+	// AdminTimeout bounds operator requests, which may walk the whole bucket
+	AdminTimeout gin.HandlerFunc
+}
+
 // RegisterRoutes configures all the API routes
-func RegisterRoutes(r *gin.Engine, healthController *controllers.HealthController, 
-	batchController *controllers.BatchController, chunkController *controllers.ChunkController,
-	fileController *controllers.FileController) {
+func RegisterRoutes(r *gin.Engine, c Controllers, m Middleware) {
 	
 	// Create a rate limiter (5 requests per minute per IP)
 	rateLimiter := middleware.NewRateLimiter(5)
+
+	// Abuse reports are cheap to send, so keep them to a trickle per IP
+	reportLimiter := middleware.NewRateLimiter(3)
 	
 	// Configure API group
 	api := r.Group("/api")
 	{
-		// Health check route
-		api.GET("/health", healthController.HealthCheck)
+		// Health check routes; liveness and readiness are split for orchestrators
+		api.GET("/health", c.Health.HealthCheck)
+		api.GET("/health/live", c.Health.Live)
+		api.GET("/health/ready", c.Health.Ready)
+		api.GET("/version", c.Health.Version)
+
+		// Batches and chunks live in the namespace of the caller's tenant and
+		// may be addressed by alias; taken-down batches are refused
+		tenantApi := api.Group("", m.Tenant, m.BatchAlias, m.Blocked)
 
 		// Batch routes
-		api.POST("/batch", batchController.CreateBatch)
-		api.GET("/batch/:batchId", batchController.GetBatchInfo)
-		api.GET("/batch/:batchId/chunks", batchController.ListChunks)
+		tenantApi.POST("/batch", m.Timeout, m.StorageCap, m.BatchQuota, c.Batch.CreateBatch)
+		tenantApi.GET("/batch/:batchId", m.Timeout, c.Batch.GetBatchInfo)
+		tenantApi.GET("/batch/:batchId/status", m.Timeout, c.Batch.GetBatchStatus) // Cheap progress polling with If-None-Match
+		tenantApi.GET("/batch/:batchId/chunks", m.Timeout, c.Batch.ListChunks)
+		tenantApi.GET("/batch/:batchId/missing", m.Timeout, c.Batch.ListMissingChunks)
+		tenantApi.POST("/batch/:batchId/check", m.Timeout, c.Chunk.CheckChunks)
+		tenantApi.POST("/batch/:batchId/presign", m.Timeout, m.StorageCap, c.Chunk.PresignUploads)    // Direct-to-storage uploads, held to the quota on confirmation
+		tenantApi.GET("/batch/:batchId/:chunkIndex/presign", m.Timeout, c.Chunk.PresignDownload)      // Direct-from-storage downloads
+		tenantApi.GET("/batch/:batchId/:chunkIndex/preview", m.DownloadTimeout, c.Chunk.PreviewChunk) // Inline text and image previews
+		tenantApi.GET("/batch/:batchId/qr", m.Timeout, c.Share.GetQRCode)
+		tenantApi.POST("/batch/:batchId/alias", m.Timeout, c.Batch.CreateAlias)
+		tenantApi.GET("/batch/:batchId/manifest", m.Timeout, c.Batch.GetManifest)
+		tenantApi.POST("/batch/:batchId/verify", m.DownloadTimeout, m.Transfer, c.Batch.VerifyBatch)                   // Whole-batch checksum
+		tenantApi.POST("/batch/:batchId/finalize", m.DownloadTimeout, m.Transfer, m.BatchOwner, c.Batch.FinalizeBatch) // Completion marker, optionally locking the batch
+		tenantApi.POST("/batch/:batchId/abort", m.Timeout, m.BatchOwner, c.Batch.AbortBatch)
+		tenantApi.POST("/batch/:batchId/copy", m.DownloadTimeout, m.Transfer, m.StorageCap, c.Batch.CopyBatch)     // Server-side duplicate under a new ID
+		tenantApi.POST("/batch/:batchId/extend", m.Timeout, m.BatchOwner, c.Batch.ExtendBatch)                     // Later expiry, within MAX_EXPIRY of creation
+		tenantApi.POST("/batch/:batchId/rename", m.DownloadTimeout, m.Transfer, m.BatchOwner, c.Batch.RenameBatch) // Move to a custom ID
+		tenantApi.POST("/batch/:batchId/report", m.Timeout, reportLimiter.Limit(), c.Batch.ReportBatch)
+		tenantApi.GET("/batch/:batchId/file/*name", m.DownloadTimeout, m.Transfer, c.Chunk.DownloadFile) // One file of a multi-file batch
+		tenantApi.HEAD("/batch/:batchId/download", m.Timeout, c.Chunk.HeadBatch)
+		tenantApi.GET("/batch/:batchId/download", m.DownloadTimeout, m.Transfer, c.Chunk.DownloadBatch)  // Whole batch as one resumable file
+		tenantApi.GET("/batch/:batchId/archive", m.DownloadTimeout, m.Transfer, c.Chunk.DownloadArchive) // Every chunk as a zip or tar entry
 
 		// Chunk routes
-		api.POST("/upload/:batchId/:chunkIndex", chunkController.UploadChunk)
-		api.HEAD("/upload/:batchId/:chunkIndex", chunkController.CheckChunk)
-		api.HEAD("/download/:batchId/:chunkIndex", chunkController.CheckChunk) // Allow HEAD for download path too
-		api.GET("/download/:batchId/:chunkIndex", chunkController.DownloadChunk)
+		tenantApi.POST("/upload/:batchId/:chunkIndex", m.UploadTimeout, m.Transfer, m.StorageCap, m.UploadQuota, m.TenantQuota, c.Chunk.UploadChunk)
+		tenantApi.PUT("/upload/:batchId/:chunkIndex", m.UploadTimeout, m.Transfer, m.StorageCap, m.UploadQuota, m.TenantQuota, c.Chunk.UploadChunkStream) // Raw-body streaming upload for CLI clients
+		tenantApi.POST("/upload/:batchId/:chunkIndex/confirm", m.UploadTimeout, m.StorageCap, c.Chunk.ConfirmChunk)
+		tenantApi.HEAD("/upload/:batchId/:chunkIndex", m.Timeout, c.Chunk.CheckChunk)        // Headers only, for resuming
+		tenantApi.GET("/upload/:batchId/:chunkIndex/status", m.Timeout, c.Chunk.ChunkStatus) // The same check as a JSON body
+		tenantApi.HEAD("/download/:batchId/:chunkIndex", m.Timeout, c.Chunk.HeadChunk)
+		tenantApi.GET("/download/:batchId/:chunkIndex", m.DownloadTimeout, m.Transfer, c.Chunk.DownloadChunk)
+
+		// Storage usage of the caller's tenant
+		tenantApi.GET("/usage", m.Timeout, c.Usage.GetUsage)
+
+		// Multipart upload session routes for single large files. Sessions
+		// share the public file API's rate limit; parts don't, as one file
+		// takes many, but each needs a session and is capped at MAX_FILE_SIZE
+		api.POST("/multipart", m.Timeout, rateLimiter.Limit(), m.StorageCap, c.Multipart.CreateUpload)
+		api.PUT("/multipart/:uploadId/:partNumber", m.UploadTimeout, m.Transfer, m.StorageCap, m.UploadQuota, c.Multipart.UploadPart)
+		api.POST("/multipart/:uploadId/complete", m.UploadTimeout, rateLimiter.Limit(), c.Multipart.CompleteUpload)
+		api.DELETE("/multipart/:uploadId", m.Timeout, rateLimiter.Limit(), c.Multipart.AbortUpload)
+
+		// Large single files sent in Content-Range pieces; kept out of the
+		// rate-limited public file group as one file takes many requests
+		api.PUT("/file/:fileId", m.UploadTimeout, m.Transfer, m.StorageCap, m.UploadQuota, c.Multipart.UploadRange)
+
+		// Operator routes
+		api.GET("/stats", m.AdminTimeout, m.AdminAuth, c.Stats.GetStats)
+		api.GET("/batches", m.AdminTimeout, m.AdminAuth, c.Admin.ListTaggedBatches) // Find batches by ?tag=key:value
+	}
+
+	// Admin API (requires the admin API key)
+	admin := r.Group("/api/admin")
+	admin.Use(m.AdminAuth, m.AdminTimeout)
+	{
+		admin.GET("/batches", c.Admin.ListBatches)
+		admin.DELETE("/batches/:batchId", c.Admin.DeleteBatch)
+		admin.POST("/batches/:batchId/takedown", c.Admin.TakedownBatch)
+		admin.POST("/purge-expired", c.Admin.PurgeExpired) // ?dryRun=true reports without deleting
+		admin.GET("/objects", c.Admin.ListObjects)         // Raw objects by ?prefix= and ?since=
+		admin.GET("/reports", c.Admin.ListReports)
+		admin.GET("/capacity", c.Stats.GetCapacity) // Bytes stored against STORAGE_SOFT_CAP_BYTES
+		admin.GET("/blocked", c.Admin.ListBlocked)
+		admin.PUT("/blocked/:batchId", c.Admin.BlockBatch)
+		admin.DELETE("/blocked/:batchId", c.Admin.UnblockBatch)
 	}
 	
 	// Public file API (with rate limiting but no CORS restrictions)
@@ -38,7 +149,9 @@ func RegisterRoutes(r *gin.Engine, healthController *controllers.HealthControlle
 	publicApi := r.Group("/api/file")
 	publicApi.Use(rateLimiter.Limit())
 	{
-		publicApi.POST("", fileController.UploadFile)
-		publicApi.GET("/:fileId", fileController.DownloadFile)
+		publicApi.POST("", m.UploadTimeout, m.Transfer, m.StorageCap, m.UploadQuota, c.File.UploadFile)
+		publicApi.GET("/:fileId", m.DownloadTimeout, m.Transfer, c.File.DownloadFile)
+		publicApi.GET("/:fileId/thumbnail", m.DownloadTimeout, c.File.GetThumbnail)
+		publicApi.DELETE("/:fileId", m.Timeout, c.File.DeleteFile)
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,125 @@
+package batch
+
+import (
+	"context"
+	"filesh/models"
+	"filesh/services/tenant"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// SortBySize orders batches by total size, largest first
+	SortBySize = "size"
+	// SortByCreated orders batches by creation time, newest first
+	SortByCreated = "created"
+
+	// MaxPageSize caps the number of batches returned per page
+	MaxPageSize = 500
+)
+
+// ParseObjectName splits a batch object name of the form
+// "tenant/batchId/rest" into its parts. ok is false for objects in reserved
+// namespaces and for names that don't belong to a batch.
+func ParseObjectName(name string) (tenantID, batchID, rest string, ok bool) {
+	tenantID, remainder, found := strings.Cut(name, "/")
+	if !found || tenant.IsReserved(tenantID) {
+		return "", "", "", false
+	}
+	batchID, rest, found = strings.Cut(remainder, "/")
+	if !found || batchID == "" {
+		return "", "", "", false
+	}
+	return tenantID, batchID, rest, true
+}
+
+// ListBatches enumerates all batches with their chunk counts and sizes.
+// Sizes and ordering come from a single listing pass; metadata sidecars are
+// only read for the batches on the requested page.
+func (s *Service) ListBatches(ctx context.Context, sortBy string, offset, limit int) (*models.BatchList, error) {
+	if sortBy == "" {
+		sortBy = SortByCreated
+	}
+	if sortBy != SortBySize && sortBy != SortByCreated {
+		return nil, fmt.Errorf("%w: sort must be %q or %q", ErrInvalidRequest, SortBySize, SortByCreated)
+	}
+	if offset < 0 || limit < 1 || limit > MaxPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d and offset non-negative", ErrInvalidRequest, MaxPageSize)
+	}
+
+	objects, err := s.storage.ListObjects(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	// Aggregate objects per tenant and batch prefix
+	summaries := make(map[string]*models.BatchSummary)
+	for _, obj := range objects {
+		tenantID, batchID, rest, ok := ParseObjectName(obj.Name)
+		if !ok {
+			continue
+		}
+
+		key := tenantID + "/" + batchID
+		summary, exists := summaries[key]
+		if !exists {
+			summary = &models.BatchSummary{ID: batchID, Tenant: tenantID}
+			summaries[key] = summary
+		}
+
+		// The sidecar is written at creation, so the earliest object approximates it
+		if summary.CreatedAt.IsZero() || obj.LastModified.Before(summary.CreatedAt) {
+			summary.CreatedAt = obj.LastModified
+		}
+		if isSidecar(rest) {
+			continue
+		}
+		summary.Chunks++
+		summary.TotalSize += obj.Size
+	}
+
+	batches := make([]*models.BatchSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		batches = append(batches, summary)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		if sortBy == SortBySize && batches[i].TotalSize != batches[j].TotalSize {
+			return batches[i].TotalSize > batches[j].TotalSize
+		}
+		if !batches[i].CreatedAt.Equal(batches[j].CreatedAt) {
+			return batches[i].CreatedAt.After(batches[j].CreatedAt)
+		}
+		if batches[i].ID != batches[j].ID {
+			return batches[i].ID < batches[j].ID
+		}
+		return batches[i].Tenant < batches[j].Tenant
+	})
+
+	// Slice out the requested page
+	total := len(batches)
+	start := min(offset, total)
+	end := min(offset+limit, total)
+	page := batches[start:end]
+
+	// Fill in creation and expiry times from the sidecars of this page only
+	for _, summary := range page {
+		record, err := s.LoadMetadata(tenant.NewContext(ctx, summary.Tenant), summary.ID)
+		if err != nil {
+			s.logger.Printf("Could not load metadata for batch %s: %v", summary.ID, err)
+		}
+		if record == nil {
+			record = s.legacyRecord(summary.ID, summary.CreatedAt)
+		}
+		summary.CreatedAt = record.CreatedAt
+		summary.ExpiresAt = record.ExpiresAt
+	}
+
+	return &models.BatchList{
+		Batches: page,
+		Total:   total,
+		Offset:  offset,
+		Limit:   limit,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"filesh/models"
+	"filesh/services/tenant"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+const (
+	// AliasLength is the number of characters in a generated alias
+	AliasLength = 8
+	// aliasAttempts bounds how often generation retries after a collision
+	aliasAttempts = 5
+	// aliasNamespace is the top-level prefix holding alias records
+	aliasNamespace = "aliases"
+)
+
+// aliasAlphabet is the base62 alphabet aliases are drawn from
+const aliasAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ErrAliasNotFound is returned when an alias doesn't map to a batch
+var ErrAliasNotFound = errors.New("alias not found")
+
+// aliasObjectName returns the storage object name of an alias record
+func aliasObjectName(tenantID, alias string) string {
+	return fmt.Sprintf("%s/%s/%s.json", aliasNamespace, tenantID, alias)
+}
+
+// IsAlias reports whether s has the shape of a generated alias
+func IsAlias(s string) bool {
+	if len(s) != AliasLength {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateAlias generates a short base62 alias for an existing batch and
+// persists the mapping. Aliases that collide with another alias or with an
+// existing batch ID are discarded and regenerated.
+func (s *Service) CreateAlias(ctx context.Context, batchID string) (*models.BatchAlias, error) {
+	objects, err := s.storage.ListObjects(ctx, batchPrefix(ctx, batchID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch objects: %w", err)
+	}
+	if len(objects) == 0 {
+		return nil, ErrBatchNotFound
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	for attempt := 0; attempt < aliasAttempts; attempt++ {
+		alias, err := randomAlias()
+		if err != nil {
+			return nil, err
+		}
+
+		taken, err := s.aliasTaken(ctx, tenantID, alias)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			s.logger.Printf("Alias collision on attempt %d, retrying", attempt+1)
+			continue
+		}
+
+		record := &models.BatchAlias{Alias: alias, BatchID: batchID, CreatedAt: time.Now()}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode alias: %w", err)
+		}
+		if _, err := s.storage.UploadObject(ctx, aliasObjectName(tenantID, alias), bytes.NewReader(data), int64(len(data))); err != nil {
+			return nil, fmt.Errorf("failed to store alias: %w", err)
+		}
+
+		s.logger.Printf("Created alias %s for batch %s", alias, batchID)
+		return record, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate a unique alias after %d attempts", aliasAttempts)
+}
+
+// ResolveAlias returns the batch ID an alias points to within the request's tenant
+func (s *Service) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	objectName := aliasObjectName(tenant.FromContext(ctx), alias)
+
+	exists, err := s.storage.CheckObjectExists(ctx, objectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to check alias: %w", err)
+	}
+	if !exists {
+		return "", ErrAliasNotFound
+	}
+
+	reader, err := s.storage.DownloadObject(ctx, objectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to read alias: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read alias: %w", err)
+	}
+
+	var record models.BatchAlias
+	if err := json.Unmarshal(data, &record); err != nil {
+		return "", fmt.Errorf("failed to decode alias: %w", err)
+	}
+	return record.BatchID, nil
+}
+
+// aliasTaken reports whether an alias is already in use or would shadow a batch ID
+func (s *Service) aliasTaken(ctx context.Context, tenantID, alias string) (bool, error) {
+	exists, err := s.storage.CheckObjectExists(ctx, aliasObjectName(tenantID, alias))
+	if err != nil {
+		return false, fmt.Errorf("failed to check alias: %w", err)
+	}
+	if exists {
+		return true, nil
+	}
+
+	objects, err := s.storage.ListObjects(ctx, batchPrefix(ctx, alias))
+	if err != nil {
+		return false, fmt.Errorf("failed to check batch IDs: %w", err)
+	}
+	return len(objects) > 0, nil
+}
+
+// randomAlias draws a uniformly random base62 alias
+func randomAlias() (string, error) {
+	alias := make([]byte, AliasLength)
+	limit := big.NewInt(int64(len(aliasAlphabet)))
+	for i := range alias {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate alias: %w", err)
+		}
+		alias[i] = aliasAlphabet[n.Int64()]
+	}
+	return string(alias), nil
+}
@@ -0,0 +1,211 @@
+package batch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"filesh/services/storage"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ArchiveZip packs a batch as a zip file, the default
+	ArchiveZip = "zip"
+	// ArchiveTar packs a batch as a tar file, which needs no central
+	// directory and so suits clients that unpack while downloading
+	ArchiveTar = "tar"
+
+	// tarBlockSize is the unit tar headers and entry data are padded to
+	tarBlockSize = 512
+)
+
+// ErrUnsupportedFormat is returned for archive formats other than zip and tar
+var ErrUnsupportedFormat = errors.New("unsupported archive format")
+
+// Archive is a batch packed as one file with an entry per chunk, named by
+// its index. Entries are streamed from storage in order as the archive is
+// written, with the next few opened ahead, so nothing is buffered.
+type Archive struct {
+	// Name is the file name to present to clients
+	Name string
+	// Format is ArchiveZip or ArchiveTar
+	Format string
+
+	batchID  string
+	storage  storage.ObjectStorage
+	prefetch int
+	logger   *log.Logger
+	entries  []archiveEntry
+}
+
+// archiveEntry is one chunk of an archive
+type archiveEntry struct {
+	index    int
+	object   string
+	size     int64
+	modified time.Time
+}
+
+// BatchArchive prepares an archive of every stored chunk of a batch. Sizes
+// come from a single listing, which lets tar headers be written up front.
+func (s *Service) BatchArchive(ctx context.Context, batchID, format string) (*Archive, error) {
+	if format != ArchiveZip && format != ArchiveTar {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.IsExpired() {
+		return nil, ErrBatchExpired
+	}
+
+	prefix := batchPrefix(ctx, batchID)
+	objects, err := s.storage.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch chunks: %w", err)
+	}
+	// Tar headers and the archive length need the exact size of each chunk
+	if err := storage.ResolveSizes(ctx, s.storage, objects, s.opts.BulkConcurrency); err != nil {
+		return nil, fmt.Errorf("failed to size batch chunks: %w", err)
+	}
+
+	archive := &Archive{
+		Name:     batchID + "." + format,
+		Format:   format,
+		batchID:  batchID,
+		storage:  s.storage,
+		prefetch: s.opts.BulkConcurrency,
+		logger:   s.logger,
+	}
+	for _, obj := range objects {
+		index, err := strconv.Atoi(strings.TrimPrefix(obj.Name, prefix))
+		if err != nil {
+			continue
+		}
+		archive.entries = append(archive.entries, archiveEntry{
+			index:    index,
+			object:   obj.Name,
+			size:     obj.Size,
+			modified: obj.LastModified,
+		})
+	}
+	if len(archive.entries) == 0 && record == nil {
+		return nil, ErrBatchNotFound
+	}
+
+	// Storage lists keys lexicographically, so order entries by index
+	sort.Slice(archive.entries, func(i, j int) bool {
+		return archive.entries[i].index < archive.entries[j].index
+	})
+	return archive, nil
+}
+
+// ContentType returns the media type of the archive
+func (a *Archive) ContentType() string {
+	if a.Format == ArchiveTar {
+		return "application/x-tar"
+	}
+	return "application/zip"
+}
+
+// Size returns the exact length of the archive, or -1 when it isn't known
+// before writing, as for zip
+func (a *Archive) Size() int64 {
+	if a.Format != ArchiveTar {
+		return -1
+	}
+
+	// Every entry is one header block plus its data padded to whole blocks,
+	// and the archive ends with two zero blocks
+	size := int64(2 * tarBlockSize)
+	for _, entry := range a.entries {
+		size += tarBlockSize + (entry.size+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	}
+	return size
+}
+
+// Write streams the archive to w, downloading chunks in order
+func (a *Archive) Write(ctx context.Context, w io.Writer) error {
+	names := make([]string, len(a.entries))
+	for i, entry := range a.entries {
+		names[i] = entry.object
+	}
+	chunks := newPrefetcher(ctx, a.storage, names, a.prefetch)
+	defer chunks.Close()
+
+	var err error
+	if a.Format == ArchiveTar {
+		err = a.writeTar(w, chunks)
+	} else {
+		err = a.writeZip(w, chunks)
+	}
+	if err != nil {
+		a.logger.Printf("Archive of batch %s ended early: %v", a.batchID, err)
+	}
+	return err
+}
+
+// writeTar writes the archive in the USTAR format, whose entry names fit in a
+// single header block, so the output matches Size
+func (a *Archive) writeTar(w io.Writer, chunks *prefetcher) error {
+	tw := tar.NewWriter(w)
+	for _, entry := range a.entries {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     strconv.Itoa(entry.index),
+			Size:     entry.size,
+			Mode:     0644,
+			ModTime:  entry.modified.Truncate(time.Second),
+			Format:   tar.FormatUSTAR,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write header of chunk %d: %w", entry.index, err)
+		}
+		if err := copyEntry(tw, chunks, entry); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeZip writes the archive with stored, uncompressed entries
+func (a *Archive) writeZip(w io.Writer, chunks *prefetcher) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range a.entries {
+		entryWriter, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     strconv.Itoa(entry.index),
+			Method:   zip.Store,
+			Modified: entry.modified,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write header of chunk %d: %w", entry.index, err)
+		}
+		if err := copyEntry(entryWriter, chunks, entry); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// copyEntry copies the data of one chunk, the next one from chunks, into the archive
+func copyEntry(w io.Writer, chunks *prefetcher, entry archiveEntry) error {
+	reader, err := chunks.Next()
+	if err != nil {
+		return fmt.Errorf("failed to open chunk %d: %w", entry.index, err)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to copy chunk %d: %w", entry.index, err)
+	}
+	return nil
+}
@@ -2,55 +2,226 @@ package batch
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"filesh/models"
 	"filesh/services/storage"
+	"filesh/services/tenant"
+	"filesh/utils"
 	"fmt"
 	"log"
+	"math"
+	"mime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// MaxExpectedChunks caps the expected chunk count accepted by FindMissingChunks
+const MaxExpectedChunks = 100000
+
+// maxContentTypeLength bounds the media type a batch may declare
+const maxContentTypeLength = 255
+
+var (
+	// ErrBatchNotFound is returned when a batch has no stored objects
+	ErrBatchNotFound = errors.New("batch not found")
+	// ErrBatchExpired is returned when a batch is past its expiry time
+	ErrBatchExpired = errors.New("batch has expired")
+	// ErrInvalidRequest is returned when a batch request fails validation
+	ErrInvalidRequest = errors.New("invalid batch request")
+	// ErrChunkOutOfRange is returned for chunk indices past the batch's expected chunk count
+	ErrChunkOutOfRange = errors.New("chunk index out of range")
+)
+
+// Options configures the batch service
+type Options struct {
+	// DefaultExpiry is how long a batch lives when the client doesn't choose
+	DefaultExpiry time.Duration
+	// MaxExpiry is the longest lifetime a client may request
+	MaxExpiry time.Duration
+	// ReportHashKey keys the hashes of reporter IPs. A random key is used when
+	// empty, so repeat reports are only recognised until the next restart.
+	ReportHashKey []byte
+	// DeleteWorkers bounds the concurrent object deletions when a batch is removed
+	DeleteWorkers int
+	// BulkConcurrency is how many chunks whole-batch downloads and archives
+	// keep open at once, the one being read included
+	BulkConcurrency int
+	// MaxCopyChunks and MaxCopySize cap the batches CopyBatch will duplicate;
+	// zero leaves them unbounded
+	MaxCopyChunks int
+	MaxCopySize   int64
+}
+
+const (
+	// defaultDeleteWorkers is the deletion concurrency used when none is configured
+	defaultDeleteWorkers = 16
+	// defaultBulkConcurrency is the chunk prefetch used when none is configured
+	defaultBulkConcurrency = 4
+)
+
 // Service handles batch-related operations
 type Service struct {
 	storage storage.ObjectStorage
+	opts    Options
 	logger  *log.Logger
+
+	// reportsMu serializes updates to report records within this process
+	reportsMu sync.Mutex
 }
 
 // NewService creates a new batch service
-func NewService(storage storage.ObjectStorage, logger *log.Logger) *Service {
+func NewService(storage storage.ObjectStorage, opts Options, logger *log.Logger) *Service {
 	if logger == nil {
 		logger = log.New(log.Writer(), "[BATCH] ", log.LstdFlags)
 	}
+	if opts.DefaultExpiry <= 0 {
+		opts.DefaultExpiry = 7 * 24 * time.Hour
+	}
+	if opts.MaxExpiry <= 0 {
+		opts.MaxExpiry = opts.DefaultExpiry
+	}
+	if opts.DeleteWorkers <= 0 {
+		opts.DeleteWorkers = defaultDeleteWorkers
+	}
+	if opts.BulkConcurrency <= 0 {
+		opts.BulkConcurrency = defaultBulkConcurrency
+	}
+	if len(opts.ReportHashKey) == 0 {
+		opts.ReportHashKey = make([]byte, 32)
+		if _, err := rand.Read(opts.ReportHashKey); err != nil {
+			panic(fmt.Sprintf("failed to generate report hash key: %v", err))
+		}
+	}
 	
 	return &Service{
 		storage: storage,
+		opts:    opts,
 		logger:  logger,
 	}
 }
 
-// CreateBatch creates a new batch with a unique ID
-func (s *Service) CreateBatch() models.BatchMetadata {
+// CreateBatch creates a new batch with a unique ID and persists its metadata sidecar
+func (s *Service) CreateBatch(ctx context.Context, req models.CreateBatchRequest) (*models.BatchMetadata, error) {
+	if req.TotalChunks < 0 || req.TotalChunks > MaxExpectedChunks {
+		return nil, fmt.Errorf("%w: totalChunks must be between 0 and %d", ErrInvalidRequest, MaxExpectedChunks)
+	}
+	if req.TotalSize < 0 {
+		return nil, fmt.Errorf("%w: totalSize cannot be negative", ErrInvalidRequest)
+	}
+	if err := validateManifest(req.Files, req.TotalChunks); err != nil {
+		return nil, err
+	}
+	if err := validateTags(req.Tags); err != nil {
+		return nil, err
+	}
+	if req.SHA256 != "" {
+		if decoded, err := hex.DecodeString(req.SHA256); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("%w: sha256 must be a hex-encoded SHA-256 digest", ErrInvalidRequest)
+		}
+	}
+
+	contentType, err := parseContentType(req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if req.StorageClass != "" && !utils.ValidStorageClass(req.StorageClass) {
+		return nil, fmt.Errorf("%w: storageClass must be a storage class name such as \"STANDARD_IA\"", ErrInvalidRequest)
+	}
+
+	expiry, err := s.ParseExpiry(req.ExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate a new UUID for the batch
 	batchID := uuid.New().String()
 
-	// Create batch metadata (7 days expiry by default)
+	// The owner token is returned once; only its hash is persisted
+	ownerToken, ownerTokenHash, err := NewOwnerToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create batch record
 	now := time.Now()
-	metadata := models.BatchMetadata{
-		ID:        batchID,
-		CreatedAt: now,
-		ExpiresAt: now.Add(7 * 24 * time.Hour),
+	record := &models.BatchRecord{
+		ID:             batchID,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(expiry),
+		TotalChunks:    req.TotalChunks,
+		TotalSize:      req.TotalSize,
+		Files:          req.Files,
+		Tags:           req.Tags,
+		SHA256:         strings.ToLower(req.SHA256),
+		ContentType:    contentType,
+		StorageClass:   req.StorageClass,
+		OwnerTokenHash: ownerTokenHash,
+	}
+
+	if err := s.SaveMetadata(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("Created new batch: %s, expires: %s", batchID, record.ExpiresAt.Format(time.RFC3339))
+	metadata := record.Metadata()
+	metadata.OwnerToken = ownerToken
+	return &metadata, nil
+}
+
+// parseContentType validates a batch's media type and returns it in
+// canonical form. An empty value leaves the batch without one.
+func parseContentType(contentType string) (string, error) {
+	if contentType == "" {
+		return "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || len(contentType) > maxContentTypeLength || strings.Contains(mediaType, "*") {
+		return "", fmt.Errorf("%w: contentType must be a media type such as \"video/mp4\"", ErrInvalidRequest)
+	}
+	if kind, subtype, found := strings.Cut(mediaType, "/"); !found || kind == "" || subtype == "" {
+		return "", fmt.Errorf("%w: contentType must be a media type such as \"video/mp4\"", ErrInvalidRequest)
+	}
+	// Downloads are served with this type, so it must not be one a browser would run
+	if utils.ActiveContentType(mediaType) {
+		return "", fmt.Errorf("%w: contentType %q could run in a browser and is not allowed", ErrInvalidRequest, mediaType)
+	}
+	return mime.FormatMediaType(mediaType, params), nil
+}
+
+// ParseExpiry validates a client-requested lifetime (a Go duration string)
+// against the configured maximum. An empty value selects the default expiry.
+func (s *Service) ParseExpiry(expiresIn string) (time.Duration, error) {
+	if expiresIn == "" {
+		return s.opts.DefaultExpiry, nil
+	}
+
+	expiry, err := time.ParseDuration(expiresIn)
+	if err != nil {
+		return 0, fmt.Errorf("%w: expiresIn must be a duration such as \"24h\"", ErrInvalidRequest)
+	}
+	if expiry <= 0 {
+		return 0, fmt.Errorf("%w: expiresIn must be positive", ErrInvalidRequest)
+	}
+	if expiry > s.opts.MaxExpiry {
+		return 0, fmt.Errorf("%w: expiresIn cannot exceed %v", ErrInvalidRequest, s.opts.MaxExpiry)
 	}
 
-	s.logger.Printf("Created new batch: %s, expires: %s", batchID, metadata.ExpiresAt.Format(time.RFC3339))
-	return metadata
+	return expiry, nil
 }
 
 // GetBatchInfo retrieves information about a batch
 func (s *Service) GetBatchInfo(ctx context.Context, batchID string) (*models.BatchMetadata, *models.BatchStats, error) {
-	// List objects with prefix batchID/
-	listPrefix := fmt.Sprintf("%s/", batchID)
+	// List objects with prefix tenant/batchID/
+	listPrefix := batchPrefix(ctx, batchID)
 	
 	objects, err := s.storage.ListObjects(ctx, listPrefix)
 	if err != nil {
@@ -58,7 +229,7 @@ func (s *Service) GetBatchInfo(ctx context.Context, batchID string) (*models.Bat
 	}
 
 	if len(objects) == 0 {
-		return nil, nil, fmt.Errorf("batch not found")
+		return nil, nil, ErrBatchNotFound
 	}
 
 	// Get the latest modified time from chunks to estimate batch creation time
@@ -67,19 +238,17 @@ func (s *Service) GetBatchInfo(ctx context.Context, batchID string) (*models.Bat
 	var totalSize int64 = 0
 	chunkMap := make([]string, 0, len(objects))
 	
-	for i, obj := range objects {
-		totalSize += obj.Size
-		chunkMap = append(chunkMap, obj.Name[len(listPrefix):])
-		
-		// Initialize with first object
-		if i == 0 {
-			earliestChunk = obj.LastModified
-			latestChunk = obj.LastModified
+	for _, obj := range objects {
+		name := obj.Name[len(listPrefix):]
+		if isSidecar(name) {
 			continue
 		}
+
+		totalSize += obj.Size
+		chunkMap = append(chunkMap, name)
 		
 		// Update earliest and latest times
-		if obj.LastModified.Before(earliestChunk) {
+		if earliestChunk.IsZero() || obj.LastModified.Before(earliestChunk) {
 			earliestChunk = obj.LastModified
 		}
 		if obj.LastModified.After(latestChunk) {
@@ -87,47 +256,69 @@ func (s *Service) GetBatchInfo(ctx context.Context, batchID string) (*models.Bat
 		}
 	}
 	
-	// Use earliest chunk as creation time or fallback to current time - 24h
-	createdAt := earliestChunk
-	if createdAt.IsZero() {
-		createdAt = time.Now().Add(-24 * time.Hour)
+	// Storage lists keys lexicographically, so order the chunk map numerically
+	sortChunkNames(chunkMap)
+
+	// Prefer the persisted metadata, falling back to chunk timestamps for legacy batches
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, nil, err
 	}
-	
+	if record == nil {
+		record = s.legacyRecord(batchID, earliestChunk)
+	} else if record.IsExpired() {
+		// Only batches with a sidecar are gated; legacy ones rely on the lifecycle
+		return nil, nil, ErrBatchExpired
+	}
+
 	// Create batch metadata with chunk information
-	metadata := &models.BatchMetadata{
-		ID:        batchID,
-		CreatedAt: createdAt,
-		ExpiresAt: createdAt.Add(7 * 24 * time.Hour), // Expires in 7 days from creation
-		ChunkMap:  chunkMap,
+	metadata := record.Metadata()
+	metadata.ChunkMap = chunkMap
+
+	// Count the chunks that fall within the expected range, when it is known
+	uploaded := len(chunkMap)
+	if record.TotalChunks > 0 {
+		uploaded = 0
+		for _, name := range chunkMap {
+			if index, err := strconv.Atoi(name); err == nil && index < record.TotalChunks {
+				uploaded++
+			}
+		}
 	}
 
 	// Create batch stats
 	stats := &models.BatchStats{
 		TotalSize:    totalSize,
-		ChunksCount:  len(objects),
+		ChunksCount:  len(chunkMap),
 		LastActivity: latestChunk,
+		IsComplete:   record.TotalChunks > 0 && uploaded == record.TotalChunks,
+		Progress:     progress(uploaded, record.TotalChunks),
 	}
 
-	return metadata, stats, nil
+	return &metadata, stats, nil
 }
 
 // ListChunks lists all chunks in a batch
 func (s *Service) ListChunks(ctx context.Context, batchID string) (*models.BatchStatus, error) {
-	// List objects with prefix batchID/
-	listPrefix := fmt.Sprintf("%s/", batchID)
+	// List objects with prefix tenant/batchID/
+	listPrefix := batchPrefix(ctx, batchID)
 	
 	objects, err := s.storage.ListObjects(ctx, listPrefix)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list batch chunks: %w", err)
 	}
+	// Clients compare these sizes against their own chunks when resuming
+	if err := storage.ResolveSizes(ctx, s.storage, objects, s.opts.BulkConcurrency); err != nil {
+		return nil, fmt.Errorf("failed to size batch chunks: %w", err)
+	}
 
 	chunks := make([]models.ChunkInfo, 0, len(objects))
 	var totalSize int64 = 0
 	var earliestChunk time.Time
 	
-	for i, obj := range objects {
+	for _, obj := range objects {
 		// Extract chunk index from object name
-		// Object name format is "batchId/chunkIndex"
+		// Object name format is "tenant/batchId/chunkIndex"
 		chunkIndexStr := obj.Name[len(listPrefix):]
 		chunkIndex, err := strconv.Atoi(chunkIndexStr)
 		if err != nil {
@@ -144,25 +335,277 @@ func (s *Service) ListChunks(ctx context.Context, batchID string) (*models.Batch
 		totalSize += obj.Size
 		
 		// Track earliest chunk for creation time
-		if i == 0 || obj.LastModified.Before(earliestChunk) {
+		if earliestChunk.IsZero() || obj.LastModified.Before(earliestChunk) {
 			earliestChunk = obj.LastModified
 		}
 	}
 	
-	// Use earliest chunk as creation time or fallback to current time - 24h
-	createdAt := earliestChunk
-	if createdAt.IsZero() {
-		createdAt = time.Now().Add(-24 * time.Hour)
+	// Storage lists keys lexicographically, so order chunks by index
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Index < chunks[j].Index
+	})
+
+	// Prefer the persisted metadata, falling back to chunk timestamps for legacy batches
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		record = s.legacyRecord(batchID, earliestChunk)
 	}
 	
 	// Create batch status
 	batchStatus := &models.BatchStatus{
 		ID:        batchID,
-		CreatedAt: createdAt,
-		ExpiresAt: createdAt.Add(7 * 24 * time.Hour),
+		CreatedAt: record.CreatedAt,
+		ExpiresAt: record.ExpiresAt,
 		Chunks:    chunks,
 		TotalSize: totalSize,
 	}
+
+	// Compare present chunks against the expected count when it is known
+	uploaded := len(chunks)
+	if record.TotalChunks > 0 {
+		batchStatus.MissingChunks = missingIndices(chunks, record.TotalChunks)
+		batchStatus.IsComplete = len(batchStatus.MissingChunks) == 0
+		uploaded = record.TotalChunks - len(batchStatus.MissingChunks)
+	}
+	batchStatus.Progress = progress(uploaded, record.TotalChunks)
 	
 	return batchStatus, nil
-} 
\ No newline at end of file
+}
+
+// CheckExpiry returns ErrBatchExpired once a batch is past its expiry, even if
+// its objects haven't been physically deleted yet. Legacy batches without a
+// metadata sidecar are always allowed.
+func (s *Service) CheckExpiry(ctx context.Context, batchID string) error {
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	if record != nil && record.IsExpired() {
+		return ErrBatchExpired
+	}
+	return nil
+}
+
+// PrepareUpload loads the batch record ahead of a chunk upload and returns
+// ErrChunkOutOfRange when any index lies beyond the chunk count declared at
+// creation, or ErrBatchLocked once the batch was finalized with a lock.
+// Batches without a declared count accept any index. The record is nil for
+// legacy batches without a sidecar.
+func (s *Service) PrepareUpload(ctx context.Context, batchID string, indices ...int) (*models.BatchRecord, error) {
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.Locked {
+		return nil, ErrBatchLocked
+	}
+	if record == nil || record.TotalChunks <= 0 {
+		return record, nil
+	}
+
+	for _, index := range indices {
+		if index >= record.TotalChunks {
+			return nil, fmt.Errorf("%w: batch expects chunks 0 to %d, got %d", ErrChunkOutOfRange, record.TotalChunks-1, index)
+		}
+	}
+	return record, nil
+}
+
+// DeleteBatch deletes every object stored under a batch, including its
+// metadata sidecar, and returns the number of objects removed. Chunks are
+// deleted by a pool of DeleteWorkers; the sidecar goes last and is kept if
+// any chunk couldn't be removed, so the owner can retry.
+func (s *Service) DeleteBatch(ctx context.Context, batchID string) (int, error) {
+	prefix := batchPrefix(ctx, batchID)
+	objects, err := s.storage.ListObjects(ctx, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list batch objects: %w", err)
+	}
+
+	var names []string
+	sidecar := ""
+	for _, obj := range objects {
+		if obj.Name == prefix+metadataObject {
+			sidecar = obj.Name
+			continue
+		}
+		names = append(names, obj.Name)
+	}
+
+	deleted, errs := s.deleteObjects(ctx, names)
+	if sidecar != "" && len(errs) == 0 {
+		if err := s.storage.DeleteObject(ctx, sidecar); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sidecar, err))
+		} else {
+			deleted++
+		}
+	}
+
+	if len(errs) > 0 {
+		return deleted, fmt.Errorf("failed to delete %d of %d objects: %w", len(objects)-deleted, len(objects), errors.Join(errs...))
+	}
+
+	s.logger.Printf("Deleted batch %s (%d objects)", batchID, deleted)
+	return deleted, nil
+}
+
+// deleteObjects removes objects concurrently with a bounded worker pool and
+// returns the number removed along with one error per failed object
+func (s *Service) deleteObjects(ctx context.Context, names []string) (int, []error) {
+	jobs := make(chan string)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		deleted int
+		errs    []error
+	)
+
+	workers := min(s.opts.DeleteWorkers, len(names))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				err := s.storage.DeleteObject(ctx, name)
+
+				mu.Lock()
+				if err != nil {
+					s.logger.Printf("Failed to delete %s: %v", name, err)
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				} else {
+					deleted++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range names {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+
+	return deleted, errs
+}
+
+// AbortBatch cancels an upload in progress by removing every chunk and the
+// metadata sidecar. It returns ErrBatchNotFound when nothing was stored.
+func (s *Service) AbortBatch(ctx context.Context, batchID string) (int, error) {
+	deleted, err := s.DeleteBatch(ctx, batchID)
+	if err != nil {
+		return deleted, err
+	}
+	if deleted == 0 {
+		return 0, ErrBatchNotFound
+	}
+
+	s.logger.Printf("Aborted batch %s", batchID)
+	return deleted, nil
+}
+
+// batchPrefix returns the object name prefix shared by a batch's objects
+// within the tenant the request is scoped to
+func batchPrefix(ctx context.Context, batchID string) string {
+	return fmt.Sprintf("%s/%s/", tenant.FromContext(ctx), batchID)
+}
+
+// legacyRecord builds a batch record for batches without a metadata sidecar,
+// using the earliest chunk as creation time or falling back to current time - 24h
+func (s *Service) legacyRecord(batchID string, earliestChunk time.Time) *models.BatchRecord {
+	createdAt := earliestChunk
+	if createdAt.IsZero() {
+		createdAt = time.Now().Add(-24 * time.Hour)
+	}
+
+	return &models.BatchRecord{
+		ID:        batchID,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(s.opts.DefaultExpiry),
+	}
+}
+
+// missingIndices returns the sorted indices in 0..expected-1 that have no chunk
+func missingIndices(chunks []models.ChunkInfo, expected int) []int {
+	present := make([]bool, expected)
+	for _, chunk := range chunks {
+		if chunk.Index < expected {
+			present[chunk.Index] = true
+		}
+	}
+
+	missing := make([]int, 0)
+	for i := 0; i < expected; i++ {
+		if !present[i] {
+			missing = append(missing, i)
+		}
+	}
+
+	return missing
+}
+
+// progress reports upload progress against the expected chunk count, which
+// is zero for batches created without one
+func progress(uploaded, expected int) models.Progress {
+	if expected <= 0 {
+		return models.Progress{UploadedChunks: uploaded, ExpectedChunks: -1}
+	}
+
+	// Round to one decimal place
+	percent := math.Round(float64(uploaded)*1000/float64(expected)) / 10
+	return models.Progress{
+		UploadedChunks:  uploaded,
+		ExpectedChunks:  expected,
+		ProgressPercent: &percent,
+	}
+}
+
+// sortChunkNames orders chunk names numerically, placing non-numeric names last
+func sortChunkNames(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		a, errA := strconv.Atoi(names[i])
+		b, errB := strconv.Atoi(names[j])
+		switch {
+		case errA == nil && errB == nil:
+			return a < b
+		case errA == nil:
+			return true
+		case errB == nil:
+			return false
+		default:
+			return names[i] < names[j]
+		}
+	})
+}
+
+// FindMissingChunks compares the chunks present in a batch against 0..expected-1
+func (s *Service) FindMissingChunks(ctx context.Context, batchID string, expected int) (*models.MissingChunks, error) {
+	if expected < 0 || expected > MaxExpectedChunks {
+		return nil, fmt.Errorf("expected chunk count must be between 0 and %d", MaxExpectedChunks)
+	}
+
+	batchStatus, err := s.ListChunks(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := missingIndices(batchStatus.Chunks, expected)
+	presentCount := expected - len(missing)
+
+	// An empty expectation is trivially complete
+	percent := 100.0
+	if expected > 0 {
+		percent = float64(presentCount) * 100 / float64(expected)
+	}
+
+	return &models.MissingChunks{
+		BatchID:         batchID,
+		ExpectedChunks:  expected,
+		PresentChunks:   presentCount,
+		Missing:         missing,
+		PercentComplete: percent,
+	}, nil
+}
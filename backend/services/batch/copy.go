@@ -0,0 +1,162 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"filesh/models"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// copyWorkers bounds the concurrent server-side copies when a batch is copied
+const copyWorkers = 8
+
+// ErrBatchTooLarge is returned when a batch exceeds the limits for copying
+var ErrBatchTooLarge = errors.New("batch is too large to copy")
+
+// CopyBatch duplicates a batch under a new ID with its own expiry and owner
+// token. Chunks are copied server-side, so no data leaves storage. The new
+// batch keeps the source's manifest, tags, digest and content type, but not
+// its finalization or lock. The metadata sidecar is written last, and the
+// copied chunks are removed again if any copy fails.
+func (s *Service) CopyBatch(ctx context.Context, batchID, expiresIn string) (*models.BatchCopy, error) {
+	expiry, err := s.ParseExpiry(expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if source != nil && source.IsExpired() {
+		return nil, ErrBatchExpired
+	}
+
+	chunks, err := s.chunkObjects(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 && source == nil {
+		return nil, ErrBatchNotFound
+	}
+
+	var totalSize int64
+	indices := make([]int, 0, len(chunks))
+	for index, chunk := range chunks {
+		indices = append(indices, index)
+		totalSize += chunk.Size
+	}
+	sort.Ints(indices)
+
+	if s.opts.MaxCopyChunks > 0 && len(indices) > s.opts.MaxCopyChunks {
+		return nil, fmt.Errorf("%w: %d chunks, at most %d can be copied", ErrBatchTooLarge, len(indices), s.opts.MaxCopyChunks)
+	}
+	if s.opts.MaxCopySize > 0 && totalSize > s.opts.MaxCopySize {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d can be copied", ErrBatchTooLarge, totalSize, s.opts.MaxCopySize)
+	}
+
+	ownerToken, ownerTokenHash, err := NewOwnerToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &models.BatchRecord{
+		ID:             uuid.New().String(),
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(expiry),
+		OwnerTokenHash: ownerTokenHash,
+	}
+	if source != nil {
+		record.TotalChunks = source.TotalChunks
+		record.TotalSize = source.TotalSize
+		record.Files = source.Files
+		record.Tags = source.Tags
+		record.SHA256 = source.SHA256
+		record.ContentType = source.ContentType
+		record.StorageClass = source.StorageClass
+	}
+
+	names := make([]string, len(indices))
+	for i, index := range indices {
+		names[i] = strconv.Itoa(index)
+	}
+	if copied, err := s.copyObjects(ctx, batchPrefix(ctx, batchID), batchPrefix(ctx, record.ID), names); err != nil {
+		if _, errs := s.deleteObjects(context.WithoutCancel(ctx), copied); len(errs) > 0 {
+			s.logger.Printf("Could not clean up partial copy %s of batch %s", record.ID, batchID)
+		}
+		return nil, err
+	}
+
+	if err := s.SaveMetadata(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("Copied batch %s to %s (%d chunks, %d bytes)", batchID, record.ID, len(indices), totalSize)
+	metadata := record.Metadata()
+	metadata.OwnerToken = ownerToken
+	return &models.BatchCopy{
+		SourceID:  batchID,
+		Chunks:    len(indices),
+		TotalSize: totalSize,
+		Batch:     metadata,
+	}, nil
+}
+
+// copyObjects copies the named objects from one batch prefix to another with
+// a bounded worker pool. It returns the full names of the copies made, so a
+// failed copy can be cleaned up, and the first error met.
+func (s *Service) copyObjects(ctx context.Context, srcPrefix, dstPrefix string, names []string) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan string)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		copied   []string
+		firstErr error
+	)
+
+	workers := min(copyWorkers, len(names))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				err := s.storage.CopyObject(ctx, srcPrefix+name, dstPrefix+name)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("failed to copy %s: %w", name, err)
+					cancel()
+				} else if err == nil {
+					copied = append(copied, dstPrefix+name)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, name := range names {
+		select {
+		case jobs <- name:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	return copied, firstErr
+}
@@ -0,0 +1,51 @@
+package batch
+
+import (
+	"context"
+	"filesh/models"
+	"fmt"
+	"time"
+)
+
+// ExtendExpiry moves a batch's expiry to expiresIn from now. Only the expiry
+// in the metadata sidecar changes; the bucket lifecycle still deletes objects
+// by their age, so the batch's whole lifetime since creation may not exceed
+// the configured maximum. An expiry can't be brought forward this way.
+func (s *Service) ExtendExpiry(ctx context.Context, batchID, expiresIn string) (*models.BatchMetadata, error) {
+	if expiresIn == "" {
+		return nil, fmt.Errorf("%w: expiresIn is required", ErrInvalidRequest)
+	}
+	expiry, err := s.ParseExpiry(expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrBatchNotFound
+	}
+	if record.IsExpired() {
+		return nil, ErrBatchExpired
+	}
+
+	expiresAt := time.Now().Add(expiry)
+	if expiresAt.Before(record.ExpiresAt) {
+		return nil, fmt.Errorf("%w: batch already expires at %s", ErrInvalidRequest, record.ExpiresAt.Format(time.RFC3339))
+	}
+	if expiresAt.Sub(record.CreatedAt) > s.opts.MaxExpiry {
+		latest := record.CreatedAt.Add(s.opts.MaxExpiry)
+		return nil, fmt.Errorf("%w: batch cannot be kept past %s", ErrInvalidRequest, latest.Format(time.RFC3339))
+	}
+
+	record.ExpiresAt = expiresAt
+	if err := s.SaveMetadata(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("Extended batch %s, expires: %s", batchID, expiresAt.Format(time.RFC3339))
+	metadata := record.Metadata()
+	return &metadata, nil
+}
@@ -0,0 +1,101 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"filesh/models"
+	"fmt"
+)
+
+// MaxFileNameLength caps the length of a manifest file name in bytes
+const MaxFileNameLength = 1024
+
+var (
+	// ErrFileNotFound is returned when a batch's manifest has no file with the requested name
+	ErrFileNotFound = errors.New("file not found in batch")
+	// ErrFileIncomplete is returned when some of a file's chunks haven't been uploaded yet
+	ErrFileIncomplete = errors.New("file is not fully uploaded")
+)
+
+// validateManifest checks that file entries have unique names and occupy
+// disjoint chunk ranges within the expected chunk count, when it is known
+func validateManifest(files []models.FileEntry, totalChunks int) error {
+	names := make(map[string]struct{}, len(files))
+	used := make(map[int]struct{})
+
+	for i, f := range files {
+		if f.Name == "" || len(f.Name) > MaxFileNameLength {
+			return fmt.Errorf("%w: files[%d].name must be between 1 and %d bytes", ErrInvalidRequest, i, MaxFileNameLength)
+		}
+		if _, dup := names[f.Name]; dup {
+			return fmt.Errorf("%w: duplicate file name %q", ErrInvalidRequest, f.Name)
+		}
+		names[f.Name] = struct{}{}
+
+		if f.Size < 0 {
+			return fmt.Errorf("%w: files[%d].size cannot be negative", ErrInvalidRequest, i)
+		}
+		if f.ChunkStart < 0 || f.ChunkCount < 1 || f.ChunkStart+f.ChunkCount > MaxExpectedChunks {
+			return fmt.Errorf("%w: files[%d] has an invalid chunk range", ErrInvalidRequest, i)
+		}
+		if totalChunks > 0 && f.ChunkStart+f.ChunkCount > totalChunks {
+			return fmt.Errorf("%w: files[%d] extends past totalChunks", ErrInvalidRequest, i)
+		}
+
+		for idx := f.ChunkStart; idx < f.ChunkStart+f.ChunkCount; idx++ {
+			if _, overlap := used[idx]; overlap {
+				return fmt.Errorf("%w: files[%d] overlaps another file at chunk %d", ErrInvalidRequest, i, idx)
+			}
+			used[idx] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// GetManifest returns the file manifest recorded when the batch was created.
+// Batches created without a manifest report an empty file list.
+func (s *Service) GetManifest(ctx context.Context, batchID string) (*models.BatchManifest, error) {
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrBatchNotFound
+	}
+	if record.IsExpired() {
+		return nil, ErrBatchExpired
+	}
+
+	files := record.Files
+	if files == nil {
+		files = []models.FileEntry{}
+	}
+	return &models.BatchManifest{BatchID: batchID, Files: files}, nil
+}
+
+// FileStream returns one file from the batch manifest as a stream of its
+// chunks in order. ErrFileIncomplete is returned while chunks are missing.
+func (s *Service) FileStream(ctx context.Context, batchID, name string) (*Stream, error) {
+	manifest, err := s.GetManifest(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *models.FileEntry
+	for i := range manifest.Files {
+		if manifest.Files[i].Name == name {
+			entry = &manifest.Files[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, ErrFileNotFound
+	}
+
+	chunks, err := s.chunkObjects(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	return s.newStream(ctx, batchID, entry.Name, chunks, entry.ChunkStart, entry.ChunkCount)
+}
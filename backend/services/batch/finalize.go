@@ -0,0 +1,82 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"filesh/models"
+	"fmt"
+	"io"
+	"time"
+)
+
+// completeObject is the name of the marker written when a batch is finalized
+const completeObject = "_complete.json"
+
+// ErrBatchLocked is returned for uploads to a batch that was finalized with a lock
+var ErrBatchLocked = errors.New("batch is finalized and locked against uploads")
+
+// FinalizeBatch marks a batch as done once every expected chunk is present.
+// It reads the batch through to compute its SHA-256, checks it against the
+// digest given at creation if there was one, and writes a _complete.json
+// marker with the chunk count, size and digest. With lock set, later chunk
+// uploads are refused with ErrBatchLocked. A lock can't be lifted by
+// finalizing again.
+func (s *Service) FinalizeBatch(ctx context.Context, batchID string, lock bool) (*models.BatchCompletion, error) {
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrBatchNotFound
+	}
+
+	// Fails with ErrFileIncomplete while any expected chunk is missing
+	stream, err := s.BatchStream(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	reader := stream.Open(ctx, 0, stream.Size)
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+	if record.SHA256 != "" && digest != record.SHA256 {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, record.SHA256, digest)
+	}
+
+	now := time.Now()
+	completion := &models.BatchCompletion{
+		BatchID:     batchID,
+		Chunks:      len(stream.names),
+		TotalSize:   stream.Size,
+		SHA256:      digest,
+		Locked:      lock || record.Locked,
+		FinalizedAt: now,
+	}
+
+	data, err := json.Marshal(completion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode completion marker: %w", err)
+	}
+	markerName := batchPrefix(ctx, batchID) + completeObject
+	if _, err := s.storage.UploadObject(ctx, markerName, bytes.NewReader(data), int64(len(data))); err != nil {
+		return nil, fmt.Errorf("failed to store completion marker: %w", err)
+	}
+
+	// Mirror the state in the sidecar, which uploads and GetBatchInfo already read
+	record.FinalizedAt = &now
+	record.Locked = completion.Locked
+	if err := s.SaveMetadata(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("Finalized batch %s: %d chunks, %d bytes, locked: %v", batchID, completion.Chunks, completion.TotalSize, completion.Locked)
+	return completion, nil
+}
@@ -0,0 +1,77 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"filesh/models"
+	"filesh/services/storage"
+	"filesh/services/tenant"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// metadataObject is the name of the sidecar object stored alongside a batch's chunks
+const metadataObject = "_meta.json"
+
+// MetadataObjectName returns the storage object name of a batch's metadata sidecar
+func MetadataObjectName(tenantID, batchID string) string {
+	return fmt.Sprintf("%s/%s/%s", tenantID, batchID, metadataObject)
+}
+
+// isSidecar reports whether an object name (relative to the batch prefix) is
+// bookkeeping data rather than a chunk
+func isSidecar(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// SaveMetadata persists the metadata sidecar for a batch
+func (s *Service) SaveMetadata(ctx context.Context, record *models.BatchRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch metadata: %w", err)
+	}
+
+	// Tag the sidecar too so batches can be found by tag without reading every chunk
+	_, err = s.storage.UploadObjectWithOptions(ctx, MetadataObjectName(tenant.FromContext(ctx), record.ID), bytes.NewReader(data), int64(len(data)), storage.UploadOptions{
+		Tags: record.Tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store batch metadata: %w", err)
+	}
+
+	return nil
+}
+
+// LoadMetadata reads the metadata sidecar for a batch. It returns nil without
+// an error for legacy batches that were created before sidecars existed.
+func (s *Service) LoadMetadata(ctx context.Context, batchID string) (*models.BatchRecord, error) {
+	objectName := MetadataObjectName(tenant.FromContext(ctx), batchID)
+
+	exists, err := s.storage.CheckObjectExists(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check batch metadata: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	reader, err := s.storage.DownloadObject(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch metadata: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch metadata: %w", err)
+	}
+
+	var record models.BatchRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode batch metadata: %w", err)
+	}
+
+	return &record, nil
+}
@@ -0,0 +1,60 @@
+package batch
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ownerTokenBytes is the amount of randomness in an owner token
+const ownerTokenBytes = 32
+
+var (
+	// ErrNotOwner is returned when an owner token is missing or doesn't match
+	ErrNotOwner = errors.New("owner token does not match")
+	// ErrNoOwner is returned for batches created without an owner token
+	ErrNoOwner = errors.New("batch has no owner token")
+)
+
+// NewOwnerToken generates a random owner token and the hash to store in its place
+func NewOwnerToken() (token, hash string, err error) {
+	buf := make([]byte, ownerTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate owner token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashOwnerToken(token), nil
+}
+
+// HashOwnerToken returns the hex SHA-256 of a token. Tokens are random, so a
+// plain hash is enough to keep them out of storage.
+func HashOwnerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyOwner checks a token against the hash recorded when the batch was
+// created. Batches without a sidecar report ErrBatchNotFound, and those
+// created before owner tokens existed report ErrNoOwner.
+func (s *Service) VerifyOwner(ctx context.Context, batchID, token string) error {
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return ErrBatchNotFound
+	}
+	if record.OwnerTokenHash == "" {
+		return ErrNoOwner
+	}
+
+	if token == "" || subtle.ConstantTimeCompare([]byte(HashOwnerToken(token)), []byte(record.OwnerTokenHash)) != 1 {
+		return ErrNotOwner
+	}
+	return nil
+}
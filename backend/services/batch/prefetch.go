@@ -0,0 +1,104 @@
+package batch
+
+import (
+	"context"
+	"filesh/services/storage"
+	"io"
+	"sync"
+)
+
+// prefetcher opens a list of objects in order while keeping up to a fixed
+// number open at once: the one being read plus the next few, so the round
+// trip to storage for each chunk overlaps reading the previous one. Objects
+// are handed out in their original order. Closing the prefetcher cancels any
+// opens still in flight and releases those that were never read.
+type prefetcher struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	slots    chan struct{}
+	results  []chan openResult
+	next     int
+	launched chan int
+}
+
+// openResult is the outcome of opening one object
+type openResult struct {
+	reader io.ReadCloser
+	err    error
+}
+
+// newPrefetcher starts opening names with at most concurrency of them open at
+// a time. A concurrency of one reads objects strictly one after another.
+func newPrefetcher(ctx context.Context, store storage.ObjectStorage, names []string, concurrency int) *prefetcher {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &prefetcher{
+		ctx:      ctx,
+		cancel:   cancel,
+		slots:    make(chan struct{}, max(concurrency, 1)),
+		results:  make([]chan openResult, len(names)),
+		launched: make(chan int, 1),
+	}
+	for i := range p.results {
+		p.results[i] = make(chan openResult, 1)
+	}
+
+	go func() {
+		i := 0
+		defer func() { p.launched <- i }()
+		for ; i < len(names); i++ {
+			// A slot frees up when a handed-out object is closed
+			select {
+			case p.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int) {
+				reader, err := store.DownloadObject(ctx, names[i])
+				p.results[i] <- openResult{reader: reader, err: err}
+			}(i)
+		}
+	}()
+	return p
+}
+
+// Next returns the next object in order, waiting for it to be opened. The
+// caller must close it before more than the concurrency limit can be opened.
+func (p *prefetcher) Next() (io.ReadCloser, error) {
+	select {
+	case result := <-p.results[p.next]:
+		p.next++
+		if result.err != nil {
+			<-p.slots
+			return nil, result.err
+		}
+		return &slotReader{ReadCloser: result.reader, release: func() { <-p.slots }}, nil
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	}
+}
+
+// Close cancels outstanding opens and closes objects that were opened but
+// never handed out. It must be called exactly once.
+func (p *prefetcher) Close() {
+	p.cancel()
+	launched := <-p.launched
+	for i := p.next; i < launched; i++ {
+		if result := <-p.results[i]; result.reader != nil {
+			result.reader.Close()
+		}
+	}
+}
+
+// slotReader gives its prefetcher slot back when closed
+type slotReader struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Close closes the object and frees its slot
+func (r *slotReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,126 @@
+package batch
+
+import (
+	"context"
+	"filesh/models"
+	"filesh/services/tenant"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Reaper periodically deletes batches whose metadata sidecar says they have
+// expired. It complements bucket lifecycle rules, which some storage backends
+// don't support. Legacy batches without a sidecar are left to the lifecycle.
+type Reaper struct {
+	batchService *Service
+	interval     time.Duration
+	dryRun       bool
+	logger       *log.Logger
+}
+
+// batchRef identifies a batch within its tenant namespace
+type batchRef struct {
+	tenant string
+	id     string
+}
+
+// String returns the qualified "tenant/batchId" form
+func (b batchRef) String() string {
+	return b.tenant + "/" + b.id
+}
+
+// NewReaper creates a new reaper for expired batches
+func NewReaper(batchService *Service, interval time.Duration, dryRun bool, logger *log.Logger) *Reaper {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[REAPER] ", log.LstdFlags)
+	}
+
+	return &Reaper{
+		batchService: batchService,
+		interval:     interval,
+		dryRun:       dryRun,
+		logger:       logger,
+	}
+}
+
+// Start runs the reaper until the context is cancelled
+func (r *Reaper) Start(ctx context.Context) {
+	r.logger.Printf("Reaper started, interval: %v, dry run: %v", r.interval, r.dryRun)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Printf("Reaper stopped")
+			return
+		case <-ticker.C:
+			summary, err := r.RunOnce(ctx, r.dryRun)
+			if err != nil {
+				r.logger.Printf("Reaper run failed: %v", err)
+				continue
+			}
+			r.logger.Printf("Reaper run complete: scanned %d batches, %d expired, %d deleted, %d bytes reclaimed (dry run: %v)",
+				summary.BatchesScanned, summary.BatchesExpired, summary.BatchesDeleted, summary.BytesReclaimed, summary.DryRun)
+		}
+	}
+}
+
+// RunOnce scans all batches once and deletes the expired ones. In dry-run
+// mode it only reports what would be deleted.
+func (r *Reaper) RunOnce(ctx context.Context, dryRun bool) (*models.PurgeSummary, error) {
+	objects, err := r.batchService.storage.ListObjects(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	// Find batches with a metadata sidecar and total up their sizes in one pass.
+	// Batches are keyed by "tenant/batchId" since IDs are only unique per tenant.
+	batchSizes := make(map[string]int64)
+	var batches []batchRef
+	for _, obj := range objects {
+		tenantID, batchID, rest, ok := ParseObjectName(obj.Name)
+		if !ok {
+			continue
+		}
+		ref := batchRef{tenant: tenantID, id: batchID}
+		batchSizes[ref.String()] += obj.Size
+		if rest == metadataObject {
+			batches = append(batches, ref)
+		}
+	}
+
+	summary := &models.PurgeSummary{DryRun: dryRun}
+
+	for _, ref := range batches {
+		summary.BatchesScanned++
+		tenantCtx := tenant.NewContext(ctx, ref.tenant)
+
+		record, err := r.batchService.LoadMetadata(tenantCtx, ref.id)
+		if err != nil {
+			r.logger.Printf("Skipping batch %s: %v", ref, err)
+			continue
+		}
+		if record == nil || !record.IsExpired() {
+			continue
+		}
+
+		summary.BatchesExpired++
+		summary.ExpiredIDs = append(summary.ExpiredIDs, ref.String())
+		if dryRun {
+			summary.BytesReclaimed += batchSizes[ref.String()]
+			continue
+		}
+
+		if _, err := r.batchService.DeleteBatch(tenantCtx, ref.id); err != nil {
+			r.logger.Printf("Failed to delete expired batch %s: %v", ref, err)
+			continue
+		}
+		summary.BatchesDeleted++
+		summary.BytesReclaimed += batchSizes[ref.String()]
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,98 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"filesh/models"
+	"filesh/services/tenant"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// slugPattern is the shape of a custom batch ID: 3 to 64 lowercase letters,
+// digits and inner hyphens
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}[a-z0-9]$`)
+
+// ErrBatchExists is returned when a batch is renamed to an ID already in use
+var ErrBatchExists = errors.New("batch ID already in use")
+
+// ValidateSlug checks that a custom batch ID has the allowed shape
+func ValidateSlug(slug string) error {
+	if !slugPattern.MatchString(slug) || strings.Contains(slug, "--") {
+		return fmt.Errorf("%w: newId must be 3 to 64 lowercase letters, digits or single hyphens, not starting or ending with a hyphen", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// RenameBatch moves a batch to a custom ID by copying every object under its
+// prefix to the new one, writing its metadata under the new ID and then
+// deleting the old objects. Nothing is deleted unless every copy succeeded;
+// a failed copy removes the copies made so far instead. The new ID may not
+// be taken by another batch or alias. Aliases of the old ID are not updated.
+func (s *Service) RenameBatch(ctx context.Context, batchID, newID string) (*models.BatchMetadata, error) {
+	if err := ValidateSlug(newID); err != nil {
+		return nil, err
+	}
+	if newID == batchID {
+		return nil, fmt.Errorf("%w: newId is the batch's current ID", ErrInvalidRequest)
+	}
+
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrBatchNotFound
+	}
+	if record.IsExpired() {
+		return nil, ErrBatchExpired
+	}
+
+	taken, err := s.aliasTaken(ctx, tenant.FromContext(ctx), newID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrBatchExists
+	}
+
+	srcPrefix := batchPrefix(ctx, batchID)
+	objects, err := s.storage.ListObjects(ctx, srcPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch objects: %w", err)
+	}
+
+	// The sidecar is rewritten with the new ID rather than copied
+	names := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		if name := strings.TrimPrefix(obj.Name, srcPrefix); name != metadataObject {
+			names = append(names, name)
+		}
+	}
+
+	copied, err := s.copyObjects(ctx, srcPrefix, batchPrefix(ctx, newID), names)
+	if err == nil {
+		renamed := *record
+		renamed.ID = newID
+		if err = s.SaveMetadata(ctx, &renamed); err == nil {
+			record = &renamed
+		}
+	}
+	if err != nil {
+		if _, errs := s.deleteObjects(context.WithoutCancel(ctx), copied); len(errs) > 0 {
+			s.logger.Printf("Could not clean up partial rename of batch %s to %s", batchID, newID)
+		}
+		return nil, err
+	}
+
+	// The batch is complete under its new ID; leftovers of the old one can
+	// still be removed by the owner with the same token
+	if _, err := s.DeleteBatch(ctx, batchID); err != nil {
+		s.logger.Printf("Warning: Renamed batch %s to %s but could not delete the old objects: %v", batchID, newID, err)
+	}
+
+	s.logger.Printf("Renamed batch %s to %s (%d objects)", batchID, newID, len(names)+1)
+	metadata := record.Metadata()
+	return &metadata, nil
+}
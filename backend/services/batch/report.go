@@ -0,0 +1,213 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"filesh/models"
+	"filesh/services/tenant"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxReportReasonLength caps the length of a report reason in characters
+	MaxReportReasonLength = 200
+	// maxReportReasons bounds the distinct reasons kept per batch; further
+	// reasons are counted under otherReason
+	maxReportReasons = 50
+	otherReason      = "other"
+	// reportNamespace is the top-level prefix holding abuse reports
+	reportNamespace = "reports"
+)
+
+// ErrInvalidReport is returned when a report reason fails validation
+var ErrInvalidReport = errors.New("invalid report")
+
+// reportObjectName returns the storage object name of a batch's reports
+func reportObjectName(tenantID, batchID string) string {
+	return fmt.Sprintf("%s/%s/%s.json", reportNamespace, tenantID, batchID)
+}
+
+// hashReporter derives a keyed hash of a reporter's IP so repeat reports can
+// be recognised without storing the address
+func (s *Service) hashReporter(batchID, ip string) string {
+	mac := hmac.New(sha256.New, s.opts.ReportHashKey)
+	mac.Write([]byte(batchID + "\x00" + ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReportBatch flags a batch for review. Each reporter is counted once per
+// batch; repeat reports from the same IP are accepted but not counted again.
+func (s *Service) ReportBatch(ctx context.Context, batchID, reason, reporterIP string) error {
+	reason = strings.ToLower(strings.TrimSpace(reason))
+	if reason == "" || utf8.RuneCountInString(reason) > MaxReportReasonLength {
+		return fmt.Errorf("%w: reason must be between 1 and %d characters", ErrInvalidReport, MaxReportReasonLength)
+	}
+
+	objects, err := s.storage.ListObjects(ctx, batchPrefix(ctx, batchID))
+	if err != nil {
+		return fmt.Errorf("failed to list batch objects: %w", err)
+	}
+	if len(objects) == 0 {
+		return ErrBatchNotFound
+	}
+
+	s.reportsMu.Lock()
+	defer s.reportsMu.Unlock()
+
+	tenantID := tenant.FromContext(ctx)
+	record, err := s.loadReport(ctx, tenantID, batchID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if record == nil {
+		record = &models.ReportRecord{
+			BatchID:         batchID,
+			Tenant:          tenantID,
+			Reasons:         make(map[string]int),
+			FirstReportedAt: now,
+		}
+	}
+
+	reporter := s.hashReporter(batchID, reporterIP)
+	if slices.Contains(record.Reporters, reporter) {
+		return nil
+	}
+	record.Reporters = append(record.Reporters, reporter)
+	if _, known := record.Reasons[reason]; !known && len(record.Reasons) >= maxReportReasons {
+		reason = otherReason
+	}
+	record.Reasons[reason]++
+	record.LastReportedAt = now
+
+	if err := s.saveReport(ctx, record); err != nil {
+		return err
+	}
+
+	s.logger.Printf("Batch %s reported (%d reports)", batchID, len(record.Reporters))
+	return nil
+}
+
+// ListReports returns every reported batch across all tenants, most reported first
+func (s *Service) ListReports(ctx context.Context) ([]models.ReportSummary, error) {
+	objects, err := s.storage.ListObjects(ctx, reportNamespace+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	summaries := make([]models.ReportSummary, 0, len(objects))
+	for _, obj := range objects {
+		record, err := s.readReport(ctx, obj.Name)
+		if err != nil {
+			s.logger.Printf("Could not read report %s: %v", obj.Name, err)
+			continue
+		}
+		summaries = append(summaries, record.Summary())
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Reports != summaries[j].Reports {
+			return summaries[i].Reports > summaries[j].Reports
+		}
+		return summaries[i].LastReportedAt.After(summaries[j].LastReportedAt)
+	})
+
+	return summaries, nil
+}
+
+// TakedownBatch deletes a batch in the request's tenant and records the
+// takedown on its reports. It returns the number of objects removed.
+func (s *Service) TakedownBatch(ctx context.Context, batchID string) (int, error) {
+	deleted, err := s.DeleteBatch(ctx, batchID)
+	if err != nil {
+		return deleted, err
+	}
+
+	s.reportsMu.Lock()
+	defer s.reportsMu.Unlock()
+
+	tenantID := tenant.FromContext(ctx)
+	record, err := s.loadReport(ctx, tenantID, batchID)
+	if err != nil {
+		return deleted, err
+	}
+	now := time.Now()
+	if record == nil {
+		// Operators may act on batches nobody reported
+		record = &models.ReportRecord{
+			BatchID:         batchID,
+			Tenant:          tenantID,
+			Reasons:         make(map[string]int),
+			FirstReportedAt: now,
+			LastReportedAt:  now,
+		}
+	}
+	record.TakenDownAt = now
+
+	if err := s.saveReport(ctx, record); err != nil {
+		return deleted, err
+	}
+
+	s.logger.Printf("Took down batch %s (%d objects)", batchID, deleted)
+	return deleted, nil
+}
+
+// loadReport reads the reports of a batch, returning nil when there are none
+func (s *Service) loadReport(ctx context.Context, tenantID, batchID string) (*models.ReportRecord, error) {
+	objectName := reportObjectName(tenantID, batchID)
+
+	exists, err := s.storage.CheckObjectExists(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check reports: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	return s.readReport(ctx, objectName)
+}
+
+// readReport decodes a report object
+func (s *Service) readReport(ctx context.Context, objectName string) (*models.ReportRecord, error) {
+	reader, err := s.storage.DownloadObject(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reports: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reports: %w", err)
+	}
+
+	var record models.ReportRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode reports: %w", err)
+	}
+	return &record, nil
+}
+
+// saveReport persists the reports of a batch
+func (s *Service) saveReport(ctx context.Context, record *models.ReportRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode reports: %w", err)
+	}
+
+	_, err = s.storage.UploadObject(ctx, reportObjectName(record.Tenant, record.BatchID), bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to store reports: %w", err)
+	}
+	return nil
+}
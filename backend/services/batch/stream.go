@@ -0,0 +1,231 @@
+package batch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"filesh/services/storage"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Stream is a run of chunks served as one logical file
+type Stream struct {
+	// Name is the file name to present to clients
+	Name string
+	// Size is the total stored size of the chunks
+	Size int64
+	// ContentType is the batch's declared media type, if any
+	ContentType string
+
+	storage  storage.ObjectStorage
+	prefetch int
+	names    []string
+	sizes    []int64
+	etags    []string
+}
+
+// BatchStream returns the whole batch as one stream of its chunks in order.
+// Batches with a manifest of exactly one file are named after it. When the
+// expected chunk count is unknown, every chunk up to the highest index
+// present must exist.
+func (s *Service) BatchStream(ctx context.Context, batchID string) (*Stream, error) {
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.IsExpired() {
+		return nil, ErrBatchExpired
+	}
+
+	chunks, err := s.chunkObjects(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		if record == nil {
+			return nil, ErrBatchNotFound
+		}
+		return nil, fmt.Errorf("%w: no chunks uploaded yet", ErrFileIncomplete)
+	}
+
+	count := 0
+	if record != nil && record.TotalChunks > 0 {
+		count = record.TotalChunks
+	} else {
+		for index := range chunks {
+			count = max(count, index+1)
+		}
+	}
+
+	name := batchID
+	if record != nil && len(record.Files) == 1 {
+		name = record.Files[0].Name
+	}
+	stream, err := s.newStream(ctx, batchID, name, chunks, 0, count)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		stream.ContentType = record.ContentType
+	}
+	return stream, nil
+}
+
+// chunkObjects maps the index of every stored chunk of a batch to its
+// listing entry, from a single listing instead of statting every chunk
+func (s *Service) chunkObjects(ctx context.Context, batchID string) (map[int]storage.ObjectInfo, error) {
+	prefix := batchPrefix(ctx, batchID)
+	objects, err := s.storage.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch chunks: %w", err)
+	}
+	// Streams are addressed by offset, so they need the exact size of each chunk
+	if err := storage.ResolveSizes(ctx, s.storage, objects, s.opts.BulkConcurrency); err != nil {
+		return nil, fmt.Errorf("failed to size batch chunks: %w", err)
+	}
+
+	chunks := make(map[int]storage.ObjectInfo, len(objects))
+	for _, obj := range objects {
+		if index, err := strconv.Atoi(strings.TrimPrefix(obj.Name, prefix)); err == nil {
+			chunks[index] = obj
+		}
+	}
+	return chunks, nil
+}
+
+// newStream builds a stream over count chunks starting at start, returning
+// ErrFileIncomplete if any of them is missing
+func (s *Service) newStream(ctx context.Context, batchID, name string, chunks map[int]storage.ObjectInfo, start, count int) (*Stream, error) {
+	prefix := batchPrefix(ctx, batchID)
+	stream := &Stream{
+		Name:     name,
+		storage:  s.storage,
+		prefetch: s.opts.BulkConcurrency,
+		names:    make([]string, 0, count),
+		sizes:    make([]int64, 0, count),
+		etags:    make([]string, 0, count),
+	}
+
+	for index := start; index < start+count; index++ {
+		chunk, ok := chunks[index]
+		if !ok {
+			return nil, fmt.Errorf("%w: chunk %d is missing", ErrFileIncomplete, index)
+		}
+		stream.names = append(stream.names, prefix+strconv.Itoa(index))
+		stream.sizes = append(stream.sizes, chunk.Size)
+		stream.etags = append(stream.etags, chunk.ETag)
+		stream.Size += chunk.Size
+	}
+	return stream, nil
+}
+
+// ETag is a composite entity tag for the stream, derived from the ETags of
+// its chunks in order and suffixed with the chunk count, in the manner of S3
+// multipart ETags. It changes whenever any chunk is replaced.
+func (s *Stream) ETag() string {
+	hash := sha256.New()
+	for _, etag := range s.etags {
+		hash.Write([]byte(etag))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(hash.Sum(nil))[:32], len(s.etags))
+}
+
+// Open reads length bytes of the stream starting at offset. Only the chunks
+// overlapping that range are fetched; the partial first chunk is skipped
+// through.
+func (s *Stream) Open(ctx context.Context, offset, length int64) io.ReadCloser {
+	reader := &chunkSequenceReader{ctx: ctx, storage: s.storage, prefetch: s.prefetch, remaining: length}
+	for i := range s.names {
+		if offset >= s.sizes[i] {
+			offset -= s.sizes[i]
+			continue
+		}
+		reader.skip = offset
+
+		// Stop at the chunk holding the last byte, so nothing past the range
+		// is prefetched
+		end, needed := i, offset+length
+		for end < len(s.names) && needed > s.sizes[end] {
+			needed -= s.sizes[end]
+			end++
+		}
+		reader.names = s.names[i:min(end+1, len(s.names))]
+		break
+	}
+	return reader
+}
+
+// chunkSequenceReader concatenates chunk objects. The next few are opened
+// while the current one is read, bounded by prefetch, so a long download
+// holds a fixed number of connections.
+type chunkSequenceReader struct {
+	ctx       context.Context
+	storage   storage.ObjectStorage
+	prefetch  int
+	names     []string
+	skip      int64 // Bytes to discard from the first chunk
+	remaining int64
+	chunks    *prefetcher
+	opened    int
+	current   io.ReadCloser
+}
+
+// Read implements io.Reader
+func (r *chunkSequenceReader) Read(p []byte) (int, error) {
+	for {
+		if r.remaining <= 0 {
+			return 0, io.EOF
+		}
+		if r.current == nil {
+			if r.opened == len(r.names) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			if r.chunks == nil {
+				r.chunks = newPrefetcher(r.ctx, r.storage, r.names, r.prefetch)
+			}
+			reader, err := r.chunks.Next()
+			if err != nil {
+				return 0, fmt.Errorf("failed to open %s: %w", r.names[r.opened], err)
+			}
+			r.current = reader
+			r.opened++
+
+			if r.skip > 0 {
+				if _, err := io.CopyN(io.Discard, r.current, r.skip); err != nil {
+					return 0, fmt.Errorf("failed to seek chunk: %w", err)
+				}
+				r.skip = 0
+			}
+		}
+
+		n, err := r.current.Read(p[:min(int64(len(p)), r.remaining)])
+		r.remaining -= int64(n)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close releases the chunk currently being read and any opened ahead of it
+func (r *chunkSequenceReader) Close() error {
+	var err error
+	if r.current != nil {
+		err = r.current.Close()
+		r.current = nil
+	}
+	if r.chunks != nil {
+		r.chunks.Close()
+		r.chunks = nil
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package batch
+
+import (
+	"context"
+	"filesh/models"
+	"filesh/services/tenant"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Object tag limits imposed by S3-compatible storage
+const (
+	MaxTags           = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// tagPattern matches the characters S3 accepts in tag keys and values
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// validateTags checks batch tags against the storage tagging limits
+func validateTags(tags map[string]string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidRequest, MaxTags)
+	}
+	for key, value := range tags {
+		if key == "" || utf8.RuneCountInString(key) > maxTagKeyLength || !tagPattern.MatchString(key) {
+			return fmt.Errorf("%w: invalid tag key %q", ErrInvalidRequest, key)
+		}
+		if utf8.RuneCountInString(value) > maxTagValueLength || !tagPattern.MatchString(value) {
+			return fmt.Errorf("%w: invalid value for tag %q", ErrInvalidRequest, key)
+		}
+	}
+	return nil
+}
+
+// ParseTagFilter splits a "key:value" filter. The value may be empty to
+// match any object carrying the key.
+func ParseTagFilter(filter string) (key, value string, err error) {
+	key, value, _ = strings.Cut(filter, ":")
+	if key == "" {
+		return "", "", fmt.Errorf("%w: tag filter must have the form key:value", ErrInvalidRequest)
+	}
+	return key, value, nil
+}
+
+// FindBatchesByTag lists batches across all tenants whose metadata sidecar
+// carries the tag. Chunks are tagged alongside the sidecar, so checking the
+// sidecar alone keeps this to one tag lookup per batch.
+func (s *Service) FindBatchesByTag(ctx context.Context, key, value string) ([]*models.BatchSummary, error) {
+	objects, err := s.storage.ListObjects(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	// Aggregate objects per tenant and batch prefix
+	summaries := make(map[string]*models.BatchSummary)
+	for _, obj := range objects {
+		tenantID, batchID, rest, ok := ParseObjectName(obj.Name)
+		if !ok {
+			continue
+		}
+
+		summary, exists := summaries[tenantID+"/"+batchID]
+		if !exists {
+			summary = &models.BatchSummary{ID: batchID, Tenant: tenantID}
+			summaries[tenantID+"/"+batchID] = summary
+		}
+		if rest == metadataObject {
+			summary.CreatedAt = obj.LastModified
+			continue
+		}
+		if isSidecar(rest) {
+			continue
+		}
+		summary.Chunks++
+		summary.TotalSize += obj.Size
+	}
+
+	matches := make([]*models.BatchSummary, 0)
+	for _, summary := range summaries {
+		// Legacy batches without a sidecar were never tagged
+		if summary.CreatedAt.IsZero() {
+			continue
+		}
+
+		tags, err := s.storage.GetObjectTags(ctx, MetadataObjectName(summary.Tenant, summary.ID))
+		if err != nil {
+			s.logger.Printf("Could not read tags of batch %s: %v", summary.ID, err)
+			continue
+		}
+		if got, ok := tags[key]; !ok || (value != "" && got != value) {
+			continue
+		}
+
+		record, err := s.LoadMetadata(tenant.NewContext(ctx, summary.Tenant), summary.ID)
+		if err != nil || record == nil {
+			s.logger.Printf("Could not load metadata for batch %s: %v", summary.ID, err)
+			continue
+		}
+		summary.CreatedAt = record.CreatedAt
+		summary.ExpiresAt = record.ExpiresAt
+		summary.Tags = tags
+		matches = append(matches, summary)
+	}
+
+	// Newest first, as in ListBatches
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	return matches, nil
+}
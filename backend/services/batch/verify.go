@@ -0,0 +1,57 @@
+package batch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"filesh/models"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrNoDigest is returned when verifying a batch created without a sha256
+	ErrNoDigest = errors.New("batch has no expected digest")
+	// ErrDigestMismatch is returned when the reassembled batch doesn't match its digest
+	ErrDigestMismatch = errors.New("batch digest mismatch")
+)
+
+// VerifyBatch reads the whole batch, every chunk in order, and compares its
+// SHA-256 with the digest given at creation. This catches missing, truncated
+// or reordered chunks that per-chunk checksums can't. The result is returned
+// along with ErrDigestMismatch when the digests differ.
+func (s *Service) VerifyBatch(ctx context.Context, batchID string) (*models.BatchVerification, error) {
+	record, err := s.LoadMetadata(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.SHA256 == "" {
+		return nil, ErrNoDigest
+	}
+
+	stream, err := s.BatchStream(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	reader := stream.Open(ctx, 0, stream.Size)
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+
+	result := &models.BatchVerification{
+		BatchID:  batchID,
+		Size:     stream.Size,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+		Expected: record.SHA256,
+	}
+	result.Verified = result.SHA256 == result.Expected
+	if !result.Verified {
+		s.logger.Printf("Batch %s failed verification: expected %s, got %s", batchID, result.Expected, result.SHA256)
+		return result, ErrDigestMismatch
+	}
+	return result, nil
+}
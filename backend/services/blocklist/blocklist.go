@@ -0,0 +1,151 @@
+// Package blocklist keeps the set of batch IDs that were taken down, so they
+// can neither be served nor uploaded to again.
+package blocklist
+
+import (
+	"bytes"
+	"context"
+	"filesh/models"
+	"filesh/services/storage"
+	"filesh/services/tenant"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespace is the top-level prefix holding one empty marker object per
+// blocked batch. It is outside the bucket lifecycle, so a take-down lasts
+// until the batch is unblocked.
+const Namespace = "blocked"
+
+// Service answers block-list lookups from memory and refreshes the set from
+// storage periodically, so other instances' changes are picked up
+type Service struct {
+	storage  storage.ObjectStorage
+	interval time.Duration
+	logger   *log.Logger
+
+	mu      sync.RWMutex
+	blocked map[string]time.Time // Keyed by "tenant/batchId"
+}
+
+// NewService creates a new block-list service that refreshes every interval
+func NewService(storage storage.ObjectStorage, interval time.Duration, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[BLOCKLIST] ", log.LstdFlags)
+	}
+
+	return &Service{
+		storage:  storage,
+		interval: interval,
+		logger:   logger,
+		blocked:  make(map[string]time.Time),
+	}
+}
+
+// markerName returns the storage object name marking a batch as blocked
+func markerName(tenantID, batchID string) string {
+	return fmt.Sprintf("%s/%s/%s", Namespace, tenantID, batchID)
+}
+
+// Start loads the block-list and keeps refreshing it until the context is cancelled
+func (s *Service) Start(ctx context.Context) {
+	if err := s.Refresh(ctx); err != nil {
+		s.logger.Printf("Initial block-list load failed: %v", err)
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				s.logger.Printf("Block-list refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// Refresh replaces the in-memory set with the markers in storage
+func (s *Service) Refresh(ctx context.Context) error {
+	objects, err := s.storage.ListObjects(ctx, Namespace+"/")
+	if err != nil {
+		return fmt.Errorf("failed to list blocked batches: %w", err)
+	}
+
+	blocked := make(map[string]time.Time, len(objects))
+	for _, obj := range objects {
+		key := strings.TrimPrefix(obj.Name, Namespace+"/")
+		if strings.Count(key, "/") != 1 {
+			continue
+		}
+		blocked[key] = obj.LastModified
+	}
+
+	s.mu.Lock()
+	s.blocked = blocked
+	s.mu.Unlock()
+	return nil
+}
+
+// IsBlocked reports whether a batch in the request's tenant is blocked
+func (s *Service) IsBlocked(ctx context.Context, batchID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, blocked := s.blocked[tenant.FromContext(ctx)+"/"+batchID]
+	return blocked
+}
+
+// Block adds a batch in the request's tenant to the block-list
+func (s *Service) Block(ctx context.Context, batchID string) error {
+	tenantID := tenant.FromContext(ctx)
+	if _, err := s.storage.UploadObject(ctx, markerName(tenantID, batchID), bytes.NewReader(nil), 0); err != nil {
+		return fmt.Errorf("failed to block batch: %w", err)
+	}
+
+	s.mu.Lock()
+	s.blocked[tenantID+"/"+batchID] = time.Now()
+	s.mu.Unlock()
+
+	s.logger.Printf("Blocked batch %s/%s", tenantID, batchID)
+	return nil
+}
+
+// Unblock removes a batch in the request's tenant from the block-list
+func (s *Service) Unblock(ctx context.Context, batchID string) error {
+	tenantID := tenant.FromContext(ctx)
+	if err := s.storage.DeleteObject(ctx, markerName(tenantID, batchID)); err != nil {
+		return fmt.Errorf("failed to unblock batch: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.blocked, tenantID+"/"+batchID)
+	s.mu.Unlock()
+
+	s.logger.Printf("Unblocked batch %s/%s", tenantID, batchID)
+	return nil
+}
+
+// List returns the blocked batches, most recently blocked first
+func (s *Service) List() []models.BlockedBatch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]models.BlockedBatch, 0, len(s.blocked))
+	for key, blockedAt := range s.blocked {
+		tenantID, batchID, _ := strings.Cut(key, "/")
+		entries = append(entries, models.BlockedBatch{BatchID: batchID, Tenant: tenantID, BlockedAt: blockedAt})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].BlockedAt.After(entries[j].BlockedAt)
+	})
+	return entries
+}
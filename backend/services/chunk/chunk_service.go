@@ -2,68 +2,147 @@ package chunk
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"filesh/models"
 	"filesh/services/storage"
+	"filesh/services/tenant"
+	"filesh/utils"
 	"fmt"
 	"io"
 	"log"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// MaxCheckIndices caps how many chunks a single bulk check may query
+	MaxCheckIndices = 10000
+	// MaxPresignIndices caps how many upload URLs a single presign request may ask for
+	MaxPresignIndices = 1000
+	// MaxChunkIndex is the highest chunk index accepted, matching the largest
+	// expected chunk count a batch may declare
+	MaxChunkIndex = 99999
+	// checkWorkers bounds the number of concurrent storage lookups in a bulk check
+	checkWorkers = 16
+)
+
+var (
+	// ErrChunkExists is returned when a chunk already exists and overwriting was not allowed
+	ErrChunkExists = errors.New("chunk already exists")
+	// ErrChecksumMismatch is returned when uploaded data doesn't match the client's digest
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrDigestRequired is returned when a chunk that can't be read ahead
+	// comes without its expected digest
+	ErrDigestRequired = errors.New("streamed chunk needs its SHA-256 up front")
+	// ErrChunkNotFound is returned when a chunk has not been stored
+	ErrChunkNotFound = errors.New("chunk not found")
+)
+
 // Service handles chunk-related operations
 type Service struct {
-	storage storage.ObjectStorage
-	logger  *log.Logger
+	storage    storage.ObjectStorage
+	verifySize bool
+	logger     *log.Logger
 }
 
-// NewService creates a new chunk service
-func NewService(storage storage.ObjectStorage, logger *log.Logger) *Service {
+// NewService creates a new chunk service. With verifySize, the size storage
+// reports for every upload is compared with the size sent.
+func NewService(storage storage.ObjectStorage, verifySize bool, logger *log.Logger) *Service {
 	if logger == nil {
 		logger = log.New(log.Writer(), "[CHUNK] ", log.LstdFlags)
 	}
 	
 	return &Service{
-		storage: storage,
-		logger:  logger,
+		storage:    storage,
+		verifySize: verifySize,
+		logger:     logger,
 	}
 }
 
-// UploadChunk uploads a file chunk to storage
-func (s *Service) UploadChunk(ctx context.Context, batchID string, chunkIndex int, reader io.Reader, size int64) (*models.ChunkUploadResponse, error) {
-	// Calculate object name based on batch ID and chunk index
-	objectName := fmt.Sprintf("%s/%d", batchID, chunkIndex)
+// UploadOptions controls how a chunk upload is performed
+type UploadOptions struct {
+	// Overwrite allows replacing an existing chunk; otherwise ErrChunkExists is returned
+	Overwrite bool
+	// ExpectedSHA256 is the client-supplied hex digest the chunk must match, if any
+	ExpectedSHA256 string
+	// Tags are the batch's object tags applied to the stored chunk
+	Tags map[string]string
+	// ContentType is the media type declared by the client, used for previews
+	ContentType string
+	// StorageClass overrides the configured storage class for the chunk
+	StorageClass string
+	// Encryption holds the client's encryption parameters, stored alongside the chunk
+	Encryption *models.EncryptionInfo
+}
+
+// UploadChunk uploads a file chunk to storage, computing its SHA-256 digest on
+// the way. The digest is stored with the chunk, so a reader that can't seek
+// needs opts.ExpectedSHA256; without it ErrDigestRequired is returned.
+func (s *Service) UploadChunk(ctx context.Context, batchID string, chunkIndex int, reader io.Reader, size int64, opts UploadOptions) (*models.ChunkUploadResponse, error) {
+	// Calculate object name based on tenant, batch ID and chunk index
+	objectName := s.GetObjectName(ctx, batchID, chunkIndex)
+	
+	// Refuse to replace an existing chunk unless asked to
+	if !opts.Overwrite {
+		exists, err := s.storage.CheckObjectExists(ctx, objectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check chunk: %w", err)
+		}
+		if exists {
+			return nil, ErrChunkExists
+		}
+	}
 	
 	// Log chunk details
 	s.logger.Printf("Uploading chunk %d for batch %s, size: %d bytes", chunkIndex, batchID, size)
 	
+	// The digest is stored with the chunk, so work it out before uploading
+	// when the client didn't tell us
+	expected := strings.ToLower(opts.ExpectedSHA256)
+	if expected == "" {
+		digest, err := precomputeDigest(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash chunk: %w", err)
+		}
+		expected = digest
+	}
+
+	// Hash the data again as it streams to storage to verify it, keeping the
+	// reader seekable so storage can rewind it to retry
+	hasher := sha256.New()
+	teeReader := utils.NewHashingReader(reader, hasher)
+
+	uploadOpts := storage.UploadOptions{ContentType: opts.ContentType, Tags: opts.Tags, StorageClass: opts.StorageClass, Retain: true}
+	uploadOpts.Metadata = map[string]string{storage.MetadataSHA256: expected}
+	uploadOpts.Metadata = encryptionMetadata(uploadOpts.Metadata, opts.Encryption)
+
 	startTime := time.Now()
 	
 	// Upload the chunk
-	err := s.storage.UploadObject(ctx, objectName, reader, size)
+	info, err := s.storage.UploadObjectWithOptions(ctx, objectName, teeReader, size, uploadOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload chunk: %w", err)
 	}
 	
 	uploadDuration := time.Since(startTime)
-	
-	// Get object info for the response
-	info, err := s.storage.GetObjectInfo(ctx, objectName)
-	if err != nil {
-		// Even if we can't get info, we still uploaded successfully
-		s.logger.Printf("Warning: Could not get object info for %s: %v", objectName, err)
-		
-		return &models.ChunkUploadResponse{
-			Success:    true,
-			BatchID:    batchID,
-			ChunkIndex: chunkIndex,
-			Size:       size,
-			UploadTime: uploadDuration.String(),
-		}, nil
+	observeDuration(uploadSeconds, info.Size, uploadDuration)
+	digest := hex.EncodeToString(hasher.Sum(nil))
+
+	// Verify the digest and discard chunks that don't match
+	if digest != expected {
+		s.logger.Printf("Checksum mismatch for chunk %d in batch %s, removing it", chunkIndex, batchID)
+		if err := s.storage.DeleteObject(ctx, objectName); err != nil {
+			s.logger.Printf("Warning: Could not delete corrupt chunk %s: %v", objectName, err)
+		}
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, digest)
 	}
-	
-	// Check for size mismatch
-	if info.Size != size {
+
+	// Check for size mismatch against what storage reported for the upload
+	if s.verifySize && info.Size != size {
 		s.logger.Printf("WARNING: Size mismatch for chunk %d in batch %s. Expected: %d bytes, Got: %d bytes",
 			chunkIndex, batchID, size, info.Size)
 	}
@@ -78,6 +157,7 @@ func (s *Service) UploadChunk(ctx context.Context, batchID string, chunkIndex in
 		ChunkIndex: chunkIndex,
 		Size:       info.Size,
 		ETag:       info.ETag,
+		SHA256:     digest,
 		Uploaded:   info.LastModified.Format(time.RFC3339),
 		UploadTime: uploadDuration.String(),
 	}, nil
@@ -85,8 +165,8 @@ func (s *Service) UploadChunk(ctx context.Context, batchID string, chunkIndex in
 
 // CheckChunk checks if a chunk exists
 func (s *Service) CheckChunk(ctx context.Context, batchID string, chunkIndex int) (*models.ChunkStatusResponse, error) {
-	// Calculate object name based on batch ID and chunk index
-	objectName := fmt.Sprintf("%s/%d", batchID, chunkIndex)
+	// Calculate object name based on tenant, batch ID and chunk index
+	objectName := s.GetObjectName(ctx, batchID, chunkIndex)
 
 	// Check if object exists
 	exists, err := s.storage.CheckObjectExists(ctx, objectName)
@@ -115,59 +195,261 @@ func (s *Service) CheckChunk(ctx context.Context, batchID string, chunkIndex int
 		ChunkIndex: chunkIndex,
 		Size:       info.Size,
 		ETag:       info.ETag,
+		SHA256:     info.SHA256,
+		Uploaded:   info.LastModified.Format(time.RFC3339),
+		Encryption: EncryptionFromMetadata(info.UserMetadata),
+	}, nil
+}
+
+// CheckChunks checks the existence of many chunks concurrently using a bounded worker pool
+func (s *Service) CheckChunks(ctx context.Context, batchID string, indices []int) (*models.ChunkCheckResponse, error) {
+	if len(indices) > MaxCheckIndices {
+		return nil, fmt.Errorf("too many indices: %d (max %d)", len(indices), MaxCheckIndices)
+	}
+
+	results := make(map[int]*models.ChunkStatusResponse, len(indices))
+	jobs := make(chan int)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	// Start the workers
+	workers := checkWorkers
+	if len(indices) < workers {
+		workers = len(indices)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				status, err := s.CheckChunk(ctx, batchID, index)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					results[index] = status
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Feed the indices, skipping duplicates and negatives
+	seen := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		if index < 0 || seen[index] {
+			continue
+		}
+		seen[index] = true
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("failed to check chunks: %w", firstErr)
+	}
+
+	return &models.ChunkCheckResponse{
+		BatchID: batchID,
+		Chunks:  results,
+	}, nil
+}
+
+// StatChunk returns storage information about a chunk without reading its data
+func (s *Service) StatChunk(ctx context.Context, batchID string, chunkIndex int) (*storage.ObjectInfo, error) {
+	info, err := s.storage.GetObjectInfo(ctx, s.GetObjectName(ctx, batchID, chunkIndex))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%w: chunk %d of batch %s", ErrChunkNotFound, chunkIndex, batchID)
+		}
+		return nil, fmt.Errorf("failed to get chunk info: %w", err)
+	}
+	return info, nil
+}
+
+// uploadObjectName returns where a presigned upload of a chunk lands. It is
+// kept apart from the chunk until confirmed, so an upload that fails the
+// checks on confirmation never replaces the chunk.
+func (s *Service) uploadObjectName(ctx context.Context, batchID string, chunkIndex int) string {
+	return "presigned/" + s.GetObjectName(ctx, batchID, chunkIndex)
+}
+
+// PresignUploads returns presigned POST forms that let clients upload chunks
+// of at most maxSize bytes straight to storage, bypassing the server. Each
+// upload must be confirmed afterwards.
+func (s *Service) PresignUploads(ctx context.Context, batchID string, indices []int, expiry time.Duration, maxSize int64) (*models.PresignResponse, error) {
+	if len(indices) == 0 || len(indices) > MaxPresignIndices {
+		return nil, fmt.Errorf("between 1 and %d indices are required", MaxPresignIndices)
+	}
+
+	uploads := make(map[int]*models.PresignedUpload, len(indices))
+	for _, index := range indices {
+		if index < 0 || index > MaxChunkIndex {
+			return nil, fmt.Errorf("chunk index must be between 0 and %d: %d", MaxChunkIndex, index)
+		}
+		if _, done := uploads[index]; done {
+			continue
+		}
+
+		post, err := s.storage.PresignedPostObject(ctx, s.uploadObjectName(ctx, batchID, index), expiry, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		uploads[index] = &models.PresignedUpload{URL: post.URL, Fields: post.Fields}
+	}
+
+	s.logger.Printf("Presigned %d chunk uploads for batch %s, valid for %v", len(uploads), batchID, expiry)
+	return &models.PresignResponse{
+		BatchID:   batchID,
+		ExpiresAt: time.Now().Add(expiry),
+		Uploads:   uploads,
+	}, nil
+}
+
+// PresignDownload returns a presigned URL that lets clients fetch a chunk
+// straight from storage. Chunks that storage can't serve as-is, such as
+// compressed ones, yield storage.ErrPresignUnsupported.
+func (s *Service) PresignDownload(ctx context.Context, batchID string, chunkIndex int, expiry time.Duration) (*models.PresignDownloadResponse, error) {
+	if _, err := s.StatChunk(ctx, batchID, chunkIndex); err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("%s_%d", batchID, chunkIndex)
+	url, err := s.storage.PresignedGetObject(ctx, s.GetObjectName(ctx, batchID, chunkIndex), expiry, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PresignDownloadResponse{
+		BatchID:    batchID,
+		ChunkIndex: chunkIndex,
+		URL:        url,
+		ExpiresAt:  time.Now().Add(expiry),
+	}, nil
+}
+
+// PendingUploadSize returns the size of a presigned upload awaiting
+// confirmation, or ErrChunkNotFound if there is none
+func (s *Service) PendingUploadSize(ctx context.Context, batchID string, chunkIndex int) (int64, error) {
+	info, err := s.storage.GetObjectInfo(ctx, s.uploadObjectName(ctx, batchID, chunkIndex))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, fmt.Errorf("%w: chunk %d of batch %s", ErrChunkNotFound, chunkIndex, batchID)
+		}
+		return 0, fmt.Errorf("failed to get upload info: %w", err)
+	}
+	return info.Size, nil
+}
+
+// DiscardUpload deletes a presigned upload once it is moved into place or refused
+func (s *Service) DiscardUpload(ctx context.Context, batchID string, chunkIndex int) {
+	uploadName := s.uploadObjectName(ctx, batchID, chunkIndex)
+	if err := s.storage.DeleteObject(ctx, uploadName); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		s.logger.Printf("Warning: Could not delete presigned upload %s: %v", uploadName, err)
+	}
+}
+
+// ConfirmChunk moves a chunk that a client uploaded directly to storage with
+// a presigned form into place. Without overwrite, an existing chunk is kept
+// and ErrChunkExists returned. Confirming a chunk again reports it as stored;
+// ErrChunkNotFound means nothing was uploaded.
+func (s *Service) ConfirmChunk(ctx context.Context, batchID string, chunkIndex int, tags map[string]string, overwrite bool) (*models.ChunkUploadResponse, error) {
+	objectName := s.GetObjectName(ctx, batchID, chunkIndex)
+	uploadName := s.uploadObjectName(ctx, batchID, chunkIndex)
+
+	pending, err := s.storage.CheckObjectExists(ctx, uploadName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check upload: %w", err)
+	}
+	if pending {
+		if !overwrite {
+			exists, err := s.storage.CheckObjectExists(ctx, objectName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check chunk: %w", err)
+			}
+			if exists {
+				s.DiscardUpload(ctx, batchID, chunkIndex)
+				return nil, ErrChunkExists
+			}
+		}
+
+		if err := s.storage.CopyObject(ctx, uploadName, objectName); err != nil {
+			return nil, fmt.Errorf("failed to store chunk: %w", err)
+		}
+		s.DiscardUpload(ctx, batchID, chunkIndex)
+	}
+
+	info, err := s.StatChunk(ctx, batchID, chunkIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Presigned uploads can't carry the batch's tags, so apply them now
+	if pending && len(tags) > 0 {
+		if err := s.storage.SetObjectTags(ctx, s.GetObjectName(ctx, batchID, chunkIndex), tags); err != nil {
+			s.logger.Printf("Warning: Could not tag chunk %d of batch %s: %v", chunkIndex, batchID, err)
+		}
+	}
+
+	s.logger.Printf("Confirmed direct upload of chunk %d for batch %s, size: %d bytes", chunkIndex, batchID, info.Size)
+	return &models.ChunkUploadResponse{
+		Success:    true,
+		BatchID:    batchID,
+		ChunkIndex: chunkIndex,
+		Size:       info.Size,
+		ETag:       info.ETag,
+		SHA256:     info.SHA256,
 		Uploaded:   info.LastModified.Format(time.RFC3339),
 	}, nil
 }
 
 // DownloadChunk downloads a chunk from storage
 func (s *Service) DownloadChunk(ctx context.Context, batchID string, chunkIndex int) (io.ReadCloser, *storage.ObjectInfo, error) {
-	// Calculate object name based on batch ID and chunk index
-	objectName := fmt.Sprintf("%s/%d", batchID, chunkIndex)
+	// Calculate object name based on tenant, batch ID and chunk index
+	objectName := s.GetObjectName(ctx, batchID, chunkIndex)
 	
 	// Log download request
 	s.logger.Printf("Download request for chunk %d of batch %s", chunkIndex, batchID)
 	
-	// Check if object exists
-	exists, err := s.storage.CheckObjectExists(ctx, objectName)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to check file existence: %w", err)
-	}
-	
-	if !exists {
-		return nil, nil, fmt.Errorf("chunk %d not found for batch %s", chunkIndex, batchID)
-	}
-	
-	// Get object info for size reporting
-	info, err := s.storage.GetObjectInfo(ctx, objectName)
+	// A single stat both proves the chunk exists and sizes the response
+	info, err := s.StatChunk(ctx, batchID, chunkIndex)
 	if err != nil {
-		s.logger.Printf("Warning: Could not get info for chunk %d of batch %s: %v", chunkIndex, batchID, err)
-	} else {
-		s.logger.Printf("Serving chunk %d from batch %s, size: %d bytes", chunkIndex, batchID, info.Size)
+		return nil, nil, err
 	}
+	s.logger.Printf("Serving chunk %d from batch %s, size: %d bytes", chunkIndex, batchID, info.Size)
 	
 	// Get object from storage
 	startTime := time.Now()
 	objectReader, err := s.storage.DownloadObject(ctx, objectName)
 	if err != nil {
+		// The chunk may have been deleted since it was stat'ed
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: chunk %d of batch %s", ErrChunkNotFound, chunkIndex, batchID)
+		}
 		return nil, nil, fmt.Errorf("failed to retrieve file: %w", err)
 	}
 	
 	// Log successful download
 	downloadDuration := time.Since(startTime)
-	if info != nil {
-		s.logger.Printf("Successfully started download of chunk %d from batch %s, size: %d bytes, setup took: %v", 
-			chunkIndex, batchID, info.Size, downloadDuration)
-	} else {
-		s.logger.Printf("Successfully started download of chunk %d from batch %s, setup took: %v", 
-			chunkIndex, batchID, downloadDuration)
-	}
+	observeDuration(downloadSetupSeconds, info.Size, downloadDuration)
+	s.logger.Printf("Successfully started download of chunk %d from batch %s, size: %d bytes, setup took: %v", 
+		chunkIndex, batchID, info.Size, downloadDuration)
 	
 	return objectReader, info, nil
 }
 
-// GetObjectName returns the storage object name for a chunk
-func (s *Service) GetObjectName(batchID string, chunkIndex int) string {
-	return fmt.Sprintf("%s/%d", batchID, chunkIndex)
+// GetObjectName returns the storage object name for a chunk, namespaced by
+// the tenant the request is scoped to
+func (s *Service) GetObjectName(ctx context.Context, batchID string, chunkIndex int) string {
+	return fmt.Sprintf("%s/%s/%d", tenant.FromContext(ctx), batchID, chunkIndex)
 }
 
 // ParseChunkIndex parses a chunk index from string
@@ -180,6 +462,9 @@ func (s *Service) ParseChunkIndex(chunkIndexStr string) (int, error) {
 	if chunkIndex < 0 {
 		return 0, fmt.Errorf("chunk index cannot be negative: %d", chunkIndex)
 	}
+	if chunkIndex > MaxChunkIndex {
+		return 0, fmt.Errorf("chunk index cannot exceed %d: %d", MaxChunkIndex, chunkIndex)
+	}
 	
 	return chunkIndex, nil
 } 
\ No newline at end of file
@@ -0,0 +1,32 @@
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// precomputeDigest hashes a chunk before it is uploaded, so the digest can be
+// stored with the object rather than rewriting the object afterwards. Only
+// seekable readers, such as multipart form files, can be read ahead and
+// rewound; streamed chunks must come with their digest, or ErrDigestRequired
+// is returned without reading anything.
+func precomputeDigest(reader io.Reader) (string, error) {
+	seeker, ok := reader.(io.ReadSeeker)
+	if !ok {
+		return "", ErrDigestRequired
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, seeker); err != nil {
+		return "", err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
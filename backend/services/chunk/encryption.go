@@ -0,0 +1,86 @@
+package chunk
+
+import (
+	"errors"
+	"filesh/models"
+	"fmt"
+	"regexp"
+)
+
+// User-metadata keys holding a chunk's client-side encryption parameters
+const (
+	metadataEncryptionAlgo = "Encryption-Algo"
+	metadataEncryptionIV   = "Encryption-Iv"
+	metadataWrappedKey     = "Encryption-Wrapped-Key"
+)
+
+// Encryption metadata limits; storage caps user metadata at 2KB per object
+const (
+	maxEncryptionAlgoLength = 64
+	maxEncryptionIVLength   = 256
+	maxWrappedKeyLength     = 1024
+)
+
+// ErrInvalidEncryption is returned when encryption metadata fails validation
+var ErrInvalidEncryption = errors.New("invalid encryption metadata")
+
+var (
+	// algoPattern matches algorithm names such as "AES-256-GCM"
+	algoPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	// encodedPattern matches hex, base64 and base64url values
+	encodedPattern = regexp.MustCompile(`^[A-Za-z0-9+/_=-]+$`)
+)
+
+// ValidateEncryption checks client-supplied encryption metadata. The values
+// are opaque to the server; only their shape and size are checked so they
+// fit in object metadata.
+func ValidateEncryption(enc *models.EncryptionInfo) error {
+	if enc.Algorithm == "" {
+		if enc.IV != "" || enc.WrappedKey != "" {
+			return fmt.Errorf("%w: an algorithm is required", ErrInvalidEncryption)
+		}
+		return nil
+	}
+	if len(enc.Algorithm) > maxEncryptionAlgoLength || !algoPattern.MatchString(enc.Algorithm) {
+		return fmt.Errorf("%w: algorithm must be at most %d letters, digits, '.', '_' or '-'", ErrInvalidEncryption, maxEncryptionAlgoLength)
+	}
+	if len(enc.IV) > maxEncryptionIVLength || (enc.IV != "" && !encodedPattern.MatchString(enc.IV)) {
+		return fmt.Errorf("%w: IV must be hex or base64 of at most %d characters", ErrInvalidEncryption, maxEncryptionIVLength)
+	}
+	if len(enc.WrappedKey) > maxWrappedKeyLength || (enc.WrappedKey != "" && !encodedPattern.MatchString(enc.WrappedKey)) {
+		return fmt.Errorf("%w: wrapped key must be hex or base64 of at most %d characters", ErrInvalidEncryption, maxWrappedKeyLength)
+	}
+	return nil
+}
+
+// encryptionMetadata adds encryption parameters to object user metadata
+func encryptionMetadata(metadata map[string]string, enc *models.EncryptionInfo) map[string]string {
+	if enc == nil || enc.Algorithm == "" {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]string, 3)
+	}
+	metadata[metadataEncryptionAlgo] = enc.Algorithm
+	if enc.IV != "" {
+		metadata[metadataEncryptionIV] = enc.IV
+	}
+	if enc.WrappedKey != "" {
+		metadata[metadataWrappedKey] = enc.WrappedKey
+	}
+	return metadata
+}
+
+// EncryptionFromMetadata returns the encryption parameters recorded on a
+// chunk, or nil for chunks uploaded without any
+func EncryptionFromMetadata(metadata map[string]string) *models.EncryptionInfo {
+	algo := metadata[metadataEncryptionAlgo]
+	if algo == "" {
+		return nil
+	}
+	return &models.EncryptionInfo{
+		Algorithm:  algo,
+		IV:         metadata[metadataEncryptionIV],
+		WrappedKey: metadata[metadataWrappedKey],
+	}
+}
@@ -0,0 +1,51 @@
+package chunk
+
+import (
+	"expvar"
+	"filesh/utils"
+	"math"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the latency histograms
+var durationBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// sizeClasses split the latency histograms by chunk size, since a large
+// chunk legitimately takes longer to store than a small one
+var sizeClasses = []struct {
+	label string
+	limit int64
+}{
+	{"le_1MiB", 1 << 20},
+	{"le_16MiB", 16 << 20},
+	{"le_128MiB", 128 << 20},
+	{"gt_128MiB", math.MaxInt64},
+}
+
+// uploadSeconds and downloadSetupSeconds hold a latency histogram per size
+// class: the time to store a chunk, and the time storage takes to start
+// returning one. They are published with the other expvar metrics on the
+// debug listener.
+var (
+	uploadSeconds        = newSizeHistograms("chunk_upload_seconds")
+	downloadSetupSeconds = newSizeHistograms("chunk_download_setup_seconds")
+)
+
+// newSizeHistograms publishes a map with a histogram for every size class
+func newSizeHistograms(name string) *expvar.Map {
+	histograms := expvar.NewMap(name)
+	for _, class := range sizeClasses {
+		histograms.Set(class.label, utils.NewHistogram(durationBuckets...))
+	}
+	return histograms
+}
+
+// observeDuration records how long an operation on a chunk of the given size took
+func observeDuration(histograms *expvar.Map, size int64, d time.Duration) {
+	for _, class := range sizeClasses {
+		if size <= class.limit {
+			histograms.Get(class.label).(*utils.Histogram).Observe(d.Seconds())
+			return
+		}
+	}
+}
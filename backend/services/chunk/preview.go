@@ -0,0 +1,85 @@
+package chunk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+const (
+	// MaxPreviewImageSize is the largest image chunk served as a preview
+	MaxPreviewImageSize = 5 * 1024 * 1024
+	// PreviewTextBytes is how much of a text chunk a preview returns
+	PreviewTextBytes = 64 * 1024
+)
+
+// previewTextType is the type every text preview is served as, whatever the
+// uploader declared, so markup and scripts are shown rather than run
+const previewTextType = "text/plain; charset=utf-8"
+
+// previewImageTypes are the raster image types served as previews. Types that
+// can carry scripts, such as SVG, are left out.
+var previewImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ErrPreviewUnsupported is returned for chunks whose type or size can't be previewed
+var ErrPreviewUnsupported = errors.New("chunk cannot be previewed")
+
+// Preview is the renderable start of a chunk
+type Preview struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+	// Truncated is set when the preview holds only the start of a text chunk
+	Truncated bool
+}
+
+// PreviewChunk returns a chunk for inline display, based on the content type
+// recorded at upload. Text is cut to PreviewTextBytes and always served as
+// plain text; raster images are returned whole up to MaxPreviewImageSize.
+// Anything else yields ErrPreviewUnsupported.
+func (s *Service) PreviewChunk(ctx context.Context, batchID string, chunkIndex int) (*Preview, error) {
+	info, err := s.StatChunk(ctx, batchID, chunkIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(info.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown content type", ErrPreviewUnsupported)
+	}
+
+	preview := &Preview{ContentType: mediaType, Size: info.Size}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		preview.ContentType = previewTextType
+		if preview.Size > PreviewTextBytes {
+			preview.Size = PreviewTextBytes
+			preview.Truncated = true
+		}
+	case previewImageTypes[mediaType]:
+		if info.Size > MaxPreviewImageSize {
+			return nil, fmt.Errorf("%w: image exceeds %d bytes", ErrPreviewUnsupported, MaxPreviewImageSize)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrPreviewUnsupported, mediaType)
+	}
+
+	reader, err := s.storage.DownloadObject(ctx, s.GetObjectName(ctx, batchID, chunkIndex))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chunk: %w", err)
+	}
+	preview.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(reader, preview.Size), reader}
+
+	return preview, nil
+}
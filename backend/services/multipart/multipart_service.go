@@ -0,0 +1,223 @@
+package multipart
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"filesh/models"
+	"filesh/services/storage"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MinPartNumber and MaxPartNumber bound part numbers as defined by the S3 API
+	MinPartNumber = 1
+	MaxPartNumber = 10000
+)
+
+var (
+	// ErrSessionNotFound is returned when an upload ID has no known session
+	ErrSessionNotFound = errors.New("multipart upload not found")
+	// ErrFileTooLarge is returned when the assembled parts exceed the size limit
+	ErrFileTooLarge = errors.New("file too large")
+)
+
+// Service handles multipart upload sessions for single large files
+type Service struct {
+	storage storage.ObjectStorage
+	logger  *log.Logger
+}
+
+// NewService creates a new multipart upload service
+func NewService(storage storage.ObjectStorage, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[MULTIPART] ", log.LstdFlags)
+	}
+
+	return &Service{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// sessionObjectName returns the storage object name of a session record
+func sessionObjectName(uploadID string) string {
+	return fmt.Sprintf("multipart/%s.json", uploadID)
+}
+
+// CreateUpload starts a new multipart upload for a file
+func (s *Service) CreateUpload(ctx context.Context, filename string) (*models.MultipartUploadResponse, error) {
+	// Place the final object where direct file downloads will find it
+	fileID := uuid.New().String()
+	objectName := fmt.Sprintf("files/%s%s", fileID, filepath.Ext(filename))
+
+	uploadID, err := s.storage.NewMultipartUpload(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Persist the session so parts can be uploaded from any server instance
+	session := &models.MultipartSession{
+		UploadID:   uploadID,
+		FileID:     fileID,
+		Filename:   filename,
+		ObjectName: objectName,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.saveSession(ctx, sessionObjectName(uploadID), session); err != nil {
+		// Don't leave an orphaned upload behind
+		if abortErr := s.storage.AbortMultipartUpload(ctx, objectName, uploadID); abortErr != nil {
+			s.logger.Printf("Warning: Could not abort multipart upload %s: %v", uploadID, abortErr)
+		}
+		return nil, err
+	}
+
+	s.logger.Printf("Created multipart upload %s for file %s", uploadID, fileID)
+	return &models.MultipartUploadResponse{
+		UploadID: uploadID,
+		FileID:   fileID,
+		Filename: filename,
+	}, nil
+}
+
+// UploadPart uploads a single part of a multipart upload
+func (s *Service) UploadPart(ctx context.Context, uploadID string, partNumber int, reader io.Reader, size int64) (*models.MultipartPart, error) {
+	if partNumber < MinPartNumber || partNumber > MaxPartNumber {
+		return nil, fmt.Errorf("part number must be between %d and %d", MinPartNumber, MaxPartNumber)
+	}
+
+	session, err := s.loadSession(ctx, sessionObjectName(uploadID))
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := s.storage.PutObjectPart(ctx, session.ObjectName, uploadID, partNumber, reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("Uploaded part %d of multipart upload %s, size: %d bytes", partNumber, uploadID, part.Size)
+	return &models.MultipartPart{
+		PartNumber: part.PartNumber,
+		ETag:       part.ETag,
+		Size:       part.Size,
+	}, nil
+}
+
+// CompleteUpload assembles the collected parts into the final file. A file
+// larger than maxSize is deleted again and reported as ErrFileTooLarge; the
+// size is the one storage reports, not the part sizes the client claims.
+func (s *Service) CompleteUpload(ctx context.Context, uploadID string, parts []models.MultipartPart, maxSize int64) (*models.MultipartCompleteResponse, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("at least one part is required")
+	}
+
+	session, err := s.loadSession(ctx, sessionObjectName(uploadID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Storage requires parts in ascending order
+	storageParts := make([]storage.PartInfo, 0, len(parts))
+	for _, part := range parts {
+		storageParts = append(storageParts, storage.PartInfo{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		})
+	}
+	sort.Slice(storageParts, func(i, j int) bool {
+		return storageParts[i].PartNumber < storageParts[j].PartNumber
+	})
+
+	info, err := s.storage.CompleteMultipartUpload(ctx, session.ObjectName, uploadID, storageParts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.deleteSession(ctx, sessionObjectName(uploadID))
+
+	if info.Size > maxSize {
+		if err := s.storage.DeleteObject(ctx, session.ObjectName); err != nil {
+			s.logger.Printf("Warning: Could not delete oversized file %s: %v", session.ObjectName, err)
+		}
+		s.logger.Printf("Rejected multipart upload %s: %d bytes exceeds the %d byte limit", uploadID, info.Size, maxSize)
+		return nil, ErrFileTooLarge
+	}
+
+	return &models.MultipartCompleteResponse{
+		FileID:       session.FileID,
+		Filename:     session.Filename,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		DownloadPath: fmt.Sprintf("/api/file/%s", session.FileID),
+	}, nil
+}
+
+// AbortUpload cancels a multipart upload and discards its parts
+func (s *Service) AbortUpload(ctx context.Context, uploadID string) error {
+	session, err := s.loadSession(ctx, sessionObjectName(uploadID))
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.AbortMultipartUpload(ctx, session.ObjectName, uploadID); err != nil {
+		return err
+	}
+
+	s.deleteSession(ctx, sessionObjectName(uploadID))
+	return nil
+}
+
+// saveSession persists a session record
+func (s *Service) saveSession(ctx context.Context, objectName string, session *models.MultipartSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode multipart session: %w", err)
+	}
+
+	_, err = s.storage.UploadObject(ctx, objectName, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to store multipart session: %w", err)
+	}
+
+	return nil
+}
+
+// loadSession reads a session record, returning ErrSessionNotFound for unknown uploads
+func (s *Service) loadSession(ctx context.Context, objectName string) (*models.MultipartSession, error) {
+	exists, err := s.storage.CheckObjectExists(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check multipart session: %w", err)
+	}
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	reader, err := s.storage.DownloadObject(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart session: %w", err)
+	}
+	defer reader.Close()
+
+	var session models.MultipartSession
+	if err := json.NewDecoder(reader).Decode(&session); err != nil {
+		return nil, fmt.Errorf("failed to decode multipart session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// deleteSession removes a finished session record
+func (s *Service) deleteSession(ctx context.Context, objectName string) {
+	if err := s.storage.DeleteObject(ctx, objectName); err != nil {
+		s.logger.Printf("Warning: Could not delete multipart session %s: %v", objectName, err)
+	}
+}
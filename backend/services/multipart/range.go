@@ -0,0 +1,173 @@
+package multipart
+
+import (
+	"context"
+	"errors"
+	"filesh/models"
+	"filesh/services/storage"
+	"filesh/utils"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MinRangeSize is the smallest piece accepted before the final one, as
+// storage requires every part but the last to be at least 5MB
+const MinRangeSize = 5 * 1024 * 1024
+
+var (
+	// ErrInvalidRange is returned for a piece that can't be stored as a part
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrRangeMismatch is returned for a piece that doesn't start where the
+	// upload left off; the returned status tells the client where that is
+	ErrRangeMismatch = errors.New("range does not continue the upload")
+	// ErrFileExists is returned when a new range upload names a file ID in use
+	ErrFileExists = errors.New("file already exists")
+)
+
+// rangeSessionObjectName returns the storage object name of the session of a
+// file uploaded in Content-Range pieces
+func rangeSessionObjectName(fileID string) string {
+	return fmt.Sprintf("multipart/files/%s.json", fileID)
+}
+
+// UploadRange stores one piece of a file sent with Content-Range. The first
+// piece must start at zero and starts a session for the client-chosen file
+// ID; later ones must continue exactly where the upload left off. Each piece
+// becomes a storage part, and the file is assembled once the last byte
+// arrives. The returned status reports progress even with ErrRangeMismatch.
+func (s *Service) UploadRange(ctx context.Context, fileID, filename string, byteRange *utils.ByteRange, total int64, reader io.Reader) (*models.RangeUploadStatus, error) {
+	if _, err := uuid.Parse(fileID); err != nil {
+		return nil, fmt.Errorf("%w: file ID must be a UUID", ErrInvalidRange)
+	}
+
+	objectName := rangeSessionObjectName(fileID)
+	session, err := s.loadSession(ctx, objectName)
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		if byteRange.Start != 0 {
+			return nil, err
+		}
+		if session, err = s.startRangeSession(ctx, fileID, filename, total); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case session.Total != total:
+		return nil, fmt.Errorf("%w: total length %d differs from the %d the upload started with", ErrInvalidRange, total, session.Total)
+	case byteRange.Start != session.Received:
+		return rangeStatus(session), ErrRangeMismatch
+	}
+
+	end := byteRange.Start + byteRange.Length
+	if end < total && byteRange.Length < MinRangeSize {
+		return rangeStatus(session), fmt.Errorf("%w: pieces before the last must be at least %d bytes", ErrInvalidRange, MinRangeSize)
+	}
+	partNumber := len(session.Parts) + 1
+	if partNumber > MaxPartNumber {
+		return rangeStatus(session), fmt.Errorf("%w: a file may be sent in at most %d pieces", ErrInvalidRange, MaxPartNumber)
+	}
+
+	part, err := s.storage.PutObjectPart(ctx, session.ObjectName, session.UploadID, partNumber, reader, byteRange.Length)
+	if err != nil {
+		return nil, err
+	}
+	session.Parts = append(session.Parts, models.MultipartPart{
+		PartNumber: part.PartNumber,
+		ETag:       part.ETag,
+		Size:       part.Size,
+	})
+	session.Received = end
+
+	if session.Received < total {
+		if err := s.saveSession(ctx, objectName, session); err != nil {
+			return nil, err
+		}
+		return rangeStatus(session), nil
+	}
+
+	// The last byte arrived, so assemble the file
+	parts := make([]storage.PartInfo, 0, len(session.Parts))
+	for _, p := range session.Parts {
+		parts = append(parts, storage.PartInfo{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+	info, err := s.storage.CompleteMultipartUpload(ctx, session.ObjectName, session.UploadID, parts)
+	if err != nil {
+		return nil, err
+	}
+	s.deleteSession(ctx, objectName)
+
+	s.logger.Printf("Completed range upload of file %s, size: %d bytes in %d pieces", fileID, total, len(parts))
+	status := rangeStatus(session)
+	status.Complete = true
+	status.ETag = info.ETag
+	status.DownloadPath = fmt.Sprintf("/api/file/%s", fileID)
+	return status, nil
+}
+
+// RangeStatus reports how much of a file sent in Content-Range pieces has
+// arrived, returning ErrSessionNotFound when no such upload is in progress
+func (s *Service) RangeStatus(ctx context.Context, fileID string) (*models.RangeUploadStatus, error) {
+	if _, err := uuid.Parse(fileID); err != nil {
+		return nil, ErrSessionNotFound
+	}
+
+	session, err := s.loadSession(ctx, rangeSessionObjectName(fileID))
+	if err != nil {
+		return nil, err
+	}
+	return rangeStatus(session), nil
+}
+
+// startRangeSession begins the storage upload behind a new range upload
+func (s *Service) startRangeSession(ctx context.Context, fileID, filename string, total int64) (*models.MultipartSession, error) {
+	if total <= 0 {
+		return nil, fmt.Errorf("%w: empty files must be uploaded in one request", ErrInvalidRange)
+	}
+
+	// Client-chosen IDs must not take over an existing file
+	existing, err := s.storage.ListObjects(ctx, "files/"+fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check file ID: %w", err)
+	}
+	if len(existing) > 0 {
+		return nil, ErrFileExists
+	}
+
+	objectName := fmt.Sprintf("files/%s%s", fileID, filepath.Ext(filename))
+	uploadID, err := s.storage.NewMultipartUpload(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.MultipartSession{
+		UploadID:   uploadID,
+		FileID:     fileID,
+		Filename:   filename,
+		ObjectName: objectName,
+		CreatedAt:  time.Now(),
+		Total:      total,
+	}
+	if err := s.saveSession(ctx, rangeSessionObjectName(fileID), session); err != nil {
+		if abortErr := s.storage.AbortMultipartUpload(ctx, objectName, uploadID); abortErr != nil {
+			s.logger.Printf("Warning: Could not abort multipart upload %s: %v", uploadID, abortErr)
+		}
+		return nil, err
+	}
+
+	s.logger.Printf("Started range upload of file %s, %d bytes expected", fileID, total)
+	return session, nil
+}
+
+// rangeStatus summarizes a range upload session
+func rangeStatus(session *models.MultipartSession) *models.RangeUploadStatus {
+	return &models.RangeUploadStatus{
+		FileID:   session.FileID,
+		Filename: session.Filename,
+		Received: session.Received,
+		Total:    session.Total,
+	}
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"filesh/models"
+)
+
+// ErrStorageFull is returned once the bytes stored reach the enforced soft cap
+var ErrStorageFull = errors.New("storage soft cap reached")
+
+// Capacity reports the bytes stored against the soft cap. It is computed from
+// the cached listing behind GetStats, so it may lag by up to the cache TTL.
+func (s *Service) Capacity(ctx context.Context) (*models.StorageCapacity, error) {
+	stats, err := s.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := &models.StorageCapacity{
+		TotalBytes:  stats.TotalBytes,
+		Enforced:    s.CapEnforced(),
+		GeneratedAt: stats.GeneratedAt,
+	}
+	if s.softCap > 0 {
+		remaining := max(s.softCap-stats.TotalBytes, 0)
+		capacity.SoftCapBytes = s.softCap
+		capacity.RemainingBytes = &remaining
+		capacity.Exceeded = stats.TotalBytes >= s.softCap
+	}
+	return capacity, nil
+}
+
+// CapEnforced reports whether new batches and uploads are refused once the
+// soft cap is reached
+func (s *Service) CapEnforced() bool {
+	return s.softCap > 0 && s.enforceCap
+}
+
+// CheckCapacity returns ErrStorageFull when the soft cap is enforced and the
+// bytes stored have reached it
+func (s *Service) CheckCapacity(ctx context.Context) error {
+	if !s.CapEnforced() {
+		return nil
+	}
+
+	capacity, err := s.Capacity(ctx)
+	if err != nil {
+		return err
+	}
+	if capacity.Exceeded {
+		return ErrStorageFull
+	}
+	return nil
+}
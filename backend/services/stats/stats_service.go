@@ -0,0 +1,89 @@
+package stats
+
+import (
+	"context"
+	"filesh/models"
+	"filesh/services/batch"
+	"filesh/services/storage"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Service computes bucket-wide statistics
+type Service struct {
+	storage  storage.ObjectStorage
+	cacheTTL time.Duration
+	logger   *log.Logger
+	// softCap is the bucket size at which the instance counts as full; zero
+	// disables it, and enforceCap decides whether a full instance refuses uploads
+	softCap    int64
+	enforceCap bool
+
+	mu       sync.Mutex
+	cached   *models.StorageStats
+	cachedAt time.Time
+}
+
+// NewService creates a new statistics service whose results are cached for
+// cacheTTL, measuring capacity against softCap bytes
+func NewService(storage storage.ObjectStorage, cacheTTL time.Duration, softCap int64, enforceCap bool, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[STATS] ", log.LstdFlags)
+	}
+
+	return &Service{
+		storage:    storage,
+		cacheTTL:   cacheTTL,
+		logger:     logger,
+		softCap:    softCap,
+		enforceCap: enforceCap,
+	}
+}
+
+// GetStats returns bucket statistics, computing them with a single listing
+// pass when the cached result is older than the TTL
+func (s *Service) GetStats(ctx context.Context) (*models.StorageStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.cachedAt) < s.cacheTTL {
+		return s.cached, nil
+	}
+
+	startTime := time.Now()
+	objects, err := s.storage.ListObjects(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	stats := &models.StorageStats{
+		ObjectCount: len(objects),
+	}
+	batches := make(map[string]bool)
+
+	for _, obj := range objects {
+		stats.TotalBytes += obj.Size
+
+		if stats.OldestObject.IsZero() || obj.LastModified.Before(stats.OldestObject) {
+			stats.OldestObject = obj.LastModified
+		}
+		if obj.LastModified.After(stats.NewestObject) {
+			stats.NewestObject = obj.LastModified
+		}
+
+		// Batches are the distinct tenant/batch prefixes outside reserved namespaces
+		if tenantID, batchID, _, ok := batch.ParseObjectName(obj.Name); ok {
+			batches[tenantID+"/"+batchID] = true
+		}
+	}
+
+	stats.BatchCount = len(batches)
+	stats.GeneratedAt = time.Now()
+
+	s.logger.Printf("Computed storage stats over %d objects in %v", stats.ObjectCount, time.Since(startTime))
+	s.cached = stats
+	s.cachedAt = stats.GeneratedAt
+	return stats, nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// CacheStorage remembers recent GetObjectInfo and CheckObjectExists results
+// for a short time, sparing the repeated stat round trips of popular
+// downloads. Writes and deletes through this instance invalidate the name at
+// once; changes made elsewhere, such as presigned uploads or other server
+// instances, are picked up when the entry expires.
+//
+// Only objects that exist are cached, so a chunk uploaded directly to storage
+// is never reported missing because of the cache.
+type CacheStorage struct {
+	ObjectStorage
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry is a cached stat result; info is nil when only existence is known
+type cacheEntry struct {
+	info    *ObjectInfo
+	expires time.Time
+}
+
+// NewCacheStorage wraps a storage backend with a stat cache holding at most
+// maxEntries names for ttl each
+func NewCacheStorage(inner ObjectStorage, ttl time.Duration, maxEntries int) ObjectStorage {
+	return &CacheStorage{
+		ObjectStorage: inner,
+		ttl:           ttl,
+		maxEntries:    maxEntries,
+		entries:       make(map[string]cacheEntry),
+	}
+}
+
+// lookup returns the live cache entry for a name
+func (s *CacheStorage) lookup(objectName string) (cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[objectName]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.entries, objectName)
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// store caches a stat result, making room first when the cache is full
+func (s *CacheStorage) store(objectName string, info *ObjectInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[objectName]; !exists && len(s.entries) >= s.maxEntries {
+		now := time.Now()
+		for name, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, name)
+			}
+		}
+		// Still full: drop an arbitrary entry, map iteration order is random
+		for name := range s.entries {
+			if len(s.entries) < s.maxEntries {
+				break
+			}
+			delete(s.entries, name)
+		}
+	}
+
+	s.entries[objectName] = cacheEntry{info: info, expires: time.Now().Add(s.ttl)}
+}
+
+// invalidate forgets a name after it was written or deleted
+func (s *CacheStorage) invalidate(objectName string) {
+	s.mu.Lock()
+	delete(s.entries, objectName)
+	s.mu.Unlock()
+}
+
+// CheckObjectExists reports whether an object exists, from the cache when possible
+func (s *CacheStorage) CheckObjectExists(ctx context.Context, objectName string) (bool, error) {
+	if _, ok := s.lookup(objectName); ok {
+		return true, nil
+	}
+
+	exists, err := s.ObjectStorage.CheckObjectExists(ctx, objectName)
+	if err == nil && exists {
+		s.store(objectName, nil)
+	}
+	return exists, err
+}
+
+// GetObjectInfo returns information about an object, from the cache when possible
+func (s *CacheStorage) GetObjectInfo(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	if entry, ok := s.lookup(objectName); ok && entry.info != nil {
+		info := *entry.info
+		return &info, nil
+	}
+
+	info, err := s.ObjectStorage.GetObjectInfo(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+	cached := *info
+	s.store(objectName, &cached)
+	return info, nil
+}
+
+// UploadObject uploads an object and forgets its cached state
+func (s *CacheStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error) {
+	defer s.invalidate(objectName)
+	return s.ObjectStorage.UploadObject(ctx, objectName, reader, objectSize)
+}
+
+// UploadObjectWithOptions uploads an object and forgets its cached state
+func (s *CacheStorage) UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error) {
+	defer s.invalidate(objectName)
+	return s.ObjectStorage.UploadObjectWithOptions(ctx, objectName, reader, objectSize, opts)
+}
+
+// SetObjectMetadata updates an object's metadata and forgets its cached state
+func (s *CacheStorage) SetObjectMetadata(ctx context.Context, objectName string, metadata map[string]string) error {
+	defer s.invalidate(objectName)
+	return s.ObjectStorage.SetObjectMetadata(ctx, objectName, metadata)
+}
+
+// DeleteObject deletes an object and forgets its cached state
+func (s *CacheStorage) DeleteObject(ctx context.Context, objectName string) error {
+	defer s.invalidate(objectName)
+	return s.ObjectStorage.DeleteObject(ctx, objectName)
+}
+
+// CopyObject copies an object and forgets the destination's cached state
+func (s *CacheStorage) CopyObject(ctx context.Context, srcName, dstName string) error {
+	defer s.invalidate(dstName)
+	return s.ObjectStorage.CopyObject(ctx, srcName, dstName)
+}
+
+// PresignedPostObject presigns an upload and forgets the object's cached state.
+// The upload itself bypasses the server, so the entry may be stale again
+// until the TTL passes if the object is read before the upload completes.
+func (s *CacheStorage) PresignedPostObject(ctx context.Context, objectName string, expiry time.Duration, maxSize int64) (*PresignedPost, error) {
+	defer s.invalidate(objectName)
+	return s.ObjectStorage.PresignedPostObject(ctx, objectName, expiry, maxSize)
+}
+
+// CompleteMultipartUpload assembles an object and forgets its cached state
+func (s *CacheStorage) CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []PartInfo) (*ObjectInfo, error) {
+	defer s.invalidate(objectName)
+	return s.ObjectStorage.CompleteMultipartUpload(ctx, objectName, uploadID, parts)
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// metadataCompressed, metadataOriginalSize and metadataOriginalSHA256
+	// describe a compressed object
+	metadataCompressed     = "Compressed"
+	metadataOriginalSize   = "Original-Size"
+	metadataOriginalSHA256 = "Original-Sha256"
+
+	// sniffLength is how much data is inspected to decide whether to compress
+	sniffLength = 512
+	// entropyThreshold is the bits-per-byte above which data is treated as
+	// already compressed or encrypted
+	entropyThreshold = 7.0
+)
+
+// incompressibleTypes lists content type prefixes that don't benefit from gzip
+var incompressibleTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp",
+	"video/", "audio/",
+	"application/zip", "application/x-gzip", "application/x-rar-compressed",
+	"application/x-7z-compressed", "application/pdf",
+}
+
+// CompressStorage transparently gzips object data before storing it and
+// decompresses it on download, so clients always see the original bytes.
+//
+// Data that is already compressed or encrypted is detected by sniffing the
+// first bytes of each upload and stored as-is. Listings only know the stored
+// size, so ListObjects reports that rather than stat every object; callers that
+// need original sizes resolve them with ResolveSizes.
+//
+// The digest of the original data is kept apart from MetadataSHA256, which
+// wrappers below, such as deduplication, take to describe the stored bytes.
+type CompressStorage struct {
+	ObjectStorage
+	logger *log.Logger
+}
+
+// NewCompressStorage wraps a storage backend with transparent gzip compression
+func NewCompressStorage(inner ObjectStorage, logger *log.Logger) ObjectStorage {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[COMPRESS] ", log.LstdFlags)
+	}
+
+	return &CompressStorage{
+		ObjectStorage: inner,
+		logger:        logger,
+	}
+}
+
+// UploadObject uploads an object, compressing it when worthwhile
+func (s *CompressStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error) {
+	return s.UploadObjectWithOptions(ctx, objectName, reader, objectSize, UploadOptions{})
+}
+
+// UploadObjectWithOptions uploads an object, compressing it when worthwhile.
+// The returned size is the original, uncompressed one.
+func (s *CompressStorage) UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error) {
+	// Peek at the start of the data without consuming it
+	bufReader := bufio.NewReaderSize(reader, sniffLength)
+	sample, err := bufReader.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	if !shouldCompress(opts.ContentType, sample) {
+		// Pass a seekable reader on rewound rather than buffered, so storage
+		// can still rewind it to retry
+		if seeker, ok := reader.(io.ReadSeeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("failed to rewind upload: %w", err)
+			}
+			return s.ObjectStorage.UploadObjectWithOptions(ctx, objectName, seeker, objectSize, opts)
+		}
+		return s.ObjectStorage.UploadObjectWithOptions(ctx, objectName, bufReader, objectSize, opts)
+	}
+
+	// Record how to restore the original data
+	metadata := make(map[string]string, len(opts.Metadata)+2)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[metadataCompressed] = "gzip"
+	metadata[metadataOriginalSize] = strconv.FormatInt(objectSize, 10)
+	if digest := metadata[MetadataSHA256]; digest != "" {
+		metadata[metadataOriginalSHA256] = digest
+		delete(metadata, MetadataSHA256)
+	}
+
+	// Compress on the fly; the compressed size isn't known up front, and the
+	// pipe can't be rewound, so storage can't retry a compressed upload
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		gzipWriter := gzip.NewWriter(pipeWriter)
+		_, err := io.Copy(gzipWriter, bufReader)
+		if err == nil {
+			err = gzipWriter.Close()
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+
+	info, err := s.ObjectStorage.UploadObjectWithOptions(ctx, objectName, pipeReader, -1, UploadOptions{
+		ContentType:  opts.ContentType,
+		Metadata:     metadata,
+		Tags:         opts.Tags,
+		StorageClass: opts.StorageClass,
+		Retain:       opts.Retain,
+	})
+	// Unblock the compressor if storage stopped reading early
+	pipeReader.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return nil, err
+	}
+
+	info.compressed = "gzip"
+	info.originalSize = objectSize
+	info.Size = objectSize
+	info.SHA256 = metadata[metadataOriginalSHA256]
+	return info, nil
+}
+
+// DownloadObject downloads an object, decompressing it if needed
+func (s *CompressStorage) DownloadObject(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	info, err := s.ObjectStorage.GetObjectInfo(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+
+	reader, err := s.ObjectStorage.DownloadObject(ctx, objectName)
+	if err != nil || info.compressed == "" {
+		return reader, err
+	}
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to decompress object: %w", err)
+	}
+
+	return &gzipReadCloser{Reader: gzipReader, source: reader}, nil
+}
+
+// PresignedGetObject presigns a download of an object stored uncompressed.
+// Compressed objects must go through DownloadObject and yield ErrPresignUnsupported.
+func (s *CompressStorage) PresignedGetObject(ctx context.Context, objectName string, expiry time.Duration, filename string) (string, error) {
+	info, err := s.ObjectStorage.GetObjectInfo(ctx, objectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign download: %w", err)
+	}
+	if info.compressed != "" {
+		return "", ErrPresignUnsupported
+	}
+
+	return s.ObjectStorage.PresignedGetObject(ctx, objectName, expiry, filename)
+}
+
+// GetObjectInfo gets information about an object, reporting its original
+// size and digest
+func (s *CompressStorage) GetObjectInfo(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	info, err := s.ObjectStorage.GetObjectInfo(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.compressed != "" {
+		info.Size = info.originalSize
+		info.SHA256 = info.UserMetadata[metadataOriginalSHA256]
+	}
+	return info, nil
+}
+
+// ListObjects lists objects with the given prefix. Sizes are the stored ones
+// and are marked as such, for ResolveSizes to correct where it matters.
+func (s *CompressStorage) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects, err := s.ObjectStorage.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range objects {
+		objects[i].storedSize = true
+	}
+	return objects, nil
+}
+
+// gzipReadCloser closes both the decompressor and the underlying object
+type gzipReadCloser struct {
+	*gzip.Reader
+	source io.ReadCloser
+}
+
+// Close closes the decompressor and the underlying object
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.source.Close()
+}
+
+// shouldCompress decides whether data is worth compressing from its content
+// type and a sample of its first bytes
+func shouldCompress(contentType string, sample []byte) bool {
+	if len(sample) == 0 {
+		return false
+	}
+
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(sample)
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+
+	// Encrypted and compressed data look random, so skip high-entropy samples
+	return entropy(sample) < entropyThreshold
+}
+
+// entropy returns the Shannon entropy of data in bits per byte
+func entropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	total := float64(len(data))
+	var bits float64
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / total
+		bits -= p * math.Log2(p)
+	}
+
+	return bits
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestCompressOverDedupKeepsDigestsApart uploads the same bytes once stored
+// raw and once compressed. Dedup must not link the compressed pointer to the
+// raw blob just because the client sent the same digest for both.
+func TestCompressOverDedupKeepsDigestsApart(t *testing.T) {
+	ctx := context.Background()
+	store := NewCompressStorage(NewDedupStorage(NewMemoryStorage(), nil), nil)
+
+	data := []byte(strings.Repeat("the same compressible bytes ", 200))
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	for name, contentType := range map[string]string{"raw": "image/png", "compressed": "text/plain"} {
+		_, err := store.UploadObjectWithOptions(ctx, name, bytes.NewReader(data), int64(len(data)), UploadOptions{
+			ContentType: contentType,
+			Metadata:    map[string]string{MetadataSHA256: digest},
+		})
+		if err != nil {
+			t.Fatalf("upload %s: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"raw", "compressed"} {
+		reader, err := store.DownloadObject(ctx, name)
+		if err != nil {
+			t.Fatalf("download %s: %v", name, err)
+		}
+		got, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: downloaded %d bytes that differ from the %d uploaded", name, len(got), len(data))
+		}
+
+		info, err := store.GetObjectInfo(ctx, name)
+		if err != nil {
+			t.Fatalf("stat %s: %v", name, err)
+		}
+		if info.Size != int64(len(data)) || info.SHA256 != digest {
+			t.Errorf("%s: got size %d and digest %q, want %d and %q", name, info.Size, info.SHA256, len(data), digest)
+		}
+	}
+}
+
+// TestResolveSizesReportsOriginalSizes checks that listings through the
+// compression wrapper can be corrected to the original sizes
+func TestResolveSizesReportsOriginalSizes(t *testing.T) {
+	ctx := context.Background()
+	store := NewCompressStorage(NewMemoryStorage(), nil)
+
+	data := []byte(strings.Repeat("a", 4096))
+	if _, err := store.UploadObjectWithOptions(ctx, "text", bytes.NewReader(data), int64(len(data)), UploadOptions{ContentType: "text/plain"}); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	objects, err := store.ListObjects(ctx, "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Size >= int64(len(data)) {
+		t.Fatalf("expected one object listed at its compressed size, got %+v", objects)
+	}
+
+	if err := ResolveSizes(ctx, store, objects, 4); err != nil {
+		t.Fatalf("resolve sizes: %v", err)
+	}
+	if objects[0].Size != int64(len(data)) {
+		t.Errorf("got size %d, want %d", objects[0].Size, len(data))
+	}
+}
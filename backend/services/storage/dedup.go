@@ -0,0 +1,397 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"filesh/utils"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// dedupPrefix is the namespace holding content-addressed blobs
+	dedupPrefix = "sha256/"
+	// dedupStagingPrefix holds uploads while they are hashed
+	dedupStagingPrefix = dedupPrefix + "staging/"
+	// metadataDedupBlob and metadataDedupSize mark an object as a pointer to a blob
+	metadataDedupBlob = "Dedup-Blob"
+	metadataDedupSize = "Dedup-Size"
+)
+
+// DedupStorage stores object data once per distinct SHA-256 digest.
+//
+// Every logical object becomes an empty pointer object whose metadata names
+// the blob at "sha256/<digest>" holding the actual bytes. A reference count
+// stored next to each blob tracks how many pointers use it, so the blob is
+// only removed once the last pointer is deleted. Blobs are kept out of the
+// bucket lifecycle, which would expire a blob by its own age while pointers
+// written since still refer to it. Reference counting is serialized within
+// this process only, so concurrent instances sharing a bucket may
+// occasionally leave an unreferenced blob behind.
+//
+// Under object lock, retention goes to the pointers. Blobs are shared and
+// refcounted, so they stay unretained, but can't be released while any
+// retained pointer still refers to them.
+type DedupStorage struct {
+	ObjectStorage
+	logger *log.Logger
+	mu     sync.Mutex
+}
+
+// NewDedupStorage wraps a storage backend with content-addressable deduplication
+func NewDedupStorage(inner ObjectStorage, logger *log.Logger) ObjectStorage {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[DEDUP] ", log.LstdFlags)
+	}
+
+	return &DedupStorage{
+		ObjectStorage: inner,
+		logger:        logger,
+	}
+}
+
+// blobName returns the object name of the blob for a digest
+func blobName(digest string) string {
+	return dedupPrefix + digest
+}
+
+// refsName returns the object name of the reference count for a digest
+func refsName(digest string) string {
+	return dedupPrefix + digest + ".refs"
+}
+
+// UploadObject uploads an object, storing its data only if the content is new
+func (s *DedupStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error) {
+	return s.UploadObjectWithOptions(ctx, objectName, reader, objectSize, UploadOptions{})
+}
+
+// UploadObjectWithOptions uploads an object, storing its data only if the
+// content is new. Like GetObjectInfo, it reports the blob's size and ETag.
+func (s *DedupStorage) UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error) {
+	// Replacing a pointer releases the blob it referenced, once the new
+	// pointer is in place so a failed upload leaves the old one intact
+	previous := s.pointerBlob(ctx, objectName)
+
+	digest := opts.Metadata[MetadataSHA256]
+
+	// When the digest is known up front and the blob exists, skip the upload entirely
+	claimed := false
+	if digest != "" {
+		var err error
+		claimed, err = s.claim(ctx, digest, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if claimed {
+		// Consume the data so callers hashing the stream still see every byte
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			s.release(ctx, digest)
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		s.logger.Printf("Deduplicated %s against existing blob %s", objectName, digest)
+	} else {
+		var err error
+		digest, err = s.storeBlob(ctx, reader, objectSize, opts.ContentType, opts.StorageClass)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Data of unknown length, such as a compressed stream, is as long as its blob
+	if objectSize < 0 {
+		blobInfo, err := s.ObjectStorage.GetObjectInfo(ctx, blobName(digest))
+		if err != nil {
+			s.addRef(ctx, digest, -1)
+			return nil, err
+		}
+		objectSize = blobInfo.Size
+	}
+
+	// Write the pointer carrying the caller's metadata
+	metadata := make(map[string]string, len(opts.Metadata)+2)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[metadataDedupBlob] = digest
+	metadata[metadataDedupSize] = strconv.FormatInt(objectSize, 10)
+
+	info, err := s.ObjectStorage.UploadObjectWithOptions(ctx, objectName, bytes.NewReader(nil), 0, UploadOptions{
+		ContentType:  opts.ContentType,
+		Metadata:     metadata,
+		Tags:         opts.Tags,
+		StorageClass: opts.StorageClass,
+		Retain:       opts.Retain,
+	})
+	if err != nil {
+		s.addRef(ctx, digest, -1)
+		return nil, err
+	}
+	s.release(ctx, previous)
+
+	// The pointer is empty, so its own ETag says nothing about the content
+	info.dedupBlob = digest
+	info.dedupSize = objectSize
+	info.Size = objectSize
+	info.SHA256 = digest
+	if blobInfo, err := s.ObjectStorage.GetObjectInfo(ctx, blobName(digest)); err == nil {
+		info.Size = blobInfo.Size
+		info.ETag = blobInfo.ETag
+	} else {
+		s.logger.Printf("Warning: Could not get info for blob %s: %v", digest, err)
+	}
+	return info, nil
+}
+
+// storeBlob uploads data to a staging object while hashing it, then moves it
+// to its content address unless an identical blob already exists, and adds a
+// reference to the blob. A blob keeps the storage class of the upload that
+// first stored it.
+func (s *DedupStorage) storeBlob(ctx context.Context, reader io.Reader, objectSize int64, contentType, storageClass string) (string, error) {
+	stagingName := dedupStagingPrefix + uuid.New().String()
+
+	hasher := sha256.New()
+	_, err := s.ObjectStorage.UploadObjectWithOptions(ctx, stagingName, utils.NewHashingReader(reader, hasher), objectSize, UploadOptions{
+		ContentType:  contentType,
+		StorageClass: storageClass,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := s.ObjectStorage.DeleteObject(ctx, stagingName); err != nil {
+			s.logger.Printf("Warning: Could not delete staging object %s: %v", stagingName, err)
+		}
+	}()
+
+	digest := hex.EncodeToString(hasher.Sum(nil))
+	if _, err := s.claim(ctx, digest, stagingName); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// resolve returns the blob digest a pointer refers to, or "" for plain objects
+func (s *DedupStorage) resolve(ctx context.Context, objectName string) (string, *ObjectInfo, error) {
+	info, err := s.ObjectStorage.GetObjectInfo(ctx, objectName)
+	if err != nil {
+		return "", nil, err
+	}
+	return info.dedupBlob, info, nil
+}
+
+// DownloadObject downloads the blob behind a pointer
+func (s *DedupStorage) DownloadObject(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	digest, _, err := s.resolve(ctx, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+	if digest == "" {
+		return s.ObjectStorage.DownloadObject(ctx, objectName)
+	}
+
+	return s.ObjectStorage.DownloadObject(ctx, blobName(digest))
+}
+
+// PresignedGetObject presigns a download of the blob behind a pointer
+func (s *DedupStorage) PresignedGetObject(ctx context.Context, objectName string, expiry time.Duration, filename string) (string, error) {
+	digest, _, err := s.resolve(ctx, objectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign download: %w", err)
+	}
+	if digest == "" {
+		return s.ObjectStorage.PresignedGetObject(ctx, objectName, expiry, filename)
+	}
+
+	return s.ObjectStorage.PresignedGetObject(ctx, blobName(digest), expiry, filename)
+}
+
+// GetObjectInfo gets information about an object, reporting the blob's size and ETag
+func (s *DedupStorage) GetObjectInfo(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	digest, info, err := s.resolve(ctx, objectName)
+	if err != nil || digest == "" {
+		return info, err
+	}
+
+	blobInfo, err := s.ObjectStorage.GetObjectInfo(ctx, blobName(digest))
+	if err != nil {
+		return nil, err
+	}
+
+	info.Size = blobInfo.Size
+	info.ETag = blobInfo.ETag
+	info.SHA256 = digest
+	return info, nil
+}
+
+// ListObjects lists objects with the given prefix, reporting the real size of pointers
+func (s *DedupStorage) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects, err := s.ObjectStorage.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pointers are empty objects, so only those need resolving
+	for i := range objects {
+		if objects[i].Size != 0 || strings.HasPrefix(objects[i].Name, dedupPrefix) {
+			continue
+		}
+		info, err := s.ObjectStorage.GetObjectInfo(ctx, objects[i].Name)
+		if err != nil || info.dedupBlob == "" {
+			continue
+		}
+		objects[i].Size = info.dedupSize
+	}
+
+	return objects, nil
+}
+
+// DeleteObject deletes a pointer and releases its blob
+func (s *DedupStorage) DeleteObject(ctx context.Context, objectName string) error {
+	digest, _, err := s.resolve(ctx, objectName)
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	if err := s.ObjectStorage.DeleteObject(ctx, objectName); err != nil {
+		return err
+	}
+
+	if digest != "" {
+		return s.addRef(ctx, digest, -1)
+	}
+	return nil
+}
+
+// CopyObject copies a pointer, adding a reference to its blob
+func (s *DedupStorage) CopyObject(ctx context.Context, srcName, dstName string) error {
+	digest, _, err := s.resolve(ctx, srcName)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	previous := s.pointerBlob(ctx, dstName)
+
+	if digest != "" {
+		claimed, err := s.claim(ctx, digest, "")
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return fmt.Errorf("failed to copy object: blob %s: %w", digest, ErrNotFound)
+		}
+	}
+
+	if err := s.ObjectStorage.CopyObject(ctx, srcName, dstName); err != nil {
+		s.release(ctx, digest)
+		return err
+	}
+	s.release(ctx, previous)
+	return nil
+}
+
+// pointerBlob returns the blob an existing pointer refers to, or "" when
+// there is no pointer by that name
+func (s *DedupStorage) pointerBlob(ctx context.Context, objectName string) string {
+	exists, err := s.ObjectStorage.CheckObjectExists(ctx, objectName)
+	if err != nil || !exists {
+		return ""
+	}
+
+	digest, _, err := s.resolve(ctx, objectName)
+	if err != nil {
+		return ""
+	}
+	return digest
+}
+
+// release drops the reference a replaced pointer held on its blob
+func (s *DedupStorage) release(ctx context.Context, digest string) {
+	if digest == "" {
+		return
+	}
+	if err := s.addRef(ctx, digest, -1); err != nil {
+		s.logger.Printf("Warning: Could not release blob %s: %v", digest, err)
+	}
+}
+
+// claim adds a reference to the blob for a digest. A missing blob is first
+// created by copying source, or claim reports false when source is empty.
+// Checking and counting under one lock keeps a concurrent release from
+// deleting the blob in between.
+func (s *DedupStorage) claim(ctx context.Context, digest, source string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exists, err := s.ObjectStorage.CheckObjectExists(ctx, blobName(digest))
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		if source != "" {
+			s.logger.Printf("Deduplicated upload against existing blob %s", digest)
+		}
+	} else if source == "" {
+		return false, nil
+	} else if err := s.ObjectStorage.CopyObject(ctx, source, blobName(digest)); err != nil {
+		return false, err
+	}
+
+	return true, s.adjustRefs(ctx, digest, 1)
+}
+
+// addRef adjusts a blob's reference count and deletes the blob once it drops to zero
+func (s *DedupStorage) addRef(ctx context.Context, digest string, delta int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.adjustRefs(ctx, digest, delta)
+}
+
+// adjustRefs is addRef for callers already holding s.mu
+func (s *DedupStorage) adjustRefs(ctx context.Context, digest string, delta int) error {
+	count := 0
+	exists, err := s.ObjectStorage.CheckObjectExists(ctx, refsName(digest))
+	if err != nil {
+		return fmt.Errorf("failed to check blob references: %w", err)
+	}
+	if exists {
+		reader, err := s.ObjectStorage.DownloadObject(ctx, refsName(digest))
+		if err != nil {
+			return fmt.Errorf("failed to read blob references: %w", err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read blob references: %w", err)
+		}
+		count, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+
+	count += delta
+	if count > 0 {
+		data := []byte(strconv.Itoa(count))
+		_, err := s.ObjectStorage.UploadObject(ctx, refsName(digest), bytes.NewReader(data), int64(len(data)))
+		return err
+	}
+
+	// The last reference is gone, so remove the blob and its counter
+	s.logger.Printf("Removing unreferenced blob %s", digest)
+	if err := s.ObjectStorage.DeleteObject(ctx, blobName(digest)); err != nil {
+		return err
+	}
+	if exists {
+		return s.ObjectStorage.DeleteObject(ctx, refsName(digest))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingReader returns its data and then fails, like a dropped upload
+type failingReader struct {
+	data []byte
+}
+
+var errUploadDropped = errors.New("upload dropped")
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errUploadDropped
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// TestDedupFailedReplaceKeepsBlob replaces an object with an upload that
+// fails. The object must still read back whole, so the blob it refers to
+// can't have been released before the new pointer was written.
+func TestDedupFailedReplaceKeepsBlob(t *testing.T) {
+	ctx := context.Background()
+	store := NewDedupStorage(NewMemoryStorage(), log.New(io.Discard, "", 0))
+
+	original := []byte("the original data")
+	if _, err := store.UploadObject(ctx, "object", bytes.NewReader(original), int64(len(original))); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	_, err := store.UploadObject(ctx, "object", &failingReader{data: []byte("partial")}, 100)
+	if !errors.Is(err, errUploadDropped) {
+		t.Fatalf("replacing upload: got %v, want errUploadDropped", err)
+	}
+
+	reader, err := store.DownloadObject(ctx, "object")
+	if err != nil {
+		t.Fatalf("download after failed replace: %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(got, original) {
+		t.Fatalf("download after failed replace: got %q, %v, want %q", got, err, original)
+	}
+}
+
+// TestDedupReplaceReleasesOldBlob replaces an object with new content and
+// deletes it; no blob may be left behind.
+func TestDedupReplaceReleasesOldBlob(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStorage()
+	store := NewDedupStorage(inner, log.New(io.Discard, "", 0))
+
+	for _, data := range [][]byte{[]byte("first"), []byte("second"), []byte("second")} {
+		if _, err := store.UploadObject(ctx, "object", bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("upload %q: %v", data, err)
+		}
+	}
+	if err := store.DeleteObject(ctx, "object"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	objects, err := inner.ListObjects(ctx, "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	for _, object := range objects {
+		t.Errorf("left behind: %s", object.Name)
+	}
+}
+
+// checkHookStorage calls onCheck after every existence check
+type checkHookStorage struct {
+	ObjectStorage
+	onCheck func(objectName string)
+}
+
+func (s *checkHookStorage) CheckObjectExists(ctx context.Context, objectName string) (bool, error) {
+	exists, err := s.ObjectStorage.CheckObjectExists(ctx, objectName)
+	s.onCheck(objectName)
+	return exists, err
+}
+
+// TestDedupReuseRacingReleaseKeepsBlob deletes the only other object using
+// a blob while a new upload is finding that blob by its digest. The delete
+// must wait for the new reference, or it takes the blob with it.
+func TestDedupReuseRacingReleaseKeepsBlob(t *testing.T) {
+	ctx := context.Background()
+	hooked := &checkHookStorage{ObjectStorage: NewMemoryStorage(), onCheck: func(string) {}}
+	store := NewDedupStorage(hooked, log.New(io.Discard, "", 0))
+
+	data := []byte("shared content")
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if _, err := store.UploadObject(ctx, "first", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	deleted := make(chan error, 1)
+	var once sync.Once
+	hooked.onCheck = func(objectName string) {
+		if objectName != blobName(digest) {
+			return
+		}
+		once.Do(func() {
+			go func() { deleted <- store.DeleteObject(ctx, "first") }()
+			// Give the delete the chance to run, unless it waits for the reuse
+			select {
+			case err := <-deleted:
+				deleted <- err
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+
+	opts := UploadOptions{Metadata: map[string]string{MetadataSHA256: digest}}
+	if _, err := store.UploadObjectWithOptions(ctx, "second", bytes.NewReader(data), int64(len(data)), opts); err != nil {
+		t.Fatalf("reusing upload: %v", err)
+	}
+	if err := <-deleted; err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	reader, err := store.DownloadObject(ctx, "second")
+	if err != nil {
+		t.Fatalf("download after concurrent release: %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("download after concurrent release: got %q, %v, want %q", got, err, data)
+	}
+}
@@ -2,18 +2,69 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
 	"time"
 )
 
 // ObjectStorage defines the interface for storage operations
 type ObjectStorage interface {
-	UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) error
+	// UploadObject and UploadObjectWithOptions return what storage reported
+	// for the new object, so callers needn't stat it again
+	UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error)
+	UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error)
+	SetObjectMetadata(ctx context.Context, objectName string, metadata map[string]string) error
+	SetObjectTags(ctx context.Context, objectName string, tags map[string]string) error
+	GetObjectTags(ctx context.Context, objectName string) (map[string]string, error)
 	DownloadObject(ctx context.Context, objectName string) (io.ReadCloser, error)
 	CheckObjectExists(ctx context.Context, objectName string) (bool, error)
 	GetObjectInfo(ctx context.Context, objectName string) (*ObjectInfo, error)
 	ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	DeleteObject(ctx context.Context, objectName string) error
+	CopyObject(ctx context.Context, srcName, dstName string) error
 	GetBucketName() string
+	// Ping verifies that the storage backend is reachable and the bucket exists
+	Ping(ctx context.Context) error
+
+	// PresignedPostObject returns a form that lets a client upload the object
+	// directly to storage until the expiry elapses. Storage itself refuses
+	// uploads that are empty or larger than maxSize.
+	PresignedPostObject(ctx context.Context, objectName string, expiry time.Duration, maxSize int64) (*PresignedPost, error)
+	// PresignedGetObject returns a URL that lets a client download the object
+	// directly from storage as an attachment named filename
+	PresignedGetObject(ctx context.Context, objectName string, expiry time.Duration, filename string) (string, error)
+
+	// Multipart upload sessions
+	NewMultipartUpload(ctx context.Context, objectName string) (string, error)
+	PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (*PartInfo, error)
+	CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []PartInfo) (*ObjectInfo, error)
+	AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error
+}
+
+var (
+	// ErrPresignUnsupported is returned when an object's stored bytes differ from
+	// what clients expect, so storage can't serve it directly
+	ErrPresignUnsupported = errors.New("object cannot be served by a presigned URL")
+	// ErrNotFound is returned when the requested object doesn't exist
+	ErrNotFound = errors.New("object not found")
+	// ErrObjectLocked is returned when deleting an object still under retention
+	ErrObjectLocked = errors.New("object is under retention")
+)
+
+// MetadataSHA256 is the user-metadata key holding an object's SHA-256 digest
+const MetadataSHA256 = "Sha256"
+
+// UploadOptions holds optional settings for an upload
+type UploadOptions struct {
+	ContentType string
+	Metadata    map[string]string
+	Tags        map[string]string
+	// StorageClass overrides the configured storage class for this object
+	StorageClass string
+	// Retain applies the configured object lock retention. It is set for
+	// uploaded chunk and file data only, never for objects the app itself
+	// rewrites or deletes.
+	Retain bool
 }
 
 // ObjectInfo contains information about a stored object
@@ -22,4 +73,33 @@ type ObjectInfo struct {
 	LastModified time.Time
 	ETag         string
 	Name         string
-} 
\ No newline at end of file
+	SHA256       string // Empty for objects stored before checksums were recorded
+	ContentType  string
+	// UserMetadata holds the object's user-defined metadata
+	UserMetadata map[string]string
+
+	// Set on deduplication pointer objects
+	dedupBlob string
+	dedupSize int64
+
+	// Set on objects stored compressed
+	compressed   string
+	originalSize int64
+
+	// Set on listed objects whose Size may be the stored rather than the original size
+	storedSize bool
+}
+
+// PresignedPost is a presigned browser upload: a multipart/form-data POST to
+// URL carrying Fields, followed by the data as the "file" field
+type PresignedPost struct {
+	URL    string
+	Fields map[string]string
+}
+
+// PartInfo contains information about an uploaded part of a multipart upload
+type PartInfo struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
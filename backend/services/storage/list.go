@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ListObjectsSince lists the objects under a prefix that were modified after
+// since. It filters a full listing, so it works through every wrapper, but
+// saves callers from holding on to objects they don't want.
+func ListObjectsSince(ctx context.Context, storage ObjectStorage, prefix string, since time.Time) ([]ObjectInfo, error) {
+	objects, err := storage.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := objects[:0]
+	for _, obj := range objects {
+		if obj.LastModified.After(since) {
+			recent = append(recent, obj)
+		}
+	}
+	return recent, nil
+}
+
+// ResolveSizes replaces the stored sizes a listing reported with the original
+// sizes of the objects, statting up to concurrency objects at a time. Only
+// objects a wrapper marked as possibly differing are statted, so this costs
+// nothing unless such a wrapper, like compression, is in use.
+func ResolveSizes(ctx context.Context, storage ObjectStorage, objects []ObjectInfo, concurrency int) error {
+	var pending []int
+	for i := range objects {
+		if objects[i].storedSize {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	slots := make(chan struct{}, max(concurrency, 1))
+	for _, i := range pending {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(obj *ObjectInfo) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			info, err := storage.GetObjectInfo(ctx, obj.Name)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			obj.Size = info.Size
+			obj.storedSize = false
+		}(&objects[i])
+	}
+	wg.Wait()
+	return firstErr
+}
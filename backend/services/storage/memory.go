@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStorage implements ObjectStorage in memory, for tests and local
+// experiments. It reports objects the way MinIO does, so the storage wrappers
+// behave on top of it as they do in production, but it can't presign URLs.
+type MemoryStorage struct {
+	mu      sync.Mutex
+	objects map[string]*memoryObject
+	uploads map[string]map[int][]byte
+}
+
+// memoryObject is an object held by MemoryStorage
+type memoryObject struct {
+	data         []byte
+	contentType  string
+	metadata     map[string]string
+	tags         map[string]string
+	lastModified time.Time
+}
+
+// NewMemoryStorage creates an empty in-memory storage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		objects: make(map[string]*memoryObject),
+		uploads: make(map[string]map[int][]byte),
+	}
+}
+
+// info describes an object as MinIO's StatObject would
+func (o *memoryObject) info(name string) *ObjectInfo {
+	sum := md5.Sum(o.data)
+	info := &ObjectInfo{
+		Size:         int64(len(o.data)),
+		LastModified: o.lastModified,
+		ETag:         hex.EncodeToString(sum[:]),
+		Name:         name,
+		ContentType:  o.contentType,
+		UserMetadata: maps.Clone(o.metadata),
+	}
+	setMetadataFields(info)
+	return info
+}
+
+// UploadObject stores an object
+func (s *MemoryStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error) {
+	return s.UploadObjectWithOptions(ctx, objectName, reader, objectSize, UploadOptions{})
+}
+
+// UploadObjectWithOptions stores an object with a content type, metadata and tags
+func (s *MemoryStorage) UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload object: %w", err)
+	}
+	if objectSize >= 0 && int64(len(data)) != objectSize {
+		return nil, fmt.Errorf("failed to upload object: read %d bytes, expected %d", len(data), objectSize)
+	}
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	object := &memoryObject{
+		data:         data,
+		contentType:  contentType,
+		metadata:     maps.Clone(opts.Metadata),
+		tags:         maps.Clone(opts.Tags),
+		lastModified: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[objectName] = object
+	return object.info(objectName), nil
+}
+
+// SetObjectMetadata merges user metadata into an existing object
+func (s *MemoryStorage) SetObjectMetadata(ctx context.Context, objectName string, metadata map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[objectName]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, objectName)
+	}
+	if object.metadata == nil {
+		object.metadata = make(map[string]string, len(metadata))
+	}
+	maps.Copy(object.metadata, metadata)
+	return nil
+}
+
+// SetObjectTags replaces the tags of an existing object
+func (s *MemoryStorage) SetObjectTags(ctx context.Context, objectName string, tags map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[objectName]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, objectName)
+	}
+	object.tags = maps.Clone(tags)
+	return nil
+}
+
+// GetObjectTags returns the tags of an object
+func (s *MemoryStorage) GetObjectTags(ctx context.Context, objectName string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[objectName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, objectName)
+	}
+	return maps.Clone(object.tags), nil
+}
+
+// DownloadObject returns the data of an object
+func (s *MemoryStorage) DownloadObject(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[objectName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, objectName)
+	}
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+// CheckObjectExists reports whether an object exists
+func (s *MemoryStorage) CheckObjectExists(ctx context.Context, objectName string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.objects[objectName]
+	return ok, nil
+}
+
+// GetObjectInfo gets information about an object, returning ErrNotFound if it doesn't exist
+func (s *MemoryStorage) GetObjectInfo(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[objectName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, objectName)
+	}
+	return object.info(objectName), nil
+}
+
+// ListObjects lists objects with the given prefix in name order. Like a
+// bucket listing, it reports neither content types nor metadata.
+func (s *MemoryStorage) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var objects []ObjectInfo
+	for name, object := range s.objects {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		info := object.info(name)
+		objects = append(objects, ObjectInfo{
+			Size:         info.Size,
+			LastModified: info.LastModified,
+			ETag:         info.ETag,
+			Name:         name,
+		})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// DeleteObject deletes an object
+func (s *MemoryStorage) DeleteObject(ctx context.Context, objectName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[objectName]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, objectName)
+	}
+	delete(s.objects, objectName)
+	return nil
+}
+
+// CopyObject copies an object with its metadata and tags
+func (s *MemoryStorage) CopyObject(ctx context.Context, srcName, dstName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[srcName]
+	if !ok {
+		return fmt.Errorf("failed to copy object %s to %s: %w", srcName, dstName, ErrNotFound)
+	}
+	s.objects[dstName] = &memoryObject{
+		data:         object.data,
+		contentType:  object.contentType,
+		metadata:     maps.Clone(object.metadata),
+		tags:         maps.Clone(object.tags),
+		lastModified: time.Now(),
+	}
+	return nil
+}
+
+// GetBucketName returns a fixed bucket name
+func (s *MemoryStorage) GetBucketName() string {
+	return "memory"
+}
+
+// Ping always succeeds
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// PresignedPostObject is not supported in memory
+func (s *MemoryStorage) PresignedPostObject(ctx context.Context, objectName string, expiry time.Duration, maxSize int64) (*PresignedPost, error) {
+	return nil, ErrPresignUnsupported
+}
+
+// PresignedGetObject is not supported in memory
+func (s *MemoryStorage) PresignedGetObject(ctx context.Context, objectName string, expiry time.Duration, filename string) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+// NewMultipartUpload starts a multipart upload session and returns its upload ID
+func (s *MemoryStorage) NewMultipartUpload(ctx context.Context, objectName string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploadID := uuid.New().String()
+	s.uploads[uploadID] = make(map[int][]byte)
+	return uploadID, nil
+}
+
+// PutObjectPart stores a single part of a multipart upload
+func (s *MemoryStorage) PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (*PartInfo, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		return nil, fmt.Errorf("%w: upload %s", ErrNotFound, uploadID)
+	}
+	parts[partNumber] = data
+	sum := md5.Sum(data)
+	return &PartInfo{PartNumber: partNumber, ETag: hex.EncodeToString(sum[:]), Size: int64(len(data))}, nil
+}
+
+// CompleteMultipartUpload joins the listed parts into the object
+func (s *MemoryStorage) CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []PartInfo) (*ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploaded, ok := s.uploads[uploadID]
+	if !ok {
+		return nil, fmt.Errorf("%w: upload %s", ErrNotFound, uploadID)
+	}
+	var data []byte
+	for _, part := range parts {
+		chunk, ok := uploaded[part.PartNumber]
+		if !ok {
+			return nil, fmt.Errorf("failed to complete upload: part %d was not uploaded", part.PartNumber)
+		}
+		data = append(data, chunk...)
+	}
+	delete(s.uploads, uploadID)
+
+	object := &memoryObject{data: data, contentType: "application/octet-stream", lastModified: time.Now()}
+	s.objects[objectName] = object
+	return object.info(objectName), nil
+}
+
+// AbortMultipartUpload discards a multipart upload session
+func (s *MemoryStorage) AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.uploads, uploadID)
+	return nil
+}
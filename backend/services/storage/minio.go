@@ -3,34 +3,87 @@ package storage
 import (
 	"filesh/config"
 	"context"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 	"bufio"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/minio/minio-go/v7/pkg/tags"
 )
 
+// Bounds on the multipart upload part size imposed by S3
+const (
+	defaultPartSize = 64 * 1024 * 1024
+	minPartSize     = 5 * 1024 * 1024
+	maxPartSize     = 5 * 1024 * 1024 * 1024
+)
+
+// lockModeHeader carries an object's retention mode, if it has one
+const lockModeHeader = "X-Amz-Object-Lock-Mode"
+
+// storageClassHeader carries an object's storage class. Storage omits it for
+// objects in the default class.
+const storageClassHeader = "X-Amz-Storage-Class"
+
 // MinioStorage implements ObjectStorage interface using MinIO
 type MinioStorage struct {
 	client     *minio.Client
+	core       minio.Core
 	bucketName string
+	partSize   uint64
+	// sse is applied to every object written; nil leaves encryption to the bucket default
+	sse encrypt.ServerSide
+	// lockDays is the retention applied to user data written; zero disables it
+	lockDays int
+	// storageClass is applied to objects written without their own; empty
+	// leaves it to the provider's default
+	storageClass string
+	// maxRetries and retryDelay govern retries of uploads and downloads; the
+	// delay doubles after every attempt
+	maxRetries int
+	retryDelay time.Duration
 	logger     *log.Logger
 }
 
-// NewMinioStorage creates a new MinIO storage handler
-func NewMinioStorage(cfg config.MinioConfig, logger *log.Logger) (ObjectStorage, error) {
+// NewMinioStorage creates a new MinIO storage handler. Objects under the
+// expiring top-level prefixes are expired by bucket lifecycle rules after the
+// given expiry, rounded up to whole days.
+func NewMinioStorage(cfg config.MinioConfig, expiry time.Duration, expiringPrefixes []string, logger *log.Logger) (ObjectStorage, error) {
 	if logger == nil {
 		logger = log.New(log.Writer(), "[MINIO] ", log.LstdFlags)
 	}
 
+	// Some S3-compatible providers only support path-style requests
+	bucketLookup := minio.BucketLookupAuto
+	if cfg.PathStyle {
+		bucketLookup = minio.BucketLookupPath
+	}
+
+	// Tune connection reuse, and trust a private CA or skip verification if configured
+	transport, err := newTransport(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize MinIO client
 	client, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
-		Secure: cfg.UseSSL,
+		Creds:        newCredentials(cfg),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: bucketLookup,
+		Transport:    transport,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
@@ -42,41 +95,226 @@ func NewMinioStorage(cfg config.MinioConfig, logger *log.Logger) (ObjectStorage,
 		return nil, fmt.Errorf("failed to check if bucket exists: %w", err)
 	}
 
+	// Object lock can only be turned on when a bucket is created
 	if !exists {
-		err = client.MakeBucket(context.Background(), cfg.BucketName, minio.MakeBucketOptions{})
+		err = client.MakeBucket(context.Background(), cfg.BucketName, minio.MakeBucketOptions{
+			Region:        cfg.Region,
+			ObjectLocking: cfg.ObjectLockDays > 0,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create bucket: %w", err)
 		}
 		logger.Printf("Created bucket %s", cfg.BucketName)
+	} else if cfg.ObjectLockDays > 0 {
+		enabled, _, _, _, err := client.GetObjectLockConfig(context.Background(), cfg.BucketName)
+		if err != nil || enabled != "Enabled" {
+			return nil, fmt.Errorf("OBJECT_LOCK_DAYS needs a bucket created with object lock enabled, and %s was not", cfg.BucketName)
+		}
+	}
+	if cfg.StorageClass != "" {
+		logger.Printf("Objects are written with storage class %s", cfg.StorageClass)
+	}
+	if cfg.ObjectLockDays > 0 {
+		logger.Printf("Object lock enabled: uploaded data is retained for %d day(s) in compliance mode", cfg.ObjectLockDays)
+	}
 
-		// Set up lifecycle policy for auto-deletion
-		config := lifecycle.NewConfiguration()
-		config.Rules = []lifecycle.Rule{
-			{
-				ID:     "expire-rule",
-				Status: "Enabled",
-				Expiration: lifecycle.Expiration{
-					Days: 7,
-				},
-			},
+	// Set up lifecycle policy for auto-deletion, also on existing buckets so
+	// that expiry changes take effect
+	expiryDays := config.LifecycleDays(expiry)
+	config := lifecycle.NewConfiguration()
+	config.Rules = lifecycleRules(NormalizeObjectPrefix(cfg.ObjectPrefix), expiringPrefixes, expiryDays, cfg.ObjectLockDays > 0)
+
+	err = client.SetBucketLifecycle(context.Background(), cfg.BucketName, config)
+	if err != nil {
+		logger.Printf("Warning: Failed to set bucket lifecycle: %v", err)
+		// Continue even if lifecycle set fails
+	} else {
+		logger.Printf("Applied bucket lifecycle: objects expire after %d day(s) (configured expiry %v)", expiryDays, expiry)
+	}
+
+	// Smaller parts lower memory per concurrent upload, larger ones suit fast links
+	partSize := cfg.UploadPartSize
+	if partSize < minPartSize || partSize > maxPartSize {
+		logger.Printf("Warning: UPLOAD_PART_SIZE %d is outside %d-%d bytes, using %d", partSize, minPartSize, int64(maxPartSize), defaultPartSize)
+		partSize = defaultPartSize
+	}
+
+	sse, err := newServerSideEncryption(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if sse != nil {
+		logger.Printf("Server-side encryption enabled (%s)", cfg.SSEMode)
+	}
+
+	return &MinioStorage{
+		client:       client,
+		core:         minio.Core{Client: client},
+		bucketName:   cfg.BucketName,
+		partSize:     uint64(partSize),
+		sse:          sse,
+		lockDays:     cfg.ObjectLockDays,
+		storageClass: cfg.StorageClass,
+		maxRetries:   cfg.MaxRetries,
+		retryDelay:   cfg.RetryDelay,
+		logger:       logger,
+	}, nil
+}
+
+// retention returns the object lock mode and retain-until date for an object
+// written now. Compliance mode can't be shortened or lifted by anyone,
+// including the root account, until the date passes. Both are zero values
+// when object lock is off or the object isn't to be retained, as for the
+// sidecars, records and sessions the app rewrites and deletes itself.
+func (s *MinioStorage) retention(retain bool) (minio.RetentionMode, time.Time) {
+	if s.lockDays <= 0 || !retain {
+		return "", time.Time{}
+	}
+	return minio.Compliance, time.Now().UTC().AddDate(0, 0, s.lockDays)
+}
+
+// newServerSideEncryption returns the encryption requested for objects at
+// rest: SSE-S3 with storage-managed keys, or SSE-KMS with the configured key.
+// Storage decrypts on read, so downloads need no changes.
+func newServerSideEncryption(cfg config.MinioConfig) (encrypt.ServerSide, error) {
+	switch cfg.SSEMode {
+	case "", "none":
+		return nil, nil
+	case "s3":
+		return encrypt.NewSSE(), nil
+	case "kms":
+		sse, err := encrypt.NewSSEKMS(cfg.SSEKMSKeyID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure SSE-KMS: %w", err)
 		}
-		
-		err = client.SetBucketLifecycle(context.Background(), cfg.BucketName, config)
+		return sse, nil
+	default:
+		return nil, fmt.Errorf("unknown server-side encryption mode %q", cfg.SSEMode)
+	}
+}
+
+// newCredentials builds the credential provider selected by the config.
+// Everything but static keys is refreshed by the SDK when it expires, so
+// rotating STS tokens work without restarting the server.
+func newCredentials(cfg config.MinioConfig) *credentials.Credentials {
+	switch cfg.CredSource {
+	case "iam":
+		// An empty endpoint lets the SDK pick the EC2/ECS metadata service
+		return credentials.NewIAM("")
+	case "env":
+		return credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+		})
+	case "file":
+		return credentials.NewFileAWSCredentials(cfg.CredFile, cfg.CredProfile)
+	default:
+		return credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	}
+}
+
+// newTransport builds an HTTP transport honouring the TLS settings of the
+// config. It returns nil when the SDK's default transport will do.
+func newTransport(cfg config.MinioConfig, logger *log.Logger) (http.RoundTripper, error) {
+	transport, err := minio.DefaultTransport(cfg.UseSSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage transport: %w", err)
+	}
+
+	// Drop idle connections before the network does, so requests after a quiet
+	// period don't land on a connection that was silently closed. Everything
+	// goes to one endpoint, so the per-host limit is the one that matters.
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConns
+
+	if cfg.CACertFile == "" && !cfg.TLSInsecure {
+		return transport, nil
+	}
+	if !cfg.UseSSL {
+		logger.Printf("Warning: MINIO_CA_CERT and MINIO_TLS_INSECURE have no effect without MINIO_USE_SSL")
+		return transport, nil
+	}
+
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
 		if err != nil {
-			logger.Printf("Warning: Failed to set bucket lifecycle: %v", err)
-			// Continue even if lifecycle set fails
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		// Add the bundle to the system roots so public endpoints keep working
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CACertFile)
 		}
+		transport.TLSClientConfig.RootCAs = rootCAs
+		logger.Printf("Trusting storage CA bundle %s", cfg.CACertFile)
 	}
 
-	return &MinioStorage{
-		client:     client,
-		bucketName: cfg.BucketName,
-		logger:     logger,
-	}, nil
+	if cfg.TLSInsecure {
+		logger.Printf("WARNING: TLS certificate verification for storage is DISABLED (MINIO_TLS_INSECURE=true). " +
+			"Connections can be intercepted; never use this in production!")
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	return transport, nil
+}
+
+// lifecycleRules returns one expiry rule per expiring top-level prefix under
+// the object prefix, which leaves other applications' objects in a shared
+// bucket alone. Dedup staging leftovers expire as well, while the blobs
+// themselves are removed by their reference counts.
+func lifecycleRules(objectPrefix string, expiringPrefixes []string, expiryDays int, locked bool) []lifecycle.Rule {
+	var rules []lifecycle.Rule
+	for _, prefix := range append(slices.Clone(expiringPrefixes), strings.TrimSuffix(dedupStagingPrefix, "/")) {
+		rules = append(rules, lifecycle.Rule{
+			ID:         "expire-" + strings.ReplaceAll(prefix, "/", "-"),
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: objectPrefix + prefix + "/"},
+			Expiration: lifecycle.Expiration{
+				Days: lifecycle.ExpirationDays(expiryDays),
+			},
+		})
+	}
+
+	// Locked buckets are versioned, so every rewrite of a sidecar, record or
+	// reference count leaves an old version behind; drop those once they are
+	// a day old, or once their retention ends for retained data
+	if locked {
+		rules = append(rules, lifecycle.Rule{
+			ID:         "noncurrent-rule",
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: objectPrefix},
+			NoncurrentVersionExpiration: lifecycle.NoncurrentVersionExpiration{
+				NoncurrentDays: 1,
+			},
+		})
+	}
+	return rules
 }
 
 // UploadObject uploads a file to MinIO
-func (s *MinioStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) error {
+func (s *MinioStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error) {
+	return s.UploadObjectWithOptions(ctx, objectName, reader, objectSize, UploadOptions{})
+}
+
+// UploadObjectWithOptions uploads a file to MinIO with a content type and
+// user metadata, returning the size and ETag MinIO reported for it. Failed
+// uploads are retried only when the reader is an io.Seeker, which is rewound
+// to the start; streamed request bodies and compressed uploads are sent once.
+func (s *MinioStorage) UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error) {
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	storageClass := opts.StorageClass
+	if storageClass == "" {
+		storageClass = s.storageClass
+	}
+
 	// Add logging for troubleshooting
 	s.logger.Printf("Starting upload of object %s with expected size: %d bytes", objectName, objectSize)
 
@@ -84,27 +322,39 @@ func (s *MinioStorage) UploadObject(ctx context.Context, objectName string, read
 	bufReader := bufio.NewReader(reader)
 
 	// Upload with retries for large files
-	var err error
-	maxRetries := 3
-	retryDelay := 2 * time.Second
+	var (
+		info minio.UploadInfo
+		err  error
+	)
+	maxRetries := s.maxRetries
+	retryDelay := s.retryDelay
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
 			s.logger.Printf("Retry #%d for object %s after waiting %v", attempt, objectName, retryDelay)
-			time.Sleep(retryDelay)
+			if waitErr := waitRetry(ctx, retryDelay); waitErr != nil {
+				break
+			}
 			retryDelay *= 2 // Exponential backoff
 		}
 
+		mode, retainUntil := s.retention(opts.Retain)
 		option := minio.PutObjectOptions{
-			ContentType: "application/octet-stream",
+			ContentType:  contentType,
+			UserMetadata: opts.Metadata,
+			UserTags:     opts.Tags,
 			// Specifying part size to ensure proper handling of large files
-			PartSize: 64 * 1024 * 1024, // 64MB parts for multipart upload
+			PartSize:             s.partSize,
+			ServerSideEncryption: s.sse,
+			StorageClass:         storageClass,
+			Mode:                 mode,
+			RetainUntilDate:      retainUntil,
 		}
 
-		info, err := s.client.PutObject(ctx, s.bucketName, objectName, bufReader, objectSize, option)
+		info, err = s.client.PutObject(ctx, s.bucketName, objectName, bufReader, objectSize, option)
 		if err == nil {
 			s.logger.Printf("Successfully uploaded object %s: ETag=%s, Size=%d", objectName, info.ETag, info.Size)
-			return nil
+			return uploadedObjectInfo(info, contentType, opts.Metadata), nil
 		}
 
 		s.logger.Printf("Error on attempt #%d uploading object %s: %v", attempt+1, objectName, err)
@@ -116,35 +366,108 @@ func (s *MinioStorage) UploadObject(ctx context.Context, objectName string, read
 
 		// If retry, reset the reader for the next attempt
 		if seekable, ok := reader.(io.Seeker); ok {
-			if _, err := seekable.Seek(0, io.SeekStart); err != nil {
-				s.logger.Printf("Failed to reset reader position: %v", err)
+			if _, seekErr := seekable.Seek(0, io.SeekStart); seekErr != nil {
+				s.logger.Printf("Failed to reset reader position: %v", seekErr)
 				break // Can't retry if we can't reset the reader
 			}
+			// Drop whatever the buffer read ahead from the failed attempt
+			bufReader.Reset(reader)
 		} else {
 			s.logger.Printf("Reader is not seekable, cannot retry")
 			break
 		}
 	}
 
-	return fmt.Errorf("failed to upload object after %d attempts: %w", maxRetries+1, err)
+	return nil, fmt.Errorf("failed to upload object after %d attempts: %w", maxRetries+1, err)
+}
+
+// uploadedObjectInfo describes a new object from what PutObject returned and
+// what was sent with it. PutObject doesn't always report a modification
+// time, so the current time stands in for it.
+func uploadedObjectInfo(info minio.UploadInfo, contentType string, metadata map[string]string) *ObjectInfo {
+	lastModified := info.LastModified
+	if lastModified.IsZero() {
+		lastModified = time.Now()
+	}
+	return &ObjectInfo{
+		Size:         info.Size,
+		LastModified: lastModified,
+		ETag:         info.ETag,
+		Name:         info.Key,
+		SHA256:       metadata[MetadataSHA256],
+		ContentType:  contentType,
+		UserMetadata: metadata,
+	}
 }
 
-// DownloadObject downloads a file from MinIO
+// DownloadObject downloads a file from MinIO. Transient storage errors are
+// retried with backoff; a missing object fails at once with ErrNotFound.
 func (s *MinioStorage) DownloadObject(ctx context.Context, objectName string) (io.ReadCloser, error) {
 	s.logger.Printf("Downloading object: %s", objectName)
+
+	retryDelay := s.retryDelay
+	for attempt := 0; ; attempt++ {
+		obj, err := s.openObject(ctx, objectName)
+		if err == nil {
+			return obj, nil
+		}
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, objectName)
+		}
+		if attempt == s.maxRetries || !isRetryable(err) {
+			return nil, fmt.Errorf("failed to download object: %w", err)
+		}
+
+		s.logger.Printf("Error on attempt #%d downloading object %s, retrying in %v: %v", attempt+1, objectName, retryDelay, err)
+		if err := waitRetry(ctx, retryDelay); err != nil {
+			return nil, fmt.Errorf("failed to download object: %w", err)
+		}
+		retryDelay *= 2 // Exponential backoff
+	}
+}
+
+// openObject starts downloading an object. GetObject is lazy; Stat sends the
+// GET now, so a missing object is reported here rather than on the first read.
+func (s *MinioStorage) openObject(ctx context.Context, objectName string) (*minio.Object, error) {
 	obj, err := s.client.GetObject(ctx, s.bucketName, objectName, minio.GetObjectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to download object: %w", err)
+		return nil, err
+	}
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, err
 	}
 	return obj, nil
 }
 
+// isRetryable reports whether MinIO failed a request for a reason that may
+// clear up on its own, such as throttling or a brief outage
+func isRetryable(err error) bool {
+	switch minio.ToErrorResponse(err).Code {
+	case "SlowDown", "InternalError", "RequestTimeout", "ServiceUnavailable":
+		return true
+	}
+	return false
+}
+
+// waitRetry sleeps before a retry, returning early with the context's error
+// if it is cancelled
+func waitRetry(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // CheckObjectExists checks if an object exists in MinIO
 func (s *MinioStorage) CheckObjectExists(ctx context.Context, objectName string) (bool, error) {
 	_, err := s.client.StatObject(ctx, s.bucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
-		// Check if the error is a Not Found error
-		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		if isNotFound(err) {
 			return false, nil
 		}
 		return false, fmt.Errorf("failed to check object: %w", err)
@@ -152,19 +475,46 @@ func (s *MinioStorage) CheckObjectExists(ctx context.Context, objectName string)
 	return true, nil
 }
 
-// GetObjectInfo gets information about an object
+// isNotFound reports whether MinIO rejected a request because the object doesn't exist
+func isNotFound(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
+// GetObjectInfo gets information about an object, returning ErrNotFound if it doesn't exist
 func (s *MinioStorage) GetObjectInfo(ctx context.Context, objectName string) (*ObjectInfo, error) {
 	info, err := s.client.StatObject(ctx, s.bucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, objectName)
+		}
 		return nil, fmt.Errorf("failed to get object info: %w", err)
 	}
 	
-	return &ObjectInfo{
+	objectInfo := &ObjectInfo{
 		Size:         info.Size,
 		LastModified: info.LastModified,
 		ETag:         info.ETag,
 		Name:         info.Key,
-	}, nil
+		ContentType:  info.ContentType,
+		UserMetadata: info.UserMetadata,
+	}
+	setMetadataFields(objectInfo)
+
+	return objectInfo, nil
+}
+
+// setMetadataFields fills in what an object's user metadata says about it:
+// its digest, and whether it is a deduplication pointer or compressed
+func setMetadataFields(info *ObjectInfo) {
+	info.SHA256 = info.UserMetadata[MetadataSHA256]
+	info.dedupBlob = info.UserMetadata[metadataDedupBlob]
+	if info.dedupBlob != "" {
+		info.dedupSize, _ = strconv.ParseInt(info.UserMetadata[metadataDedupSize], 10, 64)
+	}
+	if compressed := info.UserMetadata[metadataCompressed]; compressed != "" {
+		info.compressed = compressed
+		info.originalSize, _ = strconv.ParseInt(info.UserMetadata[metadataOriginalSize], 10, 64)
+	}
 }
 
 // ListObjects lists objects with the given prefix
@@ -191,6 +541,265 @@ func (s *MinioStorage) ListObjects(ctx context.Context, prefix string) ([]Object
 	return objects, nil
 }
 
+// SetObjectMetadata merges user metadata into an existing object using a server-side copy
+func (s *MinioStorage) SetObjectMetadata(ctx context.Context, objectName string, metadata map[string]string) error {
+	info, err := s.client.StatObject(ctx, s.bucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get object info: %w", err)
+	}
+
+	// Replacing metadata drops everything not listed, so carry the existing values over
+	merged := make(map[string]string, len(info.UserMetadata)+len(metadata)+1)
+	for k, v := range info.UserMetadata {
+		merged[k] = v
+	}
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	merged["Content-Type"] = info.ContentType
+	// A copy without a storage class moves the object to the default one
+	if class := info.Metadata.Get(storageClassHeader); class != "" {
+		merged[storageClassHeader] = class
+	}
+
+	// The new version is retained exactly when the one it replaces was
+	mode, retainUntil := s.retention(info.Metadata.Get(lockModeHeader) != "")
+	_, err = s.client.CopyObject(ctx, minio.CopyDestOptions{
+		Bucket:          s.bucketName,
+		Object:          objectName,
+		UserMetadata:    merged,
+		ReplaceMetadata: true,
+		Encryption:      s.sse,
+		Mode:            mode,
+		RetainUntilDate: retainUntil,
+	}, minio.CopySrcOptions{
+		Bucket: s.bucketName,
+		Object: objectName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update object metadata: %w", err)
+	}
+
+	return nil
+}
+
+// SetObjectTags replaces the tag set of an existing object
+func (s *MinioStorage) SetObjectTags(ctx context.Context, objectName string, objectTags map[string]string) error {
+	t, err := tags.MapToObjectTags(objectTags)
+	if err != nil {
+		return fmt.Errorf("invalid object tags: %w", err)
+	}
+
+	if err := s.client.PutObjectTagging(ctx, s.bucketName, objectName, t, minio.PutObjectTaggingOptions{}); err != nil {
+		return fmt.Errorf("failed to tag object: %w", err)
+	}
+	return nil
+}
+
+// GetObjectTags returns the tag set of an object
+func (s *MinioStorage) GetObjectTags(ctx context.Context, objectName string) (map[string]string, error) {
+	t, err := s.client.GetObjectTagging(ctx, s.bucketName, objectName, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object tags: %w", err)
+	}
+	return t.ToMap(), nil
+}
+
+// DeleteObject removes an object from MinIO. With object lock on, it returns
+// ErrObjectLocked while the object is still under retention.
+func (s *MinioStorage) DeleteObject(ctx context.Context, objectName string) error {
+	s.logger.Printf("Deleting object: %s", objectName)
+
+	// Locked buckets are versioned, where a plain delete only hides the object
+	// behind a delete marker. Removing the current version itself is what
+	// retention actually guards.
+	var opts minio.RemoveObjectOptions
+	if s.lockDays > 0 {
+		info, err := s.client.StatObject(ctx, s.bucketName, objectName, minio.StatObjectOptions{})
+		if err != nil {
+			if isNotFound(err) {
+				return fmt.Errorf("%w: %s", ErrNotFound, objectName)
+			}
+			return fmt.Errorf("failed to get object info: %w", err)
+		}
+		opts.VersionID = info.VersionID
+	}
+
+	err := s.client.RemoveObject(ctx, s.bucketName, objectName, opts)
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, objectName)
+		}
+		if isObjectLocked(err) {
+			return fmt.Errorf("%w: %s", ErrObjectLocked, objectName)
+		}
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// isObjectLocked reports whether MinIO refused a delete because of retention,
+// which it signals as AccessDenied with an object lock message
+func isObjectLocked(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	return resp.Code == "AccessDenied" && strings.Contains(strings.ToLower(resp.Message), "object lock")
+}
+
+// CopyObject copies an object server-side, without the data leaving storage.
+// The copy keeps the source's storage class, and is retained if the source is.
+func (s *MinioStorage) CopyObject(ctx context.Context, srcName, dstName string) error {
+	info, err := s.client.StatObject(ctx, s.bucketName, srcName, minio.StatObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to copy object %s to %s: %w", srcName, dstName, err)
+	}
+
+	mode, retainUntil := s.retention(info.Metadata.Get(lockModeHeader) != "")
+	dst := minio.CopyDestOptions{
+		Bucket:          s.bucketName,
+		Object:          dstName,
+		Encryption:      s.sse,
+		Mode:            mode,
+		RetainUntilDate: retainUntil,
+	}
+
+	// The storage class can only be set alongside a full set of metadata
+	if class := info.Metadata.Get(storageClassHeader); class != "" {
+		dst.UserMetadata = make(map[string]string, len(info.UserMetadata)+2)
+		for k, v := range info.UserMetadata {
+			dst.UserMetadata[k] = v
+		}
+		dst.UserMetadata["Content-Type"] = info.ContentType
+		dst.UserMetadata[storageClassHeader] = class
+		dst.ReplaceMetadata = true
+	}
+
+	// ComposeObject transparently falls back to a multipart copy for sources over 5GB
+	_, err = s.client.ComposeObject(ctx, dst, minio.CopySrcOptions{
+		Bucket: s.bucketName,
+		Object: srcName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object %s to %s: %w", srcName, dstName, err)
+	}
+	return nil
+}
+
+// PresignedPostObject returns a presigned POST policy for uploading an object
+// directly to MinIO, limited to between 1 and maxSize bytes
+func (s *MinioStorage) PresignedPostObject(ctx context.Context, objectName string, expiry time.Duration, maxSize int64) (*PresignedPost, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(s.bucketName); err != nil {
+		return nil, err
+	}
+	if err := policy.SetKey(objectName); err != nil {
+		return nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expiry)); err != nil {
+		return nil, err
+	}
+	if err := policy.SetContentLengthRange(1, maxSize); err != nil {
+		return nil, err
+	}
+
+	u, fields, err := s.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload of %s: %w", objectName, err)
+	}
+	return &PresignedPost{URL: u.String(), Fields: fields}, nil
+}
+
+// PresignedGetObject returns a presigned URL for downloading an object directly
+// from MinIO, overriding Content-Disposition so the filename is preserved
+func (s *MinioStorage) PresignedGetObject(ctx context.Context, objectName string, expiry time.Duration, filename string) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	u, err := s.client.PresignedGetObject(ctx, s.bucketName, objectName, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign download of %s: %w", objectName, err)
+	}
+	return u.String(), nil
+}
+
+// NewMultipartUpload starts a multipart upload session and returns its upload
+// ID. Multipart uploads only carry file data, so the result is retained.
+func (s *MinioStorage) NewMultipartUpload(ctx context.Context, objectName string) (string, error) {
+	mode, retainUntil := s.retention(true)
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucketName, objectName, minio.PutObjectOptions{
+		ContentType:          "application/octet-stream",
+		ServerSideEncryption: s.sse,
+		StorageClass:         s.storageClass,
+		Mode:                 mode,
+		RetainUntilDate:      retainUntil,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	s.logger.Printf("Started multipart upload %s for object %s", uploadID, objectName)
+	return uploadID, nil
+}
+
+// PutObjectPart uploads a single part of a multipart upload
+func (s *MinioStorage) PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (*PartInfo, error) {
+	part, err := s.core.PutObjectPart(ctx, s.bucketName, objectName, uploadID, partNumber, reader, partSize, minio.PutObjectPartOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+	}
+
+	return &PartInfo{
+		PartNumber: part.PartNumber,
+		ETag:       part.ETag,
+		Size:       part.Size,
+	}, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the final object
+func (s *MinioStorage) CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []PartInfo) (*ObjectInfo, error) {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		})
+	}
+
+	info, err := s.core.CompleteMultipartUpload(ctx, s.bucketName, objectName, uploadID, completeParts, minio.PutObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	s.logger.Printf("Completed multipart upload %s for object %s: ETag=%s", uploadID, objectName, info.ETag)
+	return &ObjectInfo{
+		Size:         info.Size,
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		Name:         info.Key,
+	}, nil
+}
+
+// AbortMultipartUpload cancels a multipart upload and discards its parts
+func (s *MinioStorage) AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error {
+	if err := s.core.AbortMultipartUpload(ctx, s.bucketName, objectName, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+
+	s.logger.Printf("Aborted multipart upload %s for object %s", uploadID, objectName)
+	return nil
+}
+
+// Ping checks that the bucket is reachable
+func (s *MinioStorage) Ping(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to reach storage: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", s.bucketName)
+	}
+	return nil
+}
+
 // GetBucketName returns the bucket name
 func (s *MinioStorage) GetBucketName() string {
 	return s.bucketName
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"filesh/services/tenant"
+	"filesh/utils"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+const testBucket = "filesh-test"
+
+// fakeS3 answers the handful of S3 requests MinioStorage sends for single
+// objects, failing the first failPuts uploads with an internal error
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	failPuts int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+testBucket+"/")
+	data, exists := f.objects[key]
+
+	switch r.Method {
+	case http.MethodPut:
+		// Fail partway through the body, as a dropped connection would
+		if f.failPuts > 0 {
+			f.failPuts--
+			io.CopyN(io.Discard, r.Body, 1000)
+			writeS3Error(w, http.StatusInternalServerError, "InternalError", key)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(body)))
+	case http.MethodHead, http.MethodGet:
+		if !exists {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", key)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		if !exists {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", key)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// writeS3Error sends an S3 error document
+func writeS3Error(w http.ResponseWriter, status int, code, key string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message><Key>%s</Key><BucketName>%s</BucketName></Error>`, code, code, key, testBucket)
+}
+
+// newFakeMinioStorage returns a MinioStorage talking to a fake S3 server
+func newFakeMinioStorage(t *testing.T, fake *fakeS3) *MinioStorage {
+	t.Helper()
+	if fake.objects == nil {
+		fake.objects = make(map[string][]byte)
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := minio.New(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+		Creds:      credentials.NewStaticV4("", "", ""),
+		Region:     "us-east-1",
+		MaxRetries: 1,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	return &MinioStorage{
+		client:     client,
+		core:       minio.Core{Client: client},
+		bucketName: testBucket,
+		partSize:   16 << 20,
+		maxRetries: 2,
+		retryDelay: time.Millisecond,
+		logger:     log.New(io.Discard, "", 0),
+	}
+}
+
+func TestMinioMissingObjectsReturnErrNotFound(t *testing.T) {
+	s := newFakeMinioStorage(t, &fakeS3{})
+	ctx := context.Background()
+
+	if _, err := s.GetObjectInfo(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetObjectInfo: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.DownloadObject(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DownloadObject: got %v, want ErrNotFound", err)
+	}
+	if exists, err := s.CheckObjectExists(ctx, "missing"); err != nil || exists {
+		t.Errorf("CheckObjectExists: got %v, %v, want false, nil", exists, err)
+	}
+}
+
+func TestMinioUnreachableStorageIsNotErrNotFound(t *testing.T) {
+	s := newFakeMinioStorage(t, &fakeS3{})
+	client, err := minio.New("127.0.0.1:1", &minio.Options{
+		Creds:      credentials.NewStaticV4("", "", ""),
+		Region:     "us-east-1",
+		MaxRetries: 1,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	s.client = client
+
+	if _, err := s.GetObjectInfo(context.Background(), "object"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("GetObjectInfo: got %v, want a failure other than ErrNotFound", err)
+	}
+	if _, err := s.CheckObjectExists(context.Background(), "object"); err == nil {
+		t.Error("CheckObjectExists: got no error for unreachable storage")
+	}
+}
+
+func TestMinioUploadRetryResendsWholeBody(t *testing.T) {
+	fake := &fakeS3{failPuts: 1}
+	s := newFakeMinioStorage(t, fake)
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	info, err := s.UploadObjectWithOptions(context.Background(), "object", bytes.NewReader(data), int64(len(data)), UploadOptions{})
+	if err != nil {
+		t.Fatalf("UploadObjectWithOptions: %v", err)
+	}
+	if info.Size != int64(len(data)) {
+		t.Errorf("size: got %d, want %d", info.Size, len(data))
+	}
+	if !bytes.Equal(fake.objects["object"], data) {
+		t.Errorf("stored %d bytes differing from the %d sent", len(fake.objects["object"]), len(data))
+	}
+}
+
+func TestMinioUploadErrorWrapsCause(t *testing.T) {
+	fake := &fakeS3{failPuts: 10}
+	s := newFakeMinioStorage(t, fake)
+	data := []byte("data")
+
+	_, err := s.UploadObjectWithOptions(context.Background(), "object", bytes.NewReader(data), int64(len(data)), UploadOptions{})
+	var resp minio.ErrorResponse
+	if !errors.As(err, &resp) || resp.Code != "InternalError" {
+		t.Fatalf("got %v, want the storage's InternalError", err)
+	}
+	if fake.failPuts != 10-(s.maxRetries+1) {
+		t.Errorf("sent %d uploads, want %d", 10-fake.failPuts, s.maxRetries+1)
+	}
+}
+
+// errReader fails every read with errBrokenBody
+type errReader struct{}
+
+var errBrokenBody = errors.New("broken body")
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errBrokenBody
+}
+
+func TestMinioUploadReadErrorWrapsCause(t *testing.T) {
+	s := newFakeMinioStorage(t, &fakeS3{})
+
+	_, err := s.UploadObjectWithOptions(context.Background(), "object", errReader{}, 4, UploadOptions{})
+	if !errors.Is(err, errBrokenBody) {
+		t.Fatalf("got %v, want it to wrap errBrokenBody", err)
+	}
+}
+
+// expiringRule returns the ID of the expiry rule covering an object, if any
+func expiringRule(rules []lifecycle.Rule, objectName string) string {
+	for _, rule := range rules {
+		if !rule.Expiration.IsDaysNull() && strings.HasPrefix(objectName, rule.RuleFilter.Prefix) {
+			return rule.ID
+		}
+	}
+	return ""
+}
+
+func TestLifecycleRulesSpareBlobsAndMarkers(t *testing.T) {
+	rules := lifecycleRules("app/", tenant.ExpiringPrefixes([]string{"team"}), 7, true)
+
+	for _, name := range []string{"app/public/batch/0", "app/team/batch/0", "app/files/f.txt", "app/sha256/staging/upload"} {
+		if expiringRule(rules, name) == "" {
+			t.Errorf("%s: no expiry rule", name)
+		}
+	}
+	for _, name := range []string{"app/sha256/abc", "app/sha256/abc.refs", "app/blocked/public/batch", "other/public/batch/0", "public/batch/0"} {
+		if id := expiringRule(rules, name); id != "" {
+			t.Errorf("%s: expired by rule %s", name, id)
+		}
+	}
+}
+
+func TestThrottledHashedUploadRetries(t *testing.T) {
+	fake := &fakeS3{failPuts: 1}
+	s := NewThrottleStorage(newFakeMinioStorage(t, fake), 1<<30)
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	hasher := sha256.New()
+	reader := utils.NewHashingReader(bytes.NewReader(data), hasher)
+	if _, err := s.UploadObjectWithOptions(context.Background(), "object", reader, int64(len(data)), UploadOptions{}); err != nil {
+		t.Fatalf("UploadObjectWithOptions: %v", err)
+	}
+	if !bytes.Equal(fake.objects["object"], data) {
+		t.Errorf("stored %d bytes differing from the %d sent", len(fake.objects["object"]), len(data))
+	}
+	if want := sha256.Sum256(data); !bytes.Equal(hasher.Sum(nil), want[:]) {
+		t.Error("hash covers more than one pass over the data")
+	}
+}
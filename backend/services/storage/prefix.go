@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// PrefixStorage keeps all objects under a fixed prefix, so file.sh can share a
+// bucket with other applications. Callers work with unprefixed names: the
+// prefix is added on the way in and stripped from every returned name, so
+// code that slices listed names by its own list prefix keeps working.
+type PrefixStorage struct {
+	ObjectStorage
+	prefix string
+}
+
+// NewPrefixStorage wraps a storage backend so that every object name is
+// stored under prefix. An empty prefix returns the backend unchanged.
+func NewPrefixStorage(inner ObjectStorage, prefix string) ObjectStorage {
+	prefix = NormalizeObjectPrefix(prefix)
+	if prefix == "" {
+		return inner
+	}
+	return &PrefixStorage{ObjectStorage: inner, prefix: prefix}
+}
+
+// NormalizeObjectPrefix trims surrounding slashes from a prefix and
+// terminates it with one, so "data", "/data" and "data/" all mean "data/"
+func NormalizeObjectPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// key returns the stored name of an object
+func (s *PrefixStorage) key(objectName string) string {
+	return s.prefix + objectName
+}
+
+// strip returns a copy of the info with the prefix removed from its name
+func (s *PrefixStorage) strip(info *ObjectInfo) *ObjectInfo {
+	if info == nil {
+		return nil
+	}
+	stripped := *info
+	stripped.Name = strings.TrimPrefix(info.Name, s.prefix)
+	return &stripped
+}
+
+// UploadObject uploads an object under the prefix
+func (s *PrefixStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error) {
+	info, err := s.ObjectStorage.UploadObject(ctx, s.key(objectName), reader, objectSize)
+	return s.strip(info), err
+}
+
+// UploadObjectWithOptions uploads an object under the prefix
+func (s *PrefixStorage) UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error) {
+	info, err := s.ObjectStorage.UploadObjectWithOptions(ctx, s.key(objectName), reader, objectSize, opts)
+	return s.strip(info), err
+}
+
+// SetObjectMetadata merges user metadata into an object under the prefix
+func (s *PrefixStorage) SetObjectMetadata(ctx context.Context, objectName string, metadata map[string]string) error {
+	return s.ObjectStorage.SetObjectMetadata(ctx, s.key(objectName), metadata)
+}
+
+// SetObjectTags replaces the tags of an object under the prefix
+func (s *PrefixStorage) SetObjectTags(ctx context.Context, objectName string, tags map[string]string) error {
+	return s.ObjectStorage.SetObjectTags(ctx, s.key(objectName), tags)
+}
+
+// GetObjectTags returns the tags of an object under the prefix
+func (s *PrefixStorage) GetObjectTags(ctx context.Context, objectName string) (map[string]string, error) {
+	return s.ObjectStorage.GetObjectTags(ctx, s.key(objectName))
+}
+
+// DownloadObject downloads an object under the prefix
+func (s *PrefixStorage) DownloadObject(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	return s.ObjectStorage.DownloadObject(ctx, s.key(objectName))
+}
+
+// CheckObjectExists checks whether an object exists under the prefix
+func (s *PrefixStorage) CheckObjectExists(ctx context.Context, objectName string) (bool, error) {
+	return s.ObjectStorage.CheckObjectExists(ctx, s.key(objectName))
+}
+
+// GetObjectInfo returns information about an object under the prefix
+func (s *PrefixStorage) GetObjectInfo(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	info, err := s.ObjectStorage.GetObjectInfo(ctx, s.key(objectName))
+	if err != nil {
+		return nil, err
+	}
+	return s.strip(info), nil
+}
+
+// ListObjects lists objects under the prefix, reporting unprefixed names
+func (s *PrefixStorage) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects, err := s.ObjectStorage.ListObjects(ctx, s.key(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		objects[i].Name = strings.TrimPrefix(objects[i].Name, s.prefix)
+	}
+	return objects, nil
+}
+
+// DeleteObject deletes an object under the prefix
+func (s *PrefixStorage) DeleteObject(ctx context.Context, objectName string) error {
+	return s.ObjectStorage.DeleteObject(ctx, s.key(objectName))
+}
+
+// CopyObject copies an object within the prefix
+func (s *PrefixStorage) CopyObject(ctx context.Context, srcName, dstName string) error {
+	return s.ObjectStorage.CopyObject(ctx, s.key(srcName), s.key(dstName))
+}
+
+// PresignedPostObject presigns an upload of an object under the prefix
+func (s *PrefixStorage) PresignedPostObject(ctx context.Context, objectName string, expiry time.Duration, maxSize int64) (*PresignedPost, error) {
+	return s.ObjectStorage.PresignedPostObject(ctx, s.key(objectName), expiry, maxSize)
+}
+
+// PresignedGetObject presigns a download of an object under the prefix
+func (s *PrefixStorage) PresignedGetObject(ctx context.Context, objectName string, expiry time.Duration, filename string) (string, error) {
+	return s.ObjectStorage.PresignedGetObject(ctx, s.key(objectName), expiry, filename)
+}
+
+// NewMultipartUpload starts a multipart upload of an object under the prefix
+func (s *PrefixStorage) NewMultipartUpload(ctx context.Context, objectName string) (string, error) {
+	return s.ObjectStorage.NewMultipartUpload(ctx, s.key(objectName))
+}
+
+// PutObjectPart uploads a part of an object under the prefix
+func (s *PrefixStorage) PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (*PartInfo, error) {
+	return s.ObjectStorage.PutObjectPart(ctx, s.key(objectName), uploadID, partNumber, reader, partSize)
+}
+
+// CompleteMultipartUpload assembles an object under the prefix from its parts
+func (s *PrefixStorage) CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []PartInfo) (*ObjectInfo, error) {
+	info, err := s.ObjectStorage.CompleteMultipartUpload(ctx, s.key(objectName), uploadID, parts)
+	if err != nil {
+		return nil, err
+	}
+	return s.strip(info), nil
+}
+
+// AbortMultipartUpload aborts a multipart upload of an object under the prefix
+func (s *PrefixStorage) AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error {
+	return s.ObjectStorage.AbortMultipartUpload(ctx, s.key(objectName), uploadID)
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SelfTestNamespace is the top-level prefix holding the startup self-test object
+const SelfTestNamespace = "selftest"
+
+// SelfTest round-trips a small object through storage: it uploads it, reads
+// it back, compares the bytes and deletes it. The returned error names the
+// step that failed, so credentials lacking write or delete permission are
+// reported as such.
+func SelfTest(ctx context.Context, storage ObjectStorage) error {
+	objectName := fmt.Sprintf("%s/%s", SelfTestNamespace, uuid.New().String())
+	payload := []byte("file.sh self-test " + time.Now().UTC().Format(time.RFC3339Nano))
+
+	if _, err := storage.UploadObject(ctx, objectName, bytes.NewReader(payload), int64(len(payload))); err != nil {
+		return fmt.Errorf("self-test upload failed: %w", err)
+	}
+
+	reader, err := storage.DownloadObject(ctx, objectName)
+	if err != nil {
+		storage.DeleteObject(ctx, objectName)
+		return fmt.Errorf("self-test download failed: %w", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		storage.DeleteObject(ctx, objectName)
+		return fmt.Errorf("self-test download failed: %w", err)
+	}
+	if !bytes.Equal(data, payload) {
+		storage.DeleteObject(ctx, objectName)
+		return fmt.Errorf("self-test read back %d bytes that differ from the %d uploaded", len(data), len(payload))
+	}
+
+	if err := storage.DeleteObject(ctx, objectName); err != nil {
+		return fmt.Errorf("self-test delete failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"filesh/utils"
+	"io"
+)
+
+// ThrottleStorage caps how fast each upload is sent to storage, so a single
+// fast uploader can't saturate the link to the backend. Every upload call
+// gets its own limiter; waiting honours the call's context, so a cancelled
+// upload stops at once.
+type ThrottleStorage struct {
+	ObjectStorage
+	bytesPerSecond int64
+}
+
+// NewThrottleStorage wraps a storage backend so each upload sends at most
+// bytesPerSecond. A non-positive rate returns the backend unchanged.
+func NewThrottleStorage(inner ObjectStorage, bytesPerSecond int64) ObjectStorage {
+	if bytesPerSecond <= 0 {
+		return inner
+	}
+	return &ThrottleStorage{ObjectStorage: inner, bytesPerSecond: bytesPerSecond}
+}
+
+// UploadObject uploads an object at the configured rate
+func (s *ThrottleStorage) UploadObject(ctx context.Context, objectName string, reader io.Reader, objectSize int64) (*ObjectInfo, error) {
+	return s.ObjectStorage.UploadObject(ctx, objectName, utils.NewRateLimitedReader(ctx, reader, s.bytesPerSecond), objectSize)
+}
+
+// UploadObjectWithOptions uploads an object at the configured rate
+func (s *ThrottleStorage) UploadObjectWithOptions(ctx context.Context, objectName string, reader io.Reader, objectSize int64, opts UploadOptions) (*ObjectInfo, error) {
+	return s.ObjectStorage.UploadObjectWithOptions(ctx, objectName, utils.NewRateLimitedReader(ctx, reader, s.bytesPerSecond), objectSize, opts)
+}
+
+// PutObjectPart uploads one part of a multipart upload at the configured rate
+func (s *ThrottleStorage) PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (*PartInfo, error) {
+	return s.ObjectStorage.PutObjectPart(ctx, objectName, uploadID, partNumber, utils.NewRateLimitedReader(ctx, reader, s.bytesPerSecond), partSize)
+}
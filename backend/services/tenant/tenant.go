@@ -0,0 +1,94 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// Public is the shared namespace for anonymous requests
+const Public = "public"
+
+// validID restricts tenant IDs to a single safe object-name segment
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// reservedNamespaces are top-level prefixes that hold application data other
+// than batches, so no tenant may use them. The value says whether the bucket
+// lifecycle expires the namespace's objects by age.
+var reservedNamespaces = map[string]bool{
+	"aliases":   true,
+	"files":     true,
+	"multipart": true,
+	"presigned": true,
+	"reports":   true,
+	"selftest":  true,
+	// Take-down markers must outlive the batches they block, or the batch
+	// IDs become uploadable again
+	"blocked": false,
+	// Deduplicated blobs are shared by pointers younger than the blob, so
+	// only their reference count may remove them
+	"sha256": false,
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx scoped to the given tenant
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant a request is scoped to, defaulting to the
+// public namespace when none was set
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Public
+}
+
+// IsValid reports whether id is a well-formed tenant ID, including Public
+func IsValid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// IsReserved reports whether a top-level prefix holds application data
+// other than batches
+func IsReserved(segment string) bool {
+	_, reserved := reservedNamespaces[segment]
+	return reserved
+}
+
+// ExpiringPrefixes returns the top-level prefixes whose objects the bucket
+// lifecycle expires: the batches of the public namespace and of the given
+// tenants, and the reserved namespaces that expire along with them
+func ExpiringPrefixes(tenantIDs []string) []string {
+	prefixes := []string{Public}
+	for namespace, expires := range reservedNamespaces {
+		if expires {
+			prefixes = append(prefixes, namespace)
+		}
+	}
+	for _, id := range tenantIDs {
+		if !slices.Contains(prefixes, id) {
+			prefixes = append(prefixes, id)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// Validate checks that a configured tenant ID can be used as an object prefix
+func Validate(id string) error {
+	if !IsValid(id) {
+		return fmt.Errorf("tenant ID %q must be 1-64 letters, digits, '-' or '_'", id)
+	}
+	if id == Public {
+		return fmt.Errorf("tenant ID %q is reserved for anonymous uploads", id)
+	}
+	if IsReserved(id) {
+		return fmt.Errorf("tenant ID %q collides with a reserved storage namespace", id)
+	}
+	return nil
+}
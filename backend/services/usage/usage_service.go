@@ -0,0 +1,136 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"filesh/models"
+	"filesh/services/storage"
+	"filesh/services/tenant"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when an upload would take a tenant over its quota
+var ErrQuotaExceeded = errors.New("tenant storage quota exceeded")
+
+// Service tracks per-tenant storage usage against configured quotas
+type Service struct {
+	storage  storage.ObjectStorage
+	quotas   map[string]int64
+	cacheTTL time.Duration
+	logger   *log.Logger
+
+	mu    sync.Mutex
+	cache map[string]*cachedUsage
+}
+
+type cachedUsage struct {
+	bytes    int64
+	cachedAt time.Time
+}
+
+// NewService creates a new usage service. quotas maps tenant IDs to their
+// limit in bytes; tenants without an entry are unlimited. Computed usage is
+// cached for cacheTTL.
+func NewService(storage storage.ObjectStorage, quotas map[string]int64, cacheTTL time.Duration, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New(log.Writer(), "[USAGE] ", log.LstdFlags)
+	}
+
+	return &Service{
+		storage:  storage,
+		quotas:   quotas,
+		cacheTTL: cacheTTL,
+		logger:   logger,
+		cache:    make(map[string]*cachedUsage),
+	}
+}
+
+// GetUsage returns the storage used by the request's tenant and its quota
+func (s *Service) GetUsage(ctx context.Context) (*models.TenantUsage, error) {
+	tenantID := tenant.FromContext(ctx)
+
+	used, err := s.usedBytes(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.TenantUsage{
+		Tenant:     tenantID,
+		UsedBytes:  used,
+		QuotaBytes: s.quotas[tenantID],
+	}
+	if result.QuotaBytes > 0 {
+		remaining := max(result.QuotaBytes-used, 0)
+		result.RemainingBytes = &remaining
+	}
+	return result, nil
+}
+
+// CheckQuota returns ErrQuotaExceeded when storing size more bytes would take
+// the request's tenant over its quota
+func (s *Service) CheckQuota(ctx context.Context, size int64) error {
+	tenantID := tenant.FromContext(ctx)
+	quota := s.quotas[tenantID]
+	if quota <= 0 {
+		return nil
+	}
+
+	used, err := s.usedBytes(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if used+size > quota {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, used, quota)
+	}
+	return nil
+}
+
+// RecordUpload adds freshly stored bytes to a tenant's cached usage, so that
+// consecutive uploads don't each require a full listing
+func (s *Service) RecordUpload(tenantID string, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.cache[tenantID]; ok {
+		entry.bytes += size
+	}
+}
+
+// Invalidate drops a tenant's cached usage, e.g. after objects were deleted
+func (s *Service) Invalidate(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cache, tenantID)
+}
+
+// usedBytes returns a tenant's usage, listing its namespace when the cached
+// value is missing or older than the TTL
+func (s *Service) usedBytes(ctx context.Context, tenantID string) (int64, error) {
+	s.mu.Lock()
+	if entry, ok := s.cache[tenantID]; ok && time.Since(entry.cachedAt) < s.cacheTTL {
+		s.mu.Unlock()
+		return entry.bytes, nil
+	}
+	s.mu.Unlock()
+
+	objects, err := s.storage.ListObjects(ctx, tenantID+"/")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenant objects: %w", err)
+	}
+
+	var used int64
+	for _, obj := range objects {
+		used += obj.Size
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = &cachedUsage{bytes: used, cachedAt: time.Now()}
+	s.mu.Unlock()
+
+	s.logger.Printf("Computed usage for tenant %s over %d objects: %d bytes", tenantID, len(objects), used)
+	return used, nil
+}
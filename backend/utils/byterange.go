@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrRangeNotSatisfiable is returned when a Range header lies outside the content
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// ByteRange is a single resolved byte range within a resource
+type ByteRange struct {
+	Start  int64
+	Length int64
+}
+
+// ContentRange formats the range as a Content-Range header value
+func (r ByteRange) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+// ParseByteRange resolves a Range header against a resource of the given size.
+// Only a single range is supported; it returns nil when the header is absent,
+// malformed or asks for several ranges, in which case the full content should
+// be served. ErrRangeNotSatisfiable is returned for ranges past the end.
+func ParseByteRange(header string, size int64) (*ByteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, nil
+	}
+
+	// Suffix range: the last N bytes
+	if startStr == "" {
+		suffix, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffix < 0 {
+			return nil, nil
+		}
+		if suffix == 0 || size == 0 {
+			return nil, ErrRangeNotSatisfiable
+		}
+		suffix = min(suffix, size)
+		return &ByteRange{Start: size - suffix, Length: suffix}, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return nil, nil
+	}
+	if start >= size {
+		return nil, ErrRangeNotSatisfiable
+	}
+
+	// Open-ended range: from start to the end
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return nil, nil
+		}
+		end = min(end, size-1)
+	}
+
+	return &ByteRange{Start: start, Length: end - start + 1}, nil
+}
+
+// ParseContentRange parses a Content-Range request header, either
+// "bytes start-end/total" or "bytes */total", which names no range and
+// yields a nil ByteRange. The total length must be given and the range must
+// lie within it.
+func ParseContentRange(header string) (*ByteRange, int64, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes ")
+	if !ok {
+		return nil, 0, fmt.Errorf("Content-Range must start with \"bytes \"")
+	}
+
+	rangeStr, totalStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return nil, 0, fmt.Errorf("Content-Range must give the total length")
+	}
+	total, err := strconv.ParseInt(totalStr, 10, 64)
+	if err != nil || total < 0 {
+		return nil, 0, fmt.Errorf("invalid total length %q", totalStr)
+	}
+	if rangeStr == "*" {
+		return nil, total, nil
+	}
+
+	startStr, endStr, ok := strings.Cut(rangeStr, "-")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid range %q", rangeStr)
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return nil, 0, fmt.Errorf("invalid range start %q", startStr)
+	}
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil || end < start || end >= total {
+		return nil, 0, fmt.Errorf("invalid range end %q", endStr)
+	}
+
+	return &ByteRange{Start: start, Length: end - start + 1}, total, nil
+}
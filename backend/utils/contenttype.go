@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"mime"
+	"strings"
+)
+
+// ActiveContentType reports whether a media type can run script or markup
+// when a browser renders it, such as HTML, XML, SVG or JavaScript. Such
+// content must never be served from the app's origin under its declared type.
+// Types that don't parse count as active.
+func ActiveContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+
+	_, subtype, _ := strings.Cut(mediaType, "/")
+	return strings.Contains(subtype, "html") ||
+		subtype == "xml" || strings.HasSuffix(subtype, "+xml") ||
+		strings.Contains(subtype, "javascript") || strings.Contains(subtype, "ecmascript") ||
+		strings.Contains(subtype, "svg") || subtype == "xsl" || subtype == "xslt"
+}
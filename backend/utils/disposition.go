@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// fallbackFilename is used when a requested filename sanitizes to nothing
+const fallbackFilename = "download"
+
+// MaxFilenameLength caps the length in bytes of an uploaded file's name
+const MaxFilenameLength = 255
+
+// ErrInvalidFilename is returned for upload filenames that can't be made safe
+var ErrInvalidFilename = errors.New("invalid filename")
+
+// SanitizeFilename reduces a client-supplied filename to its final path
+// element and strips control characters, so it can't smuggle a path or break
+// out of a header
+func SanitizeFilename(name string) string {
+	if name = stripFilename(name); name == "" {
+		return fallbackFilename
+	}
+	return name
+}
+
+// CleanFilename prepares the name of an uploaded file for storage. It is
+// normalized to NFC, so visually identical names compare equal, and then
+// sanitized like SanitizeFilename. Names that aren't valid UTF-8, are empty
+// once sanitized or are longer than MaxFilenameLength are rejected rather
+// than replaced.
+func CleanFilename(name string) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", fmt.Errorf("%w: not valid UTF-8", ErrInvalidFilename)
+	}
+	name = stripFilename(norm.NFC.String(name))
+	if name == "" {
+		return "", fmt.Errorf("%w: name is empty", ErrInvalidFilename)
+	}
+	if len(name) > MaxFilenameLength {
+		return "", fmt.Errorf("%w: name exceeds %d bytes", ErrInvalidFilename, MaxFilenameLength)
+	}
+	return name, nil
+}
+
+// stripFilename does the work of SanitizeFilename, returning an empty string
+// for names with nothing left
+func stripFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.TrimSpace(name)
+	if name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// ContentDisposition formats a Content-Disposition header value as per
+// RFC 6266. Names that aren't plain ASCII get an RFC 5987 filename*
+// parameter alongside an ASCII approximation for older clients.
+func ContentDisposition(dispositionType, filename string) string {
+	filename = SanitizeFilename(filename)
+
+	ascii := true
+	fallback := strings.Map(func(r rune) rune {
+		switch {
+		case r > unicode.MaxASCII:
+			ascii = false
+			return '_'
+		case r == '"' || r == '\\':
+			return '_'
+		}
+		return r
+	}, filename)
+
+	if ascii {
+		return fmt.Sprintf("%s; filename=\"%s\"", dispositionType, fallback)
+	}
+	return fmt.Sprintf("%s; filename=\"%s\"; filename*=UTF-8''%s", dispositionType, fallback, encodeExtValue(filename))
+}
+
+// encodeExtValue percent-encodes a string as an RFC 5987 ext-value, leaving
+// only attr-char unescaped
+func encodeExtValue(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c < unicode.MaxASCII && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", c) >= 0) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"hash"
+	"io"
+)
+
+// errHashingSeek is returned for seeks other than a rewind to the start
+var errHashingSeek = errors.New("hashing reader can only rewind to the start")
+
+// hashingReader writes everything read through it to a hash
+type hashingReader struct {
+	reader io.Reader
+	hash   hash.Hash
+}
+
+// hashingReadSeeker is a hashingReader over a seekable source
+type hashingReadSeeker struct {
+	*hashingReader
+	seeker io.Seeker
+}
+
+// NewHashingReader returns a reader that writes what it reads to h, like
+// io.TeeReader. A seekable source stays seekable, so a failed upload can be
+// retried: rewinding to the start rewinds the source and resets h.
+func NewHashingReader(reader io.Reader, h hash.Hash) io.Reader {
+	r := &hashingReader{reader: reader, hash: h}
+	if seeker, ok := reader.(io.Seeker); ok {
+		return &hashingReadSeeker{hashingReader: r, seeker: seeker}
+	}
+	return r
+}
+
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.hash.Write(p[:n])
+	return n, err
+}
+
+// Seek rewinds the source to the start and restarts the hash
+func (r *hashingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, errHashingSeek
+	}
+	pos, err := r.seeker.Seek(0, io.SeekStart)
+	if err != nil {
+		return pos, err
+	}
+	r.hash.Reset()
+	return pos, nil
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Histogram counts observations into buckets by upper bound, in the manner
+// of a Prometheus histogram, so percentiles can be estimated from it. It is
+// an expvar.Var and renders as JSON with cumulative bucket counts.
+type Histogram struct {
+	bounds []float64
+
+	mu     sync.Mutex
+	counts []uint64 // Per bucket, with one more for values above every bound
+	count  uint64
+	sum    float64
+}
+
+// histogramBucket is one cumulative bucket of a rendered histogram
+type histogramBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds
+func NewHistogram(bounds ...float64) *Histogram {
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe records one value
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.bounds, value)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += value
+}
+
+// String renders the histogram as JSON, implementing expvar.Var. The last
+// bucket has no upper bound and is given as the largest float64.
+func (h *Histogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make([]histogramBucket, 0, len(h.counts))
+	var cumulative uint64
+	for i, n := range h.counts {
+		cumulative += n
+		bound := math.MaxFloat64
+		if i < len(h.bounds) {
+			bound = h.bounds[i]
+		}
+		buckets = append(buckets, histogramBucket{UpperBound: bound, Count: cumulative})
+	}
+
+	out, _ := json.Marshal(struct {
+		Buckets []histogramBucket `json:"buckets"`
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum"`
+	}{buckets, h.count, h.sum})
+	return string(out)
+}
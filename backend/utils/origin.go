@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// OriginPattern matches CORS origins whose leftmost host label is a wildcard,
+// such as https://*.myapp.com. The wildcard stands for exactly one label, so
+// that pattern matches https://alice.myapp.com but neither
+// https://myapp.com nor https://a.b.myapp.com.
+type OriginPattern struct {
+	scheme string
+	suffix string
+}
+
+// ParseOriginPattern validates a wildcard origin pattern. The pattern must be
+// a bare origin (scheme, host and optional port) whose host starts with "*."
+// followed by at least two labels, so it can't cover a whole public suffix.
+func ParseOriginPattern(pattern string) (OriginPattern, error) {
+	scheme, rest, found := strings.Cut(pattern, "://")
+	if !found || (scheme != "http" && scheme != "https") {
+		return OriginPattern{}, fmt.Errorf("origin pattern %q must start with http:// or https://", pattern)
+	}
+	if !strings.HasPrefix(rest, "*.") || strings.Count(rest, "*") != 1 {
+		return OriginPattern{}, fmt.Errorf("origin pattern %q may only use * as the leftmost host label", pattern)
+	}
+
+	suffix := rest[1:]
+	u, err := url.Parse(scheme + "://placeholder" + suffix)
+	if err != nil || u.Host != "placeholder"+suffix || u.User != nil {
+		return OriginPattern{}, fmt.Errorf("origin pattern %q must be an origin without path, query or credentials", pattern)
+	}
+	if labels := strings.Split(u.Hostname(), "."); len(labels) < 3 || slices.Contains(labels, "") {
+		return OriginPattern{}, fmt.Errorf("origin pattern %q must name a domain below *, such as https://*.example.com", pattern)
+	}
+
+	return OriginPattern{scheme: scheme + "://", suffix: strings.ToLower(suffix)}, nil
+}
+
+// Matches reports whether origin falls under the pattern
+func (p OriginPattern) Matches(origin string) bool {
+	origin = strings.ToLower(origin)
+	if !strings.HasPrefix(origin, p.scheme) || !strings.HasSuffix(origin, p.suffix) {
+		return false
+	}
+
+	label := origin[len(p.scheme) : len(origin)-len(p.suffix)]
+	if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return false
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the pattern as it was configured
+func (p OriginPattern) String() string {
+	return p.scheme + "*" + p.suffix
+}
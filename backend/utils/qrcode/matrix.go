@@ -0,0 +1,271 @@
+package qrcode
+
+// Penalty weights from the specification's mask evaluation rules
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// matrix is a symbol under construction
+type matrix struct {
+	size     int
+	modules  [][]bool
+	reserved [][]bool
+}
+
+// set places a function pattern module that data must not overwrite
+func (m *matrix) set(row, col int, dark bool) {
+	m.modules[row][col] = dark
+	m.reserved[row][col] = true
+}
+
+// build lays out a complete symbol with the given mask applied
+func build(version, mask int, codewords []byte) *Code {
+	size := 17 + 4*version
+	m := &matrix{size: size, modules: make([][]bool, size), reserved: make([][]bool, size)}
+	for i := range m.modules {
+		m.modules[i] = make([]bool, size)
+		m.reserved[i] = make([]bool, size)
+	}
+
+	m.drawFinder(0, 0)
+	m.drawFinder(0, size-7)
+	m.drawFinder(size-7, 0)
+
+	// Alignment patterns, except where they would overlap the finders
+	positions := alignmentPositions[version-1]
+	for _, row := range positions {
+		for _, col := range positions {
+			if !m.reserved[row][col] {
+				m.drawAlignment(row, col)
+			}
+		}
+	}
+
+	// Timing patterns
+	for i := 8; i < size-8; i++ {
+		m.set(6, i, i%2 == 0)
+		m.set(i, 6, i%2 == 0)
+	}
+
+	// Reserve the format areas now so data skips them, then fill them in last
+	m.drawFormat(0)
+	if version >= 7 {
+		m.drawVersion(version)
+	}
+
+	m.placeData(codewords, mask)
+	m.drawFormat(mask)
+
+	return &Code{Version: version, Modules: m.modules}
+}
+
+// drawFinder draws a finder pattern and its separator with the top-left corner at row, col
+func (m *matrix) drawFinder(row, col int) {
+	for dr := -1; dr <= 7; dr++ {
+		for dc := -1; dc <= 7; dc++ {
+			r, c := row+dr, col+dc
+			if r < 0 || r >= m.size || c < 0 || c >= m.size {
+				continue
+			}
+			ring := max(abs(dr-3), abs(dc-3))
+			m.set(r, c, ring != 2 && ring != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centred at row, col
+func (m *matrix) drawAlignment(row, col int) {
+	for dr := -2; dr <= 2; dr++ {
+		for dc := -2; dc <= 2; dc++ {
+			m.set(row+dr, col+dc, max(abs(dr), abs(dc)) != 1)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information for level M
+func (m *matrix) drawFormat(mask int) {
+	data := mask // Level M is encoded as 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	// First copy, around the top-left finder
+	for i := 0; i <= 5; i++ {
+		m.set(i, 8, bit(bits, i))
+	}
+	m.set(7, 8, bit(bits, 6))
+	m.set(8, 8, bit(bits, 7))
+	m.set(8, 7, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		m.set(8, 14-i, bit(bits, i))
+	}
+
+	// Second copy, split between the other two finders
+	for i := 0; i < 8; i++ {
+		m.set(8, m.size-1-i, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		m.set(m.size-15+i, 8, bit(bits, i))
+	}
+	m.set(m.size-8, 8, true) // Always-dark module
+}
+
+// drawVersion draws both copies of the version information
+func (m *matrix) drawVersion(version int) {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1f25)
+	}
+	bits := version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		a, b := m.size-11+i%3, i/3
+		m.set(b, a, bit(bits, i))
+		m.set(a, b, bit(bits, i))
+	}
+}
+
+// placeData fills the non-reserved modules with codeword bits in the
+// two-column zigzag order, applying the mask as it goes
+func (m *matrix) placeData(codewords []byte, mask int) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // Skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < m.size; vert++ {
+			row := vert
+			if upward {
+				row = m.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				col := right - j
+				if m.reserved[row][col] {
+					continue
+				}
+				dark := false
+				if i < len(codewords)*8 {
+					dark = codewords[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+				m.modules[row][col] = dark != masked(mask, row, col)
+			}
+		}
+	}
+}
+
+// masked reports whether the mask pattern inverts the module at row, col
+func masked(mask, row, col int) bool {
+	switch mask {
+	case 0:
+		return (row+col)%2 == 0
+	case 1:
+		return row%2 == 0
+	case 2:
+		return col%3 == 0
+	case 3:
+		return (row+col)%3 == 0
+	case 4:
+		return (row/2+col/3)%2 == 0
+	case 5:
+		return row*col%2+row*col%3 == 0
+	case 6:
+		return (row*col%2+row*col%3)%2 == 0
+	default:
+		return ((row+col)%2+row*col%3)%2 == 0
+	}
+}
+
+// penalty scores a masked symbol; lower is easier to scan
+func penalty(modules [][]bool) int {
+	size := len(modules)
+	at := func(row, col int, transpose bool) bool {
+		if transpose {
+			row, col = col, row
+		}
+		if row < 0 || row >= size || col < 0 || col >= size {
+			return false // The quiet zone is light
+		}
+		return modules[row][col]
+	}
+
+	finder := [11]bool{true, false, true, true, true, false, true, false, false, false, false}
+	score := 0
+
+	for _, transpose := range []bool{false, true} {
+		for line := 0; line < size; line++ {
+			// Runs of five or more same-coloured modules
+			run := 1
+			for i := 1; i <= size; i++ {
+				if i < size && at(line, i, transpose) == at(line, i-1, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += penaltyRun + run - 5
+				}
+				run = 1
+			}
+
+			// Finder-like 1:1:3:1:1 patterns with four light modules on either side
+			for i := -4; i < size; i++ {
+				forward, backward := true, true
+				for k := 0; k < 11; k++ {
+					if at(line, i+k, transpose) != finder[k] {
+						forward = false
+					}
+					if at(line, i+k, transpose) != finder[10-k] {
+						backward = false
+					}
+				}
+				if forward {
+					score += penaltyFinder
+				}
+				if backward {
+					score += penaltyFinder
+				}
+			}
+		}
+	}
+
+	// 2x2 blocks of one colour
+	dark := 0
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			if modules[row][col] {
+				dark++
+			}
+			if row > 0 && col > 0 {
+				c := modules[row][col]
+				if modules[row-1][col] == c && modules[row][col-1] == c && modules[row-1][col-1] == c {
+					score += penaltyBlock
+				}
+			}
+		}
+	}
+
+	// Deviation of the dark proportion from 50%, in steps of 5%
+	deviation := abs(dark*100/(size*size) - 50)
+	score += deviation / 5 * penaltyBalance
+
+	return score
+}
+
+// bit returns bit i of value
+func bit(value, i int) bool {
+	return (value>>i)&1 == 1
+}
+
+// abs returns the absolute value of an int
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,261 @@
+// Package qrcode encodes short texts such as share links as QR codes
+// (ISO/IEC 18004) using byte mode and error correction level M.
+package qrcode
+
+import (
+	"errors"
+)
+
+// maxVersion is the largest symbol version supported, enough for 666 bytes
+const maxVersion = 20
+
+// ErrTooLong is returned when the data doesn't fit in the largest supported symbol
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// blockLayout describes how a version's codewords are split into
+// Reed-Solomon blocks at error correction level M
+type blockLayout struct {
+	ecPerBlock int
+	blocks1    int
+	data1      int
+	blocks2    int
+	data2      int
+}
+
+// layouts is indexed by version - 1
+var layouts = [maxVersion]blockLayout{
+	{10, 1, 16, 0, 0},
+	{16, 1, 28, 0, 0},
+	{26, 1, 44, 0, 0},
+	{18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0},
+	{16, 4, 27, 0, 0},
+	{18, 4, 31, 0, 0},
+	{22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37},
+	{26, 4, 43, 1, 44},
+	{30, 1, 50, 4, 51},
+	{22, 6, 36, 2, 37},
+	{22, 8, 37, 1, 38},
+	{24, 4, 40, 5, 41},
+	{24, 5, 41, 5, 42},
+	{28, 7, 45, 3, 46},
+	{28, 10, 46, 1, 47},
+	{26, 9, 43, 4, 44},
+	{26, 3, 44, 11, 45},
+	{26, 3, 41, 13, 42},
+}
+
+// alignmentPositions is indexed by version - 1
+var alignmentPositions = [maxVersion][]int{
+	{},
+	{6, 18},
+	{6, 22},
+	{6, 26},
+	{6, 30},
+	{6, 34},
+	{6, 22, 38},
+	{6, 24, 42},
+	{6, 26, 46},
+	{6, 28, 50},
+	{6, 30, 54},
+	{6, 32, 58},
+	{6, 34, 62},
+	{6, 26, 46, 66},
+	{6, 26, 48, 70},
+	{6, 26, 50, 74},
+	{6, 30, 54, 78},
+	{6, 30, 56, 82},
+	{6, 30, 58, 86},
+	{6, 34, 62, 90},
+}
+
+// dataCodewords returns how many data codewords a layout holds
+func (l blockLayout) dataCodewords() int {
+	return l.blocks1*l.data1 + l.blocks2*l.data2
+}
+
+// Code is an encoded QR symbol. Modules are indexed [row][column] and true
+// means dark. The quiet zone is not included.
+type Code struct {
+	Version int
+	Modules [][]bool
+}
+
+// Size returns the number of modules per side
+func (c *Code) Size() int {
+	return len(c.Modules)
+}
+
+// Encode encodes data in the smallest symbol that fits, choosing the mask
+// with the lowest penalty score
+func Encode(data []byte) (*Code, error) {
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		if 4+countBits(v)+8*len(data) <= 8*layouts[v-1].dataCodewords() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := interleave(version, dataBytes(version, data))
+
+	var best *Code
+	bestPenalty := -1
+	for mask := 0; mask < 8; mask++ {
+		code := build(version, mask, codewords)
+		if p := penalty(code.Modules); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = code, p
+		}
+	}
+	return best, nil
+}
+
+// countBits returns the width of the byte mode character count indicator
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// bitWriter accumulates a big-endian bit stream
+type bitWriter struct {
+	bytes []byte
+	bits  int
+}
+
+// write appends the low n bits of value
+func (w *bitWriter) write(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.bytes = append(w.bytes, 0)
+		}
+		if (value>>i)&1 == 1 {
+			w.bytes[len(w.bytes)-1] |= 0x80 >> (w.bits % 8)
+		}
+		w.bits++
+	}
+}
+
+// dataBytes builds the padded data codewords for a byte mode segment
+func dataBytes(version int, data []byte) []byte {
+	capacity := layouts[version-1].dataCodewords()
+
+	w := &bitWriter{}
+	w.write(0b0100, 4)
+	w.write(len(data), countBits(version))
+	for _, b := range data {
+		w.write(int(b), 8)
+	}
+
+	// Terminator, then pad to a byte boundary and fill with alternating pad bytes
+	w.write(0, min(4, 8*capacity-w.bits))
+	if w.bits%8 != 0 {
+		w.write(0, 8-w.bits%8)
+	}
+	for pad := 0; len(w.bytes) < capacity; pad++ {
+		if pad%2 == 0 {
+			w.write(0xEC, 8)
+		} else {
+			w.write(0x11, 8)
+		}
+	}
+	return w.bytes
+}
+
+// interleave splits data into blocks, appends their error correction and
+// interleaves the result in transmission order
+func interleave(version int, data []byte) []byte {
+	layout := layouts[version-1]
+	generator := rsGenerator(layout.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	for i := 0; i < layout.blocks1+layout.blocks2; i++ {
+		n := layout.data1
+		if i >= layout.blocks1 {
+			n = layout.data2
+		}
+		block := data[offset : offset+n]
+		offset += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, generator))
+	}
+
+	var out []byte
+	for i := 0; i < max(layout.data1, layout.data2); i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+// Galois field GF(256) arithmetic with the QR primitive polynomial 0x11d
+var gfExp, gfLog [256]int
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	gfExp[255] = gfExp[0]
+}
+
+// gfMul multiplies two field elements
+func gfMul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[(gfLog[a]+gfLog[b])%255]
+}
+
+// rsGenerator returns the generator polynomial of the given degree,
+// highest coefficient first
+func rsGenerator(degree int) []int {
+	poly := []int{1}
+	for i := 0; i < degree; i++ {
+		next := make([]int, len(poly)+1)
+		for j, c := range poly {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfExp[i])
+		}
+		poly = next
+	}
+	return poly
+}
+
+// rsRemainder computes the error correction codewords for a block
+func rsRemainder(data []byte, generator []int) []byte {
+	remainder := make([]int, len(generator)-1)
+	for _, b := range data {
+		factor := int(b) ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[len(remainder)-1] = 0
+		for i := range remainder {
+			remainder[i] ^= gfMul(generator[i+1], factor)
+		}
+	}
+
+	out := make([]byte, len(remainder))
+	for i, c := range remainder {
+		out[i] = byte(c)
+	}
+	return out
+}
@@ -0,0 +1,403 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestReedSolomonKnownVector checks the error correction of the 1-M
+// "HELLO WORLD" example worked through in most QR references
+func TestReedSolomonKnownVector(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if got := rsRemainder(data, rsGenerator(10)); !bytes.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// formatStrings are the level M format information strings from the
+// specification, indexed by mask
+var formatStrings = [8]string{
+	"101010000010010",
+	"101000100100101",
+	"101111001111100",
+	"101101101001011",
+	"100010111111001",
+	"100000011001110",
+	"100111110010111",
+	"100101010100000",
+}
+
+// readFormat reads both copies of the format information, most significant bit first
+func readFormat(modules [][]bool) (string, string) {
+	size := len(modules)
+	var first, second strings.Builder
+	for _, p := range [][2]int{{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8}, {7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8}} {
+		first.WriteString(moduleBit(modules[p[0]][p[1]]))
+	}
+	for i := 0; i < 7; i++ {
+		second.WriteString(moduleBit(modules[size-1-i][8]))
+	}
+	for i := 0; i < 8; i++ {
+		second.WriteString(moduleBit(modules[8][size-8+i]))
+	}
+	return first.String(), second.String()
+}
+
+func moduleBit(dark bool) string {
+	if dark {
+		return "1"
+	}
+	return "0"
+}
+
+func TestFormatInformation(t *testing.T) {
+	for _, version := range []int{1, 7, 20} {
+		codewords := interleave(version, dataBytes(version, []byte("format")))
+		for mask, want := range formatStrings {
+			code := build(version, mask, codewords)
+			first, second := readFormat(code.Modules)
+			if first != want || second != want {
+				t.Errorf("version %d mask %d: got %s and %s, want %s", version, mask, first, second, want)
+			}
+			if !code.Modules[code.Size()-8][8] {
+				t.Errorf("version %d mask %d: dark module is light", version, mask)
+			}
+		}
+	}
+}
+
+// versionInformation is the specification's version information table
+var versionInformation = map[int]int{
+	7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3, 11: 0x0BBF6, 12: 0x0C762, 13: 0x0D847,
+	14: 0x0E60D, 15: 0x0F928, 16: 0x10B78, 17: 0x1145D, 18: 0x12A17, 19: 0x13532, 20: 0x149A6,
+}
+
+func TestVersionInformation(t *testing.T) {
+	for version, want := range versionInformation {
+		code := build(version, 0, interleave(version, dataBytes(version, nil)))
+		size := code.Size()
+
+		// Bottom-left block, and its transpose in the top-right
+		var bottomLeft, topRight int
+		for i := 17; i >= 0; i-- {
+			bottomLeft <<= 1
+			topRight <<= 1
+			if code.Modules[size-11+i%3][i/3] {
+				bottomLeft |= 1
+			}
+			if code.Modules[i/3][size-11+i%3] {
+				topRight |= 1
+			}
+		}
+		if bottomLeft != want || topRight != want {
+			t.Errorf("version %d: got %05X and %05X, want %05X", version, bottomLeft, topRight, want)
+		}
+	}
+}
+
+// functionModules marks the modules a reader skips when collecting data,
+// worked out from the specification rather than from build
+func functionModules(version int) [][]bool {
+	size := 17 + 4*version
+	function := make([][]bool, size)
+	for i := range function {
+		function[i] = make([]bool, size)
+	}
+	fill := func(row, col, height, width int) {
+		for r := row; r < row+height; r++ {
+			for c := col; c < col+width; c++ {
+				function[r][c] = true
+			}
+		}
+	}
+
+	// Finders with their separators and format information
+	fill(0, 0, 9, 9)
+	fill(0, size-8, 9, 8)
+	fill(size-8, 0, 8, 9)
+	// Timing
+	fill(6, 0, 1, size)
+	fill(0, 6, size, 1)
+	// Alignment patterns not overlapping a finder
+	positions := alignmentPositions[version-1]
+	last := len(positions) - 1
+	for i, row := range positions {
+		for j, col := range positions {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			fill(row-2, col-2, 5, 5)
+		}
+	}
+	if version >= 7 {
+		fill(size-11, 0, 3, 6)
+		fill(0, size-11, 6, 3)
+	}
+	return function
+}
+
+// maskConditions are the specification's data mask conditions, indexed by mask
+var maskConditions = [8]func(i, j int) bool{
+	func(i, j int) bool { return (i+j)%2 == 0 },
+	func(i, j int) bool { return i%2 == 0 },
+	func(i, j int) bool { return j%3 == 0 },
+	func(i, j int) bool { return (i+j)%3 == 0 },
+	func(i, j int) bool { return (i/2+j/3)%2 == 0 },
+	func(i, j int) bool { return i*j%2+i*j%3 == 0 },
+	func(i, j int) bool { return (i*j%2+i*j%3)%2 == 0 },
+	func(i, j int) bool { return ((i+j)%2+i*j%3)%2 == 0 },
+}
+
+// decode reads a symbol back the way a scanner would, checking the error
+// correction of every block, and returns the byte mode payload
+func decode(code *Code) ([]byte, error) {
+	version, size := code.Version, code.Size()
+	if size != 17+4*version {
+		return nil, fmt.Errorf("size %d for version %d", size, version)
+	}
+
+	format, _ := readFormat(code.Modules)
+	mask := -1
+	for m, s := range formatStrings {
+		if s == format {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return nil, fmt.Errorf("format information %s is not level M", format)
+	}
+
+	// Collect the data modules in placement order, two columns at a time
+	function := functionModules(version)
+	var bits []bool
+	upward := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for step := 0; step < size; step++ {
+			row := step
+			if upward {
+				row = size - 1 - step
+			}
+			for _, col := range []int{right, right - 1} {
+				if !function[row][col] {
+					bits = append(bits, code.Modules[row][col] != maskConditions[mask](row, col))
+				}
+			}
+		}
+		upward = !upward
+	}
+
+	layout := layouts[version-1]
+	total := layout.dataCodewords() + layout.ecPerBlock*(layout.blocks1+layout.blocks2)
+	if len(bits) < 8*total {
+		return nil, fmt.Errorf("%d data modules, want at least %d", len(bits), 8*total)
+	}
+	codewords := make([]byte, total)
+	for i := range codewords {
+		for _, b := range bits[8*i : 8*i+8] {
+			codewords[i] <<= 1
+			if b {
+				codewords[i] |= 1
+			}
+		}
+	}
+
+	// De-interleave into blocks of data followed by error correction
+	blocks := make([][]byte, layout.blocks1+layout.blocks2)
+	next := 0
+	for i := 0; i < max(layout.data1, layout.data2)+layout.ecPerBlock; i++ {
+		for b := range blocks {
+			n := layout.data1
+			if b >= layout.blocks1 {
+				n = layout.data2
+			}
+			// Short blocks have no codeword in the last data column
+			if i >= n && i < max(layout.data1, layout.data2) {
+				continue
+			}
+			blocks[b] = append(blocks[b], codewords[next])
+			next++
+		}
+	}
+
+	// A valid codeword has a root at each power of the generator
+	var data []byte
+	for b, block := range blocks {
+		for i := 0; i < layout.ecPerBlock; i++ {
+			syndrome := 0
+			for _, c := range block {
+				syndrome = gfMul(syndrome, gfExp[i]) ^ int(c)
+			}
+			if syndrome != 0 {
+				return nil, fmt.Errorf("block %d: syndrome %d is %d", b, i, syndrome)
+			}
+		}
+		data = append(data, block[:len(block)-layout.ecPerBlock]...)
+	}
+
+	r := &bitReader{data: data}
+	if mode := r.read(4); mode != 0b0100 {
+		return nil, fmt.Errorf("mode %04b, want byte mode", mode)
+	}
+	count := r.read(countBits(version))
+	payload := make([]byte, count)
+	for i := range payload {
+		payload[i] = byte(r.read(8))
+	}
+	if r.pos > 8*len(data) {
+		return nil, errors.New("payload runs past the data codewords")
+	}
+	return payload, nil
+}
+
+// bitReader reads a big-endian bit stream, yielding zeros past the end
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	value := 0
+	for i := 0; i < n; i++ {
+		value <<= 1
+		if r.pos/8 < len(r.data) && r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0 {
+			value |= 1
+		}
+		r.pos++
+	}
+	return value
+}
+
+func TestRoundTripAllMasks(t *testing.T) {
+	// Payload lengths filling versions 1, 2, 7, 9, 10 and 20 exactly
+	for _, tc := range []struct {
+		length, version int
+	}{{14, 1}, {26, 2}, {122, 7}, {180, 9}, {213, 10}, {666, 20}} {
+		data := make([]byte, tc.length)
+		for i := range data {
+			data[i] = byte(i*37 + tc.length)
+		}
+
+		code, err := Encode(data)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", tc.length, err)
+		}
+		if code.Version != tc.version {
+			t.Errorf("%d bytes: version %d, want %d", tc.length, code.Version, tc.version)
+		}
+
+		codewords := interleave(tc.version, dataBytes(tc.version, data))
+		for mask := 0; mask < 8; mask++ {
+			got, err := decode(build(tc.version, mask, codewords))
+			if err != nil {
+				t.Errorf("version %d mask %d: %v", tc.version, mask, err)
+			} else if !bytes.Equal(got, data) {
+				t.Errorf("version %d mask %d: decoded %q, want %q", tc.version, mask, got, data)
+			}
+		}
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(make([]byte, 667)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("got %v, want ErrTooLong", err)
+	}
+}
+
+// render draws modules as text, # for dark
+func render(modules [][]bool) string {
+	var b strings.Builder
+	for _, row := range modules {
+		for _, dark := range row {
+			if dark {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// TestGoldenMatrices pins complete version 1 and 2 symbols, including the
+// mask chosen by the penalty rules
+func TestGoldenMatrices(t *testing.T) {
+	for _, tc := range []struct {
+		data   string
+		golden string
+	}{
+		{"HELLO WORLD", goldenHelloWorld},
+		{"https://file.sh/b/3f2a9c", goldenShareLink},
+	} {
+		code, err := Encode([]byte(tc.data))
+		if err != nil {
+			t.Fatalf("%q: %v", tc.data, err)
+		}
+		if got := render(code.Modules); got != strings.TrimPrefix(tc.golden, "\n") {
+			t.Errorf("%q: got\n%swant%s", tc.data, got, tc.golden)
+		}
+		if got, err := decode(code); err != nil || string(got) != tc.data {
+			t.Errorf("%q: decoded %q, %v", tc.data, got, err)
+		}
+	}
+}
+
+const goldenHelloWorld = `
+#######.##..#.#######
+#.....#....#..#.....#
+#.###.#..#.#..#.###.#
+#.###.#.#..#..#.###.#
+#.###.#.###.#.#.###.#
+#.....#.#..#..#.....#
+#######.#.#.#.#######
+........#..##........
+#...#.######.#####..#
+...#....#.###....####
+..######..##.##.#..#.
+#####...##...#.......
+#####.#.#.#.#.##..##.
+........#.#.####.#.##
+#######.###.#.#.##.#.
+#.....#..#.###.##..##
+#.###.#.##.#.##...##.
+#.###.#..#..#...##.##
+#.###.#..###...###...
+#.....#....#.#.......
+#######.#########.#.#
+`
+
+const goldenShareLink = `
+#######.###.#.#.#.#######
+#.....#.#.#.#.....#.....#
+#.###.#.#..#.##.#.#.###.#
+#.###.#...##.##.#.#.###.#
+#.###.#.##......#.#.###.#
+#.....#...#.##....#.....#
+#######.#.#.#.#.#.#######
+.........#..##.##........
+#..########...#.##..#.###
+...##...###.#.###..#####.
+###.###.#..###.#.#...#..#
+.......###.#....#.##.####
+.###..##...#..#.#.##....#
+#......##...##.##...#..#.
+####.####.#....#.#..#####
+#..##..###..#.###.##.##.#
+#.#######.####.######.##.
+........####...##...#.##.
+#######.#...###.#.#.#...#
+#.....#.#.##.#.##...#...#
+#.###.#.##.##..######....
+#.###.#.#...##...##....##
+#.###.#..###..#.##..#####
+#.....#..##.#.#...###.###
+#######.#.###..##.#..#..#
+`
@@ -0,0 +1,69 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// quietZone is the light border required around a symbol, in modules
+const quietZone = 4
+
+// PNG renders the symbol as a size x size pixel grayscale PNG. Modules are
+// scaled by a whole number of pixels and centred, so size must leave at
+// least one pixel per module including the quiet zone.
+func (c *Code) PNG(size int) ([]byte, error) {
+	total := c.Size() + 2*quietZone
+	scale := size / total
+	if scale < 1 {
+		return nil, fmt.Errorf("size %d is too small for a %dx%d module code", size, total, total)
+	}
+	offset := (size - scale*total) / 2
+
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for row, modules := range c.Modules {
+		for col, dark := range modules {
+			if !dark {
+				continue
+			}
+			x0 := offset + (col+quietZone)*scale
+			y0 := offset + (row+quietZone)*scale
+			for y := y0; y < y0+scale; y++ {
+				for x := x0; x < x0+scale; x++ {
+					img.SetGray(x, y, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the symbol as a scalable SVG document displayed at size x size pixels
+func (c *Code) SVG(size int) []byte {
+	total := c.Size() + 2*quietZone
+
+	var path strings.Builder
+	for row, modules := range c.Modules {
+		for col, dark := range modules {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", col+quietZone, row+quietZone)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, total, total)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`, path.String())
+	return buf.Bytes()
+}
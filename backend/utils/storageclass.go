@@ -0,0 +1,13 @@
+package utils
+
+import "regexp"
+
+// storageClassPattern matches provider storage class names such as
+// STANDARD_IA or GLACIER_IR. Providers differ in the classes they offer, so
+// only the form is checked and the provider rejects classes it doesn't know.
+var storageClassPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,63}$`)
+
+// ValidStorageClass reports whether class is well-formed as a storage class
+func ValidStorageClass(class string) bool {
+	return storageClassPattern.MatchString(class)
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// minThrottleBurst keeps reads reasonably sized even at very low rates
+const minThrottleBurst = 32 * 1024
+
+// rateLimitedReader limits how fast data can be read from the wrapped reader
+type rateLimitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rate.Limiter
+}
+
+// rateLimitedReadSeeker is a rateLimitedReader over a seekable source
+type rateLimitedReadSeeker struct {
+	*rateLimitedReader
+	seeker io.Seeker
+}
+
+// NewRateLimitedReader wraps a reader so it yields at most bytesPerSecond,
+// using a token bucket. Waiting for tokens honours ctx, so a cancelled
+// request stops reading immediately. A seekable reader stays seekable, so
+// uploads can still be rewound and retried. A non-positive rate disables
+// throttling.
+func NewRateLimitedReader(ctx context.Context, reader io.Reader, bytesPerSecond int64) io.Reader {
+	if bytesPerSecond <= 0 {
+		return reader
+	}
+
+	burst := int(max(bytesPerSecond, minThrottleBurst))
+	limited := &rateLimitedReader{
+		ctx:     ctx,
+		reader:  reader,
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), burst),
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		return &rateLimitedReadSeeker{rateLimitedReader: limited, seeker: seeker}
+	}
+	return limited
+}
+
+// Seek seeks the wrapped reader
+func (r *rateLimitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
+
+// Read reads at most one burst worth of data, then waits for enough tokens
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
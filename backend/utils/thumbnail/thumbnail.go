@@ -0,0 +1,123 @@
+// Package thumbnail scales images down to small JPEG previews of JPEG, PNG,
+// GIF and WebP images.
+package thumbnail
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// Register the remaining decoders with image.Decode
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxSourcePixels bounds the decoded size of a source image, so a small
+	// file can't expand into gigabytes of memory
+	MaxSourcePixels = 40_000_000
+	// headerSize is how much of the source is buffered to read its
+	// dimensions; JPEG metadata can push the frame header well past the start
+	headerSize = 256 * 1024
+	// jpegQuality is the encoder quality of generated thumbnails
+	jpegQuality = 80
+)
+
+var (
+	// ErrUnsupported is returned when the data isn't an image in a supported format
+	ErrUnsupported = errors.New("unsupported image format")
+	// ErrTooLarge is returned when the source image exceeds MaxSourcePixels
+	ErrTooLarge = errors.New("image too large")
+)
+
+// Generate decodes an image and returns a JPEG of the given width with the
+// aspect ratio preserved. Images narrower than width are not enlarged.
+func Generate(r io.Reader, width int) ([]byte, error) {
+	if width < 1 {
+		return nil, fmt.Errorf("invalid thumbnail width %d", width)
+	}
+
+	// Check the dimensions before decoding any pixel data
+	br := bufio.NewReaderSize(r, headerSize)
+	header, err := br.Peek(headerSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(header))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width < 1 || cfg.Height < 1 || int64(cfg.Width)*int64(cfg.Height) > MaxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(br)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupported
+		}
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	if width > bounds.Dx() {
+		width = bounds.Dx()
+	}
+	height := (bounds.Dy()*width + bounds.Dx()/2) / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(src, width, height), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scale resizes an image by averaging the source pixels covered by each
+// destination pixel. Transparent areas are composed onto white, since JPEG
+// has no alpha channel.
+func scale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	// Flatten the source once so the averaging loop reads plain bytes
+	flat := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max((y+1)*srcH/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max((x+1)*srcW/width, x0+1)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := flat.Pix[sy*flat.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4:]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					n++
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), 0xff
+		}
+	}
+	return dst
+}
@@ -0,0 +1,52 @@
+// Package version reports which build of the server is running. The values
+// are set at link time, for example:
+//
+//	go build -ldflags "-X filesh/version.Version=1.2.0 -X filesh/version.Commit=$(git rev-parse --short HEAD) -X filesh/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import "runtime/debug"
+
+// Set with -ldflags "-X filesh/version.<Name>=<value>"
+var (
+	// Version is the release version
+	Version = "1.0.0"
+	// Commit is the git revision the binary was built from
+	Commit = ""
+	// BuildDate is when the binary was built, in RFC 3339 format
+	BuildDate = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information. Without a Commit from the linker, the
+// revision the Go toolchain stamps into builds from a git checkout is used.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = build.GoVersion
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}